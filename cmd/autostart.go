@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+// autostartCmd groups the autostart subcommands.
+var autostartCmd = &cobra.Command{
+	Use:   "autostart",
+	Short: "Start resources like VMs and networks along with the host",
+}
+
+var autostartVmCmd = &cobra.Command{
+	Use:          "vm <vm-name> on|off",
+	Short:        "Set whether a virtual machine starts along with the host",
+	Example:      `  kvmcli autostart vm web-server-01 on`,
+	Args:         cobra.ExactArgs(2),
+	ValidArgs:    []string{"on", "off"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[1])
+		if err != nil {
+			return err
+		}
+		return operations.SetVMAutostart(args[0], on)
+	},
+}
+
+var autostartNetworkCmd = &cobra.Command{
+	Use:          "network <network-name> on|off",
+	Aliases:      []string{"net"},
+	Short:        "Set whether a virtual network starts along with the host",
+	Example:      `  kvmcli autostart network homelab-net on`,
+	Args:         cobra.ExactArgs(2),
+	ValidArgs:    []string{"on", "off"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[1])
+		if err != nil {
+			return err
+		}
+		return operations.SetNetworkAutostart(args[0], on)
+	},
+}
+
+// parseOnOff parses the on|off argument of the autostart subcommands.
+func parseOnOff(arg string) (bool, error) {
+	switch arg {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid setting %q (want on or off)", arg)
+}
+
+func init() {
+	autostartCmd.AddCommand(autostartVmCmd, autostartNetworkCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/backup"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupOutput      string // Path of the archive to write.
+	backupFlatten     bool   // Merge the backing image into the archived disk.
+	backupIncludeBase bool   // Ship the backing image alongside the overlay.
+)
+
+// backupCmd groups the backup subcommands.
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up resources like VMs to a portable archive",
+}
+
+var backupVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Back up a virtual machine",
+	Long: `Write the disk, domain XML, database record and HCL of a virtual machine
+to a zstd-compressed tar archive. Running VMs are backed up live.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := backup.Options{
+			Output:      backupOutput,
+			Flatten:     backupFlatten,
+			IncludeBase: backupIncludeBase,
+		}
+		return operations.BackupVM(args[0], opts)
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupVmCmd)
+
+	backupVmCmd.Flags().
+		StringVarP(&backupOutput, "output", "o", "", "archive to write (default <vm-name>.tar.zst)")
+	backupVmCmd.Flags().
+		BoolVar(&backupFlatten, "flatten", false, "merge the backing image into the archived disk")
+	backupVmCmd.Flags().
+		BoolVar(&backupIncludeBase, "include-base", false, "include the backing image of an unflattened disk")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneIP      string // Address of the clone.
+	cloneLinked  bool   // Create a fresh overlay instead of copying the disk.
+	cloneStart   bool   // Start the clone once defined.
+	cloneEmitHCL string // Where to write the HCL block of the clone.
+)
+
+// cloneCmd groups the clone subcommands.
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Duplicate resources like VMs",
+}
+
+var cloneVmCmd = &cobra.Command{
+	Use:   "vm <source> <destination>",
+	Short: "Clone a virtual machine under a new name, MAC and IP",
+	Long: `Clone a virtual machine. A full clone copies the disk of a stopped VM;
+a linked clone (--linked) gets a fresh overlay on the same base image.
+Without --ip the first free static address of the network, outside its
+DHCP range, is reserved for the clone; networks without a subnet leave it
+on a dynamic lease.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := vms.CloneOptions{
+			IP:     cloneIP,
+			Linked: cloneLinked,
+			Start:  cloneStart,
+		}
+		if err := operations.CloneVM(args[0], args[1], opts, cloneEmitHCL); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+func init() {
+	cloneCmd.AddCommand(cloneVmCmd)
+
+	cloneVmCmd.Flags().StringVar(&cloneIP, "ip", "", "IP address of the clone")
+	cloneVmCmd.Flags().
+		BoolVar(&cloneLinked, "linked", false, "create a fresh overlay on the base image instead of copying the disk")
+	cloneVmCmd.Flags().BoolVar(&cloneStart, "start", false, "start the clone")
+	cloneVmCmd.Flags().
+		StringVar(&cloneEmitHCL, "emit-hcl", "", "write an HCL block for the clone to this file (- for stdout)")
+}
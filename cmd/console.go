@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consoleEscape string // Escape sequence used to detach, in caret notation.
+	consoleRecord string // Optional file receiving a copy of the console output.
+	consoleDevice string // Console device alias.
+	consoleForce  bool   // Take over a console opened by another client.
+)
+
+// consoleCmd groups the interactive console subcommands.
+var consoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: "Attach to the console of resources like VMs",
+}
+
+var consoleVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Open an interactive serial console on a virtual machine",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		escape, err := vms.ParseEscapeSequence(consoleEscape)
+		if err != nil {
+			log.Errorf("%v", err)
+			return
+		}
+		opts := vms.ConsoleOptions{
+			Device:     consoleDevice,
+			EscapeChar: escape,
+			Force:      consoleForce,
+		}
+		if err := operations.ConsoleVM(args[0], consoleRecord, opts); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+func init() {
+	consoleVmCmd.Flags().
+		StringVarP(&consoleEscape, "escape", "e", "^]", "Escape sequence to detach from the console")
+	consoleVmCmd.Flags().
+		StringVarP(&consoleRecord, "record", "r", "", "Append the console output to this file")
+	consoleVmCmd.Flags().
+		StringVar(&consoleDevice, "device", "", "Console device alias (defaults to the primary console)")
+	consoleVmCmd.Flags().
+		BoolVar(&consoleForce, "force", false, "Disconnect any other client attached to the console")
+	consoleCmd.AddCommand(consoleVmCmd)
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"time"
+
 	log "github.com/kebairia/kvmcli/internal/logger"
 	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
 	"github.com/spf13/cobra"
 )
 
+var (
+	createWait        bool          // Wait for the created VMs to be ready.
+	createWaitTimeout time.Duration // How long each VM may take to be ready.
+)
+
 // CreateCmd represents the command to create resource(s) from a manifest file.
 var CreateCmd = &cobra.Command{
 	Use:   "create",
@@ -16,7 +24,7 @@ var CreateCmd = &cobra.Command{
 		}
 
 		// Use the provided configuration file to create resources.
-		if err := operations.CreateFromManifest(ManifestPath); err != nil {
+		if err := operations.CreateFromManifest(ManifestPath, createWait, createWaitTimeout); err != nil {
 			log.Errorf("%v", err)
 		}
 	},
@@ -26,4 +34,8 @@ func init() {
 	// Bind the manifest file flag to the global variable.
 	CreateCmd.Flags().
 		StringVarP(&ManifestPath, "file", "f", "", "Configuration file for the resource(s)")
+	CreateCmd.Flags().
+		BoolVar(&createWait, "wait", false, "wait for the created VMs to pass their healthcheck")
+	CreateCmd.Flags().
+		DurationVar(&createWaitTimeout, "wait-timeout", vms.DefaultReadyTimeout, "how long each VM may take to be ready")
 }
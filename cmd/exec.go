@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+var execTimeout time.Duration // How long the command may run.
+
+// execCmd groups the exec subcommands.
+var execCmd = &cobra.Command{
+	Use:   "exec",
+	Short: "Run commands inside resources like VMs",
+}
+
+var execVmCmd = &cobra.Command{
+	Use:   "vm <vm-name> -- command...",
+	Short: "Run a command inside a virtual machine through the QEMU guest agent",
+	Long: `Run a command inside a virtual machine through the QEMU guest agent,
+without SSH. The VM needs qemu-guest-agent running. Output is printed once
+the command exits, and its exit code is kvmcli's exit code.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		code, err := operations.ExecVM(args[0], args[1:], execTimeout)
+		if err != nil {
+			log.Errorf("%v", err)
+			os.Exit(1)
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	execVmCmd.Flags().
+		DurationVar(&execTimeout, "timeout", 5*time.Minute, "how long the command may run")
+	execCmd.AddCommand(execVmCmd)
+}
@@ -35,11 +35,9 @@ var GetSnapshotsCmd = &cobra.Command{
 	Aliases: []string{"snap"},
 	Short:   "Display snapshots for virtual machines",
 	Run: func(cmd *cobra.Command, args []string) {
-		if ConfigFile == "" {
-			log.Errorf("Configuration file is required (-f flag)")
+		if err := operations.ListAllSnapshots(Namespace); err != nil {
+			log.Errorf("%v", err)
 		}
-		fmt.Println("You snapshosts are here")
-		// op.ListSnapshost()
 	},
 }
 
@@ -73,6 +71,31 @@ var GetStoreCmd = &cobra.Command{
 	},
 }
 
+// 'get cluster' subcommand: shows clusters.
+var GetClusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Display clusters, their members by role and aggregate state",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := operations.ListAllClusters(Namespace); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+var leaseNetwork string // Only show the leases of this network.
+
+// 'get lease' subcommand: shows DHCP leases and reservations.
+var GetLeaseCmd = &cobra.Command{
+	Use:     "lease",
+	Aliases: []string{"leases"},
+	Short:   "Display DHCP leases and static reservations of networks",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := operations.ListAllLeases(Namespace, leaseNetwork); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
 func init() {
 	// Flags for virtual machines
 	GetVMCmd.Flags().
@@ -86,5 +109,13 @@ func init() {
 		// Flags for stores
 	GetStoreCmd.Flags().
 		StringVarP(&Namespace, "namespace", "n", "", "Namespace")
-	GetCmd.AddCommand(GetVMCmd, GetSnapshotsCmd, GetNetworkCmd, GetStoreCmd)
+		// Flags for clusters
+	GetClusterCmd.Flags().
+		StringVarP(&Namespace, "namespace", "n", "", "Namespace")
+		// Flags for leases
+	GetLeaseCmd.Flags().
+		StringVarP(&Namespace, "namespace", "n", "", "Namespace")
+	GetLeaseCmd.Flags().
+		StringVar(&leaseNetwork, "network", "", "Only show the leases of this network")
+	GetCmd.AddCommand(GetVMCmd, GetSnapshotsCmd, GetNetworkCmd, GetStoreCmd, GetClusterCmd, GetLeaseCmd)
 }
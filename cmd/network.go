@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+var pruneDryRun bool // List the stale reservations without removing them.
+
+// networkCmd groups the network maintenance subcommands.
+var networkCmd = &cobra.Command{
+	Use:     "network",
+	Aliases: []string{"net"},
+	Short:   "Maintain virtual networks",
+}
+
+var networkPruneReservationsCmd = &cobra.Command{
+	Use:   "prune-reservations [network-name]",
+	Short: "Remove DHCP reservations that belong to no VM",
+	Long: `Remove the DHCP host entries of a network whose address no VM record
+holds, such as those left behind by VMs deleted before kvmcli cleaned
+up after them. Host entries declared in the ip blocks of the network are
+kept. Without a network name, every network is pruned.`,
+	Example: `  kvmcli network prune-reservations homelab-net
+  kvmcli network prune-reservations --dry-run`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var name string
+		if len(args) == 1 {
+			name = args[0]
+		}
+		return operations.PruneReservations(name, pruneDryRun)
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkPruneReservationsCmd)
+
+	networkPruneReservationsCmd.Flags().
+		BoolVar(&pruneDryRun, "dry-run", false, "list the stale reservations without removing them")
+}
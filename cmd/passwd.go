@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var passwdUser string // Guest user whose password is set.
+
+// passwdCmd groups the passwd subcommands.
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Set user passwords inside resources like VMs",
+}
+
+var passwdVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Set a user password inside a virtual machine through the QEMU guest agent",
+	Long: `Set a user password inside a virtual machine through the QEMU guest agent.
+The password is prompted for, or read from the first line of stdin when it
+is not a terminal.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		password, err := readPassword()
+		if err != nil {
+			log.Errorf("read password: %v", err)
+			return
+		}
+		if err := operations.SetVMPassword(args[0], passwdUser, password); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+// readPassword prompts for a password without echo, or reads it from stdin.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "New password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
+
+func init() {
+	passwdVmCmd.Flags().StringVarP(&passwdUser, "user", "u", "root", "guest user")
+	passwdCmd.AddCommand(passwdVmCmd)
+}
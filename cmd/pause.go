@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+// pauseCmd groups the pause subcommands.
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause resources like VMs",
+}
+
+var pauseVmCmd = &cobra.Command{
+	Use:          "vm <vm-name>",
+	Short:        "Pause a running virtual machine, keeping it in memory",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.PauseVM(args[0])
+	},
+}
+
+func init() {
+	pauseCmd.AddCommand(pauseVmCmd)
+}
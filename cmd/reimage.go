@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reimageImage    string          // Store image to boot the VM from.
+	reimageConfirm  bool            // Keep the last re-image and drop the old overlay.
+	reimageRollback bool            // Go back to the overlay the last re-image replaced.
+	reimageStop     vms.StopOptions // How the VM is shut down before its disk is replaced.
+)
+
+// reimageCmd groups the reimage subcommands.
+var reimageCmd = &cobra.Command{
+	Use:   "reimage",
+	Short: "Rebuild resources from a store image",
+}
+
+var reimageVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Boot a virtual machine from a fresh disk on another store image",
+	Long: `Stop a virtual machine, replace its system disk with a fresh overlay on
+the base file of a store image, and start it again. The VM keeps its MAC
+address, IP, DHCP reservation and extra data disks.
+
+The replaced overlay is kept until the re-image is confirmed with
+--confirm, which deletes it, or undone with --rollback, which boots the
+VM from it again. A VM cannot be re-imaged again before that.`,
+	Example: `  kvmcli reimage vm k8s-worker-01 --image rocky-9.6
+  kvmcli reimage vm k8s-worker-01 --confirm
+  kvmcli reimage vm k8s-worker-01 --rollback`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case reimageConfirm:
+			return operations.ConfirmReimage(args[0])
+		case reimageRollback:
+			return operations.RollbackReimage(args[0], reimageStop)
+		case reimageImage == "":
+			return fmt.Errorf("an image is required (--image flag)")
+		}
+		return operations.ReimageVM(args[0], reimageImage, reimageStop)
+	},
+}
+
+func init() {
+	reimageCmd.AddCommand(reimageVmCmd)
+
+	reimageVmCmd.Flags().
+		StringVar(&reimageImage, "image", "", "store image to boot the VM from")
+	reimageVmCmd.Flags().
+		BoolVar(&reimageConfirm, "confirm", false, "keep the last re-image and delete the replaced overlay")
+	reimageVmCmd.Flags().
+		BoolVar(&reimageRollback, "rollback", false, "boot the VM from the overlay the last re-image replaced")
+	reimageVmCmd.Flags().
+		DurationVar(&reimageStop.Timeout, "timeout", vms.DefaultStopTimeout, "how long to wait for a graceful shutdown")
+	reimageVmCmd.Flags().
+		BoolVar(&reimageStop.Force, "force", false, "destroy the VM if it does not shut down in time")
+	reimageVmCmd.MarkFlagsMutuallyExclusive("image", "confirm", "rollback")
+}
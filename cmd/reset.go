@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+// resetCmd groups the reset subcommands.
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset resources like VMs",
+}
+
+var resetVmCmd = &cobra.Command{
+	Use:          "vm <vm-name>",
+	Short:        "Hard-reset a virtual machine",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.ResetVM(args[0])
+	},
+}
+
+func init() {
+	resetCmd.AddCommand(resetVmCmd)
+}
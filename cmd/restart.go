@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/cluster"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restartTimeout time.Duration // How long to wait for a graceful shutdown.
+	restartForce   bool          // Destroy the VM when the graceful shutdown times out.
+)
+
+// restartCmd groups the restart subcommands.
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart resources like VMs",
+}
+
+var restartVmCmd = &cobra.Command{
+	Use:          "vm <vm-name>",
+	Short:        "Stop a virtual machine gracefully and start it again",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.RestartVM(args[0], vms.StopOptions{Timeout: restartTimeout, Force: restartForce})
+	},
+}
+
+var restartClusterCmd = &cobra.Command{
+	Use:          "cluster <cluster-name>",
+	Short:        "Stop a cluster in stop_order and start it again in start_order",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.RestartCluster(
+			ClusterFile,
+			args[0],
+			vms.StopOptions{Timeout: restartTimeout, Force: restartForce},
+			cluster.StartOptions{ReadyTimeout: clusterReadyTimeout},
+		)
+	},
+}
+
+func init() {
+	restartCmd.AddCommand(restartVmCmd)
+	restartCmd.AddCommand(restartClusterCmd)
+
+	restartClusterCmd.Flags().
+		StringVarP(&ClusterFile, "file", "f", "", "Manifest file defining the cluster")
+	restartClusterCmd.Flags().
+		DurationVar(&restartTimeout, "timeout", vms.DefaultStopTimeout, "how long to wait for each VM to shut down")
+	restartClusterCmd.Flags().
+		BoolVar(&restartForce, "force", false, "destroy VMs that do not shut down in time")
+	restartClusterCmd.Flags().
+		DurationVar(&clusterReadyTimeout, "ready-timeout", vms.DefaultReadyTimeout, "how long each group may take to become ready")
+	_ = restartClusterCmd.MarkFlagRequired("file")
+
+	restartVmCmd.Flags().
+		DurationVar(&restartTimeout, "timeout", vms.DefaultStopTimeout, "how long to wait for a graceful shutdown")
+	restartVmCmd.Flags().
+		BoolVar(&restartForce, "force", false, "destroy the VM if it does not shut down in time")
+}
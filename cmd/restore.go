@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kebairia/kvmcli/internal/backup"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreArchive string // Path of the archive to restore.
+	restoreName    string // Restore under a different VM name.
+	restoreStore   string // Target store.
+	restoreNetwork string // Target network.
+	restoreStart   bool   // Start the VM after restoring it.
+)
+
+// restoreCmd re-registers a VM from a backup archive.
+var restoreCmd = &cobra.Command{
+	Use:          "restore -f <archive>",
+	Short:        "Restore a virtual machine from a backup archive",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if restoreArchive == "" {
+			return fmt.Errorf("backup archive is required (-f flag)")
+		}
+		opts := backup.RestoreOptions{
+			Archive: restoreArchive,
+			Name:    restoreName,
+			Store:   restoreStore,
+			Network: restoreNetwork,
+			Start:   restoreStart,
+		}
+		return operations.RestoreVM(opts)
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringVarP(&restoreArchive, "file", "f", "", "backup archive to restore")
+	restoreCmd.Flags().StringVar(&restoreName, "name", "", "restore under a new VM name")
+	restoreCmd.Flags().StringVar(&restoreStore, "store", "", "target store (default: the original)")
+	restoreCmd.Flags().StringVar(&restoreNetwork, "network", "", "target network (default: the original)")
+	restoreCmd.Flags().BoolVar(&restoreStart, "start", false, "start the VM once restored")
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd groups the resume subcommands.
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume resources like VMs",
+}
+
+var resumeVmCmd = &cobra.Command{
+	Use:          "vm <vm-name>",
+	Short:        "Resume a paused virtual machine",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.ResumeVM(args[0])
+	},
+}
+
+func init() {
+	resumeCmd.AddCommand(resumeVmCmd)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kebairia/kvmcli/internal/cluster"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rolloutOpts   cluster.RolloutOptions // Action and settings of a new rollout.
+	rolloutResume bool                   // Continue the paused rollout of the cluster.
+	rolloutAbort  bool                   // Drop the paused rollout of the cluster.
+	rolloutNS     string                 // Namespace of the cluster whose rollout is aborted.
+)
+
+// rolloutCmd groups the rollout subcommands.
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Roll an operation over the VMs of a cluster, a few at a time",
+}
+
+var rolloutClusterCmd = &cobra.Command{
+	Use:   "cluster <cluster-name>",
+	Short: "Restart, re-image or resize the VMs of a cluster a batch at a time",
+	Long: `Restart, re-image or resize the VMs of a cluster defined in a manifest file
+without taking the whole cluster down. Roles are rolled in the lifecycle
+start_order, in batches of at most max_unavailable VMs of the same role,
+and every VM of a batch must pass its healthcheck before the next batch
+is taken down.
+
+When a batch fails the rollout is paused and the remaining VMs are left
+alone; fix the cause, then run it again with --resume, or drop it with
+--abort.`,
+	Example: `  kvmcli rollout cluster k8s -f k8s.hcl --action reimage --image rocky-9.6
+  kvmcli rollout cluster k8s -f k8s.hcl --action resize --role workers --cpu 4 --memory 8192
+  kvmcli rollout cluster k8s -f k8s.hcl --resume`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case rolloutAbort:
+			return operations.AbortRollout(args[0], rolloutNS)
+		case ClusterFile == "":
+			return fmt.Errorf("manifest file is required (-f flag)")
+		case rolloutResume:
+			return operations.ResumeRollout(ClusterFile, args[0])
+		}
+		return operations.RolloutCluster(ClusterFile, args[0], rolloutOpts)
+	},
+}
+
+func init() {
+	rolloutCmd.AddCommand(rolloutClusterCmd)
+
+	rolloutClusterCmd.Flags().
+		StringVarP(&ClusterFile, "file", "f", "", "Manifest file defining the cluster")
+	rolloutClusterCmd.Flags().
+		StringVar(&rolloutOpts.Action, "action", cluster.ActionRestart, "operation to roll out: restart, reimage or resize")
+	rolloutClusterCmd.Flags().
+		StringVar(&rolloutOpts.Image, "image", "", "store image to re-image the VMs from")
+	rolloutClusterCmd.Flags().
+		IntVar(&rolloutOpts.CPU, "cpu", 0, "vCPU count to resize the VMs to")
+	rolloutClusterCmd.Flags().
+		IntVar(&rolloutOpts.Memory, "memory", 0, "memory (MiB) to resize the VMs to")
+	rolloutClusterCmd.Flags().
+		IntVar(&rolloutOpts.MaxUnavailable, "max-unavailable", 0, "VMs of a role taken down at once (default: lifecycle max_unavailable, or 1)")
+	rolloutClusterCmd.Flags().
+		StringSliceVar(&rolloutOpts.Roles, "role", nil, "roll only these roles (default: every role)")
+	rolloutClusterCmd.Flags().
+		DurationVar(&rolloutOpts.Stop.Timeout, "timeout", vms.DefaultStopTimeout, "how long to wait for each VM to shut down")
+	rolloutClusterCmd.Flags().
+		BoolVar(&rolloutOpts.Stop.Force, "force", false, "destroy VMs that do not shut down in time")
+	rolloutClusterCmd.Flags().
+		DurationVar(&rolloutOpts.ReadyTimeout, "ready-timeout", vms.DefaultReadyTimeout, "how long each VM may take to become ready")
+	rolloutClusterCmd.Flags().
+		BoolVar(&rolloutResume, "resume", false, "continue the paused rollout of the cluster")
+	rolloutClusterCmd.Flags().
+		BoolVar(&rolloutAbort, "abort", false, "drop the paused rollout of the cluster")
+	rolloutClusterCmd.Flags().
+		StringVarP(&rolloutNS, "namespace", "n", "", "namespace of the cluster, for --abort without a manifest")
+	rolloutClusterCmd.MarkFlagsMutuallyExclusive("resume", "abort")
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	log "github.com/kebairia/kvmcli/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -21,20 +23,42 @@ var rootCmd = &cobra.Command{
 	Use:   "kvmcli",
 	Short: "A CLI for managing KVM virtual machines",
 	Long:  "A CLI similar to kubectl for creating, deleting, and managing KVM VMs.",
+	// Execute reports errors itself.
+	SilenceErrors: true,
 }
 
-// Execute runs the root command.
+// Execute runs the root command and exits non-zero when it fails.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Errorf("Error executing command: %v", err)
+		log.Errorf("%v", err)
+		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.AddCommand(CreateCmd)
 	rootCmd.AddCommand(DeleteCmd)
+	rootCmd.AddCommand(UpdateCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(suspendCmd)
+	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(waitCmd)
+	rootCmd.AddCommand(rolloutCmd)
 	rootCmd.AddCommand(GetCmd)
 	rootCmd.AddCommand(InitVMCmd)
+	rootCmd.AddCommand(consoleCmd)
+	rootCmd.AddCommand(sshCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(passwdCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(reimageCmd)
+	rootCmd.AddCommand(networkCmd)
+	rootCmd.AddCommand(autostartCmd)
 }
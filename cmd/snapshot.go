@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotName        string // Name of the snapshot to create, revert to or delete.
+	snapshotDescription string // Free-form description stored with the snapshot.
+	snapshotDiskOnly    bool   // Take an external disk-only snapshot.
+)
+
+// snapshotCmd groups the snapshot subcommands.
+var snapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snap"},
+	Short:   "Manage snapshots of resources like VMs",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Take a snapshot",
+}
+
+var snapshotCreateVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Take a snapshot of a virtual machine",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := vms.SnapshotOptions{
+			Name:        snapshotName,
+			Description: snapshotDescription,
+			DiskOnly:    snapshotDiskOnly,
+		}
+		if err := operations.CreateSnapshot(args[0], opts); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List snapshots",
+}
+
+var snapshotListVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "List the snapshots of a virtual machine",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := operations.ListSnapshots(args[0]); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+var snapshotRevertCmd = &cobra.Command{
+	Use:   "revert",
+	Short: "Revert to a snapshot",
+}
+
+var snapshotRevertVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Revert a virtual machine to one of its snapshots",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := operations.RevertSnapshot(args[0], snapshotName); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a snapshot",
+}
+
+var snapshotDeleteVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Delete a snapshot of a virtual machine",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := operations.DeleteSnapshot(args[0], snapshotName); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+func init() {
+	snapshotCreateVmCmd.Flags().
+		StringVarP(&snapshotName, "name", "s", "", "Snapshot name (defaults to a timestamp)")
+	snapshotCreateVmCmd.Flags().
+		StringVarP(&snapshotDescription, "description", "d", "", "Snapshot description")
+	snapshotCreateVmCmd.Flags().
+		BoolVar(&snapshotDiskOnly, "disk-only", false, "Take an external disk-only snapshot")
+
+	snapshotRevertVmCmd.Flags().
+		StringVarP(&snapshotName, "name", "s", "", "Snapshot to revert to")
+	snapshotRevertVmCmd.MarkFlagRequired("name")
+
+	snapshotDeleteVmCmd.Flags().
+		StringVarP(&snapshotName, "name", "s", "", "Snapshot to delete")
+	snapshotDeleteVmCmd.MarkFlagRequired("name")
+
+	snapshotCreateCmd.AddCommand(snapshotCreateVmCmd)
+	snapshotListCmd.AddCommand(snapshotListVmCmd)
+	snapshotRevertCmd.AddCommand(snapshotRevertVmCmd)
+	snapshotDeleteCmd.AddCommand(snapshotDeleteVmCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRevertCmd, snapshotDeleteCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+var sshUser string // Remote user, overrides the image and namespace defaults.
+
+// sshCmd groups the SSH subcommands.
+var sshCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "Open an SSH session to resources like VMs",
+}
+
+var sshVmCmd = &cobra.Command{
+	Use:   "vm <vm-name> [-- command...]",
+	Short: "SSH into a virtual machine using the IP known to kvmcli",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := operations.SSHVM(args[0], sshUser, args[1:])
+
+		// Propagate the remote command's exit status.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		if err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+func init() {
+	sshVmCmd.Flags().
+		StringVarP(&sshUser, "user", "l", "", "Remote user (defaults to the image user)")
+	sshCmd.AddCommand(sshVmCmd)
+}
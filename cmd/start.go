@@ -1,14 +1,18 @@
 package cmd
 
 import (
-	"context"
-	"fmt"
+	"time"
 
-	"github.com/kebairia/kvmcli/internal"
+	"github.com/kebairia/kvmcli/internal/cluster"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
 	"github.com/kebairia/kvmcli/internal/vms"
 	"github.com/spf13/cobra"
 )
 
+// clusterReadyTimeout is how long each cluster group may take to become ready.
+var clusterReadyTimeout time.Duration
+
 // CreateCmd represents the command to create resource(s) from a manifest file.
 var startCmd = &cobra.Command{
 	Use:   "start",
@@ -20,20 +24,39 @@ var startVmCmd = &cobra.Command{
 	Short: "Start a virtual machine",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		vmName := args[0]
-		conn, err := internal.InitConnection()
-		if err != nil {
-			fmt.Println("init libvirt: %w", err)
+		if err := operations.StartVM(args[0]); err != nil {
+			log.Errorf("%v", err)
 		}
-		// TODO: Add your VM starting logic here
-		ctx := context.Background()
-		dom := vms.NewLibvirtDomainManager(conn)
-		dom.Start(ctx, vmName)
-		fmt.Printf("vm/%s started\n", vmName)
+	},
+}
+
+var startClusterCmd = &cobra.Command{
+	Use:   "cluster <cluster-name>",
+	Short: "Start the VMs of a cluster in start_order",
+	Long: `Start the VMs of a cluster defined in a manifest file. Roles are started
+in the lifecycle start_order, the VMs of a role in parallel, and a role
+must be ready before the next one is started: its VMs pass their
+healthcheck or, without one, the guest agent answers or a DHCP lease
+is taken.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.StartCluster(
+			ClusterFile,
+			args[0],
+			cluster.StartOptions{ReadyTimeout: clusterReadyTimeout},
+		)
 	},
 }
 
 func init() {
 	// Bind the manifest file flag to the global variable.
 	startCmd.AddCommand(startVmCmd)
+	startCmd.AddCommand(startClusterCmd)
+
+	startClusterCmd.Flags().
+		StringVarP(&ClusterFile, "file", "f", "", "Manifest file defining the cluster")
+	startClusterCmd.Flags().
+		DurationVar(&clusterReadyTimeout, "ready-timeout", vms.DefaultReadyTimeout, "how long each group may take to become ready")
+	_ = startClusterCmd.MarkFlagRequired("file")
 }
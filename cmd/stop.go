@@ -1,15 +1,19 @@
 package cmd
 
 import (
-	"context"
-	"fmt"
+	"time"
 
-	"github.com/kebairia/kvmcli/internal"
+	"github.com/kebairia/kvmcli/internal/operations"
 	"github.com/kebairia/kvmcli/internal/vms"
 	"github.com/spf13/cobra"
 )
 
-// CreateCmd represents the command to create resource(s) from a manifest file.
+var (
+	stopTimeout time.Duration // How long to wait for a graceful shutdown.
+	stopForce   bool          // Destroy the VM when the graceful shutdown times out.
+)
+
+// stopCmd groups the stop subcommands.
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop resources like VMs",
@@ -18,24 +22,44 @@ var stopCmd = &cobra.Command{
 var stopVmCmd = &cobra.Command{
 	Use:   "vm <vm-name>",
 	Short: "Stop a virtual machine",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		vmName := args[0]
-		conn, err := internal.InitConnection()
-		if err != nil {
-			fmt.Println("init libvirt: %w", err)
-		}
-		// TODO: Add your VM stopping logic here
-		ctx := context.Background()
-		dom := vms.NewLibvirtDomainManager(conn)
-		dom.Stop(ctx, vmName)
-		fmt.Printf("vm/%s stopped\n", vmName)
-		// Create a new vm object
-		// then start this object using the name of this vm
+	Long: `Stop a virtual machine: request an ACPI shutdown, then a guest agent
+shutdown, and wait for the VM to be shut off. With --force the VM is
+destroyed when it is still running once the timeout expires.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.StopVM(args[0], vms.StopOptions{Timeout: stopTimeout, Force: stopForce})
+	},
+}
+
+var stopClusterCmd = &cobra.Command{
+	Use:   "cluster <cluster-name>",
+	Short: "Stop the VMs of a cluster in stop_order",
+	Long: `Stop the VMs of a cluster defined in a manifest file. Roles are stopped
+in the lifecycle stop_order (the reverse of start_order by default), the
+VMs of a role in parallel, and a role must be shut off before the next
+one is stopped.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.StopCluster(ClusterFile, args[0], vms.StopOptions{Timeout: stopTimeout, Force: stopForce})
 	},
 }
 
 func init() {
-	// Bind the manifest file flag to the global variable.
 	stopCmd.AddCommand(stopVmCmd)
+	stopCmd.AddCommand(stopClusterCmd)
+
+	stopClusterCmd.Flags().
+		StringVarP(&ClusterFile, "file", "f", "", "Manifest file defining the cluster")
+	stopClusterCmd.Flags().
+		DurationVar(&stopTimeout, "timeout", vms.DefaultStopTimeout, "how long to wait for each VM to shut down")
+	stopClusterCmd.Flags().
+		BoolVar(&stopForce, "force", false, "destroy VMs that do not shut down in time")
+	_ = stopClusterCmd.MarkFlagRequired("file")
+
+	stopVmCmd.Flags().
+		DurationVar(&stopTimeout, "timeout", vms.DefaultStopTimeout, "how long to wait for a graceful shutdown")
+	stopVmCmd.Flags().
+		BoolVar(&stopForce, "force", false, "destroy the VM if it does not shut down in time")
 }
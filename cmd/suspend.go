@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+)
+
+// suspendCmd groups the suspend subcommands.
+var suspendCmd = &cobra.Command{
+	Use:   "suspend",
+	Short: "Suspend resources like VMs",
+}
+
+var suspendVmCmd = &cobra.Command{
+	Use:          "vm <vm-name>",
+	Short:        "Save a virtual machine's state to disk and stop it",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.SuspendVM(args[0])
+	},
+}
+
+func init() {
+	suspendCmd.AddCommand(suspendVmCmd)
+}
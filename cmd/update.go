@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var updateYes bool // Redefine or restart networks without asking.
+
+// UpdateCmd represents the command to apply manifest changes to existing resource(s).
+var UpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update existing resource(s) from a manifest file",
+	Long: `Update existing resource(s) from a manifest file.
+VM cpu and memory changes are applied live within max_cpu and max_memory;
+other changes take effect on the next boot and the VM is flagged as
+"restart required" in 'get vm'.
+
+Network DHCP ranges, static hosts and DNS records are changed in place.
+Other network changes need the network to be redefined, and restarted
+when it is running, which cuts its VMs off; kvmcli asks first, or goes
+ahead with --yes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if ManifestPath == "" {
+			log.Errorf("Manifest file is required (-f flag)")
+			return
+		}
+
+		if err := operations.UpdateFromManifest(ManifestPath, confirmUpdate); err != nil {
+			log.Errorf("%v", err)
+		}
+	},
+}
+
+// confirmUpdate asks on the terminal whether to go ahead with a disruptive
+// change. Without a terminal, only --yes confirms.
+func confirmUpdate(prompt string) bool {
+	if updateYes {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	UpdateCmd.Flags().
+		StringVarP(&ManifestPath, "file", "f", "", "Configuration file for the resource(s)")
+	UpdateCmd.Flags().
+		BoolVarP(&updateYes, "yes", "y", false, "redefine or restart networks without asking")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/operations"
+	"github.com/kebairia/kvmcli/internal/vms"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitFor     string        // Condition to wait for.
+	waitTimeout time.Duration // How long to wait.
+)
+
+// waitCmd groups the wait subcommands.
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait for resources like VMs to reach a condition",
+}
+
+var waitVmCmd = &cobra.Command{
+	Use:   "vm <vm-name>",
+	Short: "Wait for a virtual machine to be ready, running or stopped",
+	Long: `Wait for a virtual machine to reach a condition:
+
+  ready    running and passing its healthcheck (without one: the guest
+           agent answers or a DHCP lease is taken)
+  running  the domain is running
+  stopped  the domain is shut off
+
+The exit code is non-zero when the condition is not met in time.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return operations.WaitVM(args[0], waitFor, waitTimeout)
+	},
+}
+
+func init() {
+	waitCmd.AddCommand(waitVmCmd)
+
+	waitVmCmd.Flags().
+		StringVar(&waitFor, "for", operations.WaitForReady, "condition to wait for: ready, running or stopped")
+	waitVmCmd.Flags().
+		DurationVar(&waitTimeout, "timeout", vms.DefaultReadyTimeout, "how long to wait")
+}
@@ -155,7 +155,8 @@ vm "k8s-worker-01" {
   tags = ["k8s-prod", "worker"]
 }
 
-// Cluster auto-discovers VMs with matching tag
+// Cluster auto-discovers VMs with matching tag (not supported: VMs have
+// labels, not tags; use select with a label selector, see below)
 cluster "k8s-prod" {
   discover = "tag:k8s-prod"
   
@@ -179,10 +180,14 @@ cluster "k8s-prod" {
     }
   }
 }
+*/
 
-// Or even simpler selector syntax
+// Selector syntax (supported): roles can be label selectors too
+/*
 cluster "k8s-prod" {
-  select = "app=kubernetes,environment=production"
+  select  = "app=kubernetes,environment=production"
+  masters = "role=master"
+  workers = "role in (worker)"
 }
 */
 
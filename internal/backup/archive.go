@@ -0,0 +1,185 @@
+// Package backup packs a VM into a portable .tar.zst archive and restores it.
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archive entries, written in this order so a restore can stream them.
+const (
+	manifestFile = "manifest.json"
+	recordFile   = "record.json"
+	domainFile   = "domain.xml"
+	hclFile      = "vm.hcl"
+	diskFile     = "disk.qcow2"
+	baseFile     = "base.qcow2"
+)
+
+// manifestVersion is bumped whenever the archive layout changes.
+const manifestVersion = 1
+
+// Manifest describes the content of a backup archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	VM        string    `json:"vm"`
+	Namespace string    `json:"namespace"`
+	Network   string    `json:"network"`
+	Store     string    `json:"store"`
+	Image     string    `json:"image"`
+	Live      bool      `json:"live"`      // taken with DomainBackupBegin while running
+	Flattened bool      `json:"flattened"` // disk.qcow2 has no backing file
+	HasBase   bool      `json:"has_base"`  // base.qcow2 is included
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// archiveWriter writes a zstd-compressed tarball.
+type archiveWriter struct {
+	file *os.File
+	zw   *zstd.Encoder
+	tw   *tar.Writer
+}
+
+func newArchiveWriter(path string) (*archiveWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create archive %q: %w", path, err)
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("init zstd writer: %w", err)
+	}
+	return &archiveWriter{file: f, zw: zw, tw: tar.NewWriter(zw)}, nil
+}
+
+// addJSON stores v as an indented JSON entry.
+func (a *archiveWriter) addJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", name, err)
+	}
+	return a.addBytes(name, data)
+}
+
+func (a *archiveWriter) addBytes(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write %s header: %w", name, err)
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// addFile streams the file at path into the archive under name.
+func (a *archiveWriter) addFile(name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %q: %w", path, err)
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write %s header: %w", name, err)
+	}
+	if _, err := io.Copy(a.tw, f); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close flushes every layer and closes the file.
+func (a *archiveWriter) Close() error {
+	return errors.Join(a.tw.Close(), a.zw.Close(), a.file.Close())
+}
+
+// archiveReader reads the entries of a zstd-compressed tarball in order.
+type archiveReader struct {
+	file *os.File
+	zr   *zstd.Decoder
+	tr   *tar.Reader
+}
+
+func newArchiveReader(path string) (*archiveReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive %q: %w", path, err)
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("init zstd reader: %w", err)
+	}
+	return &archiveReader{file: f, zr: zr, tr: tar.NewReader(zr)}, nil
+}
+
+// readManifest reads the manifest, which is the first entry of an archive.
+func readManifest(a *archiveReader, path string) (Manifest, error) {
+	var manifest Manifest
+	if name, err := a.next(); err != nil || name != manifestFile {
+		return manifest, fmt.Errorf("%q is not a kvmcli backup archive", path)
+	}
+	if err := a.readJSON(&manifest); err != nil {
+		return manifest, fmt.Errorf("read %s: %w", manifestFile, err)
+	}
+	if manifest.Version != manifestVersion {
+		return manifest, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// next advances to the next entry and returns its name, or io.EOF at the end.
+func (a *archiveReader) next() (string, error) {
+	hdr, err := a.tr.Next()
+	if err != nil {
+		return "", err
+	}
+	return hdr.Name, nil
+}
+
+// readJSON decodes the current entry into v.
+func (a *archiveReader) readJSON(v any) error {
+	return json.NewDecoder(a.tr).Decode(v)
+}
+
+// extract writes the current entry to path.
+func (a *archiveReader) extract(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %q: %w", path, err)
+	}
+	if _, err := io.Copy(f, a.tr); err != nil {
+		f.Close()
+		return fmt.Errorf("extract to %q: %w", path, err)
+	}
+	return f.Close()
+}
+
+func (a *archiveReader) Close() error {
+	a.zr.Close()
+	return a.file.Close()
+}
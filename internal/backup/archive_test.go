@@ -0,0 +1,226 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	diskPath := filepath.Join(dir, "web-01.qcow2")
+	if err := os.WriteFile(diskPath, []byte("disk content"), 0o600); err != nil {
+		t.Fatalf("write disk: %v", err)
+	}
+	basePath := filepath.Join(dir, "rocky.qcow2")
+	if err := os.WriteFile(basePath, []byte("base content"), 0o600); err != nil {
+		t.Fatalf("write base: %v", err)
+	}
+
+	manifest := Manifest{
+		Version:   manifestVersion,
+		VM:        "web-01",
+		Namespace: "homelab",
+		Network:   "services",
+		Store:     "default",
+		Image:     "rocky-9",
+		HasBase:   true,
+		CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	record := db.VirtualMachine{Name: "web-01", Namespace: "homelab", IP: "192.168.100.10"}
+
+	path := filepath.Join(dir, "web-01.tar.zst")
+	archive, err := newArchiveWriter(path)
+	if err != nil {
+		t.Fatalf("newArchiveWriter failed: %v", err)
+	}
+	if err := writeArchive(archive, manifest, record, "<domain/>", []byte("vm \"web-01\" {}\n"), diskPath, basePath); err != nil {
+		t.Fatalf("writeArchive failed: %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	// An archive is never overwritten.
+	if _, err := newArchiveWriter(path); err == nil {
+		t.Error("newArchiveWriter on an existing archive succeeded")
+	}
+
+	reader, err := newArchiveReader(path)
+	if err != nil {
+		t.Fatalf("newArchiveReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := readManifest(reader, path)
+	if err != nil {
+		t.Fatalf("readManifest failed: %v", err)
+	}
+	if got != manifest {
+		t.Errorf("manifest = %+v, want %+v", got, manifest)
+	}
+
+	if name, err := reader.next(); err != nil || name != recordFile {
+		t.Fatalf("second entry = %q, %v, want %s", name, err, recordFile)
+	}
+	var gotRecord db.VirtualMachine
+	if err := reader.readJSON(&gotRecord); err != nil {
+		t.Fatalf("read record: %v", err)
+	}
+	if gotRecord.Name != record.Name || gotRecord.IP != record.IP {
+		t.Errorf("record = %+v, want %+v", gotRecord, record)
+	}
+
+	restored := filepath.Join(dir, "restored")
+	if err := os.Mkdir(restored, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	want := map[string]string{
+		domainFile: "<domain/>",
+		hclFile:    "vm \"web-01\" {}\n",
+		diskFile:   "disk content",
+		baseFile:   "base content",
+	}
+	for _, entry := range []string{domainFile, hclFile, diskFile, baseFile} {
+		name, err := reader.next()
+		if err != nil || name != entry {
+			t.Fatalf("entry = %q, %v, want %s", name, err, entry)
+		}
+		out := filepath.Join(restored, name)
+		if err := reader.extract(out); err != nil {
+			t.Fatalf("extract %s: %v", name, err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(data) != want[name] {
+			t.Errorf("%s = %q, want %q", name, data, want[name])
+		}
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	write := func(t *testing.T, first string, v any) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "archive.tar.zst")
+		archive, err := newArchiveWriter(path)
+		if err != nil {
+			t.Fatalf("newArchiveWriter failed: %v", err)
+		}
+		if err := archive.addJSON(first, v); err != nil {
+			t.Fatalf("addJSON failed: %v", err)
+		}
+		if err := archive.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name  string
+		first string
+		v     any
+		want  string
+	}{
+		{"manifest not first", recordFile, db.VirtualMachine{Name: "web-01"}, "not a kvmcli backup archive"},
+		{"newer layout", manifestFile, Manifest{Version: manifestVersion + 1}, "unsupported backup version"},
+		{"not a manifest", manifestFile, []string{"web-01"}, "read manifest.json"},
+	}
+	for _, tt := range tests {
+		path := write(t, tt.first, tt.v)
+		reader, err := newArchiveReader(path)
+		if err != nil {
+			t.Fatalf("%s: newArchiveReader failed: %v", tt.name, err)
+		}
+		_, err = readManifest(reader, path)
+		reader.Close()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: readManifest error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestShareWithQemu(t *testing.T) {
+	pool := t.TempDir()
+	if err := os.Chmod(pool, 0o751); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	staging, err := os.MkdirTemp(pool, ".kvmcli-backup-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	if err := shareWithQemu(staging, pool); err != nil {
+		t.Fatalf("shareWithQemu failed: %v", err)
+	}
+	info, err := os.Stat(staging)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	// The pool belongs to us, so the staging directory takes its mode.
+	if got := info.Mode().Perm(); got != 0o751 {
+		t.Errorf("staging mode = %o, want 751", got)
+	}
+}
+
+// TestRestoreDeleteRestore checks that deleting a restored VM removes the
+// backing image its restore extracted, so that it can be restored again.
+func TestRestoreDeleteRestore(t *testing.T) {
+	dir := t.TempDir()
+	diskPath := filepath.Join(dir, "web-01.qcow2")
+	basePath := filepath.Join(dir, "rocky.qcow2")
+	for path, content := range map[string]string{diskPath: "disk content", basePath: "base content"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	path := filepath.Join(dir, "web-01.tar.zst")
+	archive, err := newArchiveWriter(path)
+	if err != nil {
+		t.Fatalf("newArchiveWriter failed: %v", err)
+	}
+	manifest := Manifest{Version: manifestVersion, VM: "web-01", HasBase: true}
+	record := db.VirtualMachine{Name: "web-01"}
+	if err := writeArchive(archive, manifest, record, "<domain/>", nil, diskPath, basePath); err != nil {
+		t.Fatalf("writeArchive failed: %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	images := filepath.Join(dir, "images")
+	if err := os.Mkdir(images, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	restoredDisk := filepath.Join(images, "web-01.qcow2")
+	restore := func() {
+		t.Helper()
+		reader, err := newArchiveReader(path)
+		if err != nil {
+			t.Fatalf("newArchiveReader failed: %v", err)
+		}
+		defer reader.Close()
+		if _, err := readManifest(reader, path); err != nil {
+			t.Fatalf("readManifest failed: %v", err)
+		}
+		if _, _, _, err := unpack(reader, restoredDisk, vms.BaseImagePath(restoredDisk)); err != nil {
+			t.Fatalf("unpack failed: %v", err)
+		}
+	}
+
+	restore()
+	if err := vms.DeleteDisk(context.Background(), &vms.QemuDiskManager{}, restoredDisk); err != nil {
+		t.Fatalf("DeleteDisk failed: %v", err)
+	}
+	for _, leftover := range []string{restoredDisk, vms.BaseImagePath(restoredDisk)} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s left behind after delete", leftover)
+		}
+	}
+	restore()
+}
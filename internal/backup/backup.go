@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/templates"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// jobPollInterval is how often a running backup job is checked.
+const jobPollInterval = time.Second
+
+// Options controls what goes into a backup archive.
+type Options struct {
+	Output      string // path of the .tar.zst archive to create
+	Flatten     bool   // merge the backing image into the disk instead of copying the overlay
+	IncludeBase bool   // ship the backing image next to an unflattened overlay
+}
+
+// Backup writes a portable archive of a VM: its disk, domain XML, database
+// record and an HCL manifest. Running VMs are backed up live with
+// DomainBackupBegin, which always yields a flattened disk; stopped VMs are
+// copied offline.
+func Backup(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	vmName string,
+	opts Options,
+) error {
+	var record db.VirtualMachine
+	if err := record.GetRecord(ctx, database, vmName); err != nil {
+		return err
+	}
+	networkName, err := db.GetNetworkNameByID(ctx, database, record.NetworkID)
+	if err != nil {
+		return err
+	}
+	storeName, err := db.GetStoreNameByID(ctx, database, record.StoreID)
+	if err != nil {
+		return err
+	}
+
+	dom, err := conn.DomainLookupByName(vmName)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", vmName, err)
+	}
+	domainXML, err := conn.DomainGetXMLDesc(dom, libvirt.DomainXMLInactive)
+	if err != nil {
+		return fmt.Errorf("get XML for %q: %w", vmName, err)
+	}
+	active, err := conn.DomainIsActive(dom)
+	if err != nil {
+		return fmt.Errorf("get state for %q: %w", vmName, err)
+	}
+
+	// Stage temporary disks next to the VM disk, where qemu is allowed to write.
+	staging, err := os.MkdirTemp(filepath.Dir(record.DiskPath), ".kvmcli-backup-")
+	if err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := shareWithQemu(staging, filepath.Dir(record.DiskPath)); err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		Version:   manifestVersion,
+		VM:        record.Name,
+		Namespace: record.Namespace,
+		Network:   networkName,
+		Store:     storeName,
+		Image:     record.Image,
+		CreatedAt: time.Now(),
+	}
+
+	// External snapshots leave the domain writing to an overlay on top of
+	// the recorded disk, which then misses every write since the snapshot.
+	source, err := vms.ParseDiskSource(domainXML, templates.TargetDevVDA)
+	if err != nil {
+		return fmt.Errorf("vm %q: %w", vmName, err)
+	}
+
+	disk := &vms.QemuDiskManager{}
+	diskPath := source
+	switch {
+	case active == 1:
+		diskPath = filepath.Join(staging, diskFile)
+		if err := liveBackup(ctx, conn, dom, diskPath); err != nil {
+			return err
+		}
+		manifest.Live = true
+		manifest.Flattened = true
+	case opts.Flatten:
+		diskPath = filepath.Join(staging, diskFile)
+		if err := disk.Flatten(ctx, source, diskPath); err != nil {
+			return err
+		}
+		manifest.Flattened = true
+	case source != record.DiskPath:
+		// The archive holds a single overlay, not a chain of them.
+		return fmt.Errorf("vm %q runs on the snapshot overlay %s; back it up with --flatten", vmName, source)
+	}
+
+	var basePath string
+	if opts.IncludeBase && !manifest.Flattened {
+		img, err := db.GetImage(ctx, database, record.Image)
+		if err != nil {
+			return fmt.Errorf("fetch image %q: %w", record.Image, err)
+		}
+		basePath = filepath.Join(img.ArtifactsPath, img.ImageFile)
+		manifest.HasBase = true
+	}
+
+	hcl := vms.NewHCLFile(record, networkName, storeName).Bytes()
+
+	archive, err := newArchiveWriter(opts.Output)
+	if err != nil {
+		return err
+	}
+	if err := writeArchive(archive, manifest, record, domainXML, hcl, diskPath, basePath); err != nil {
+		archive.Close()
+		os.Remove(opts.Output)
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		os.Remove(opts.Output)
+		return fmt.Errorf("finalize archive %q: %w", opts.Output, err)
+	}
+	return nil
+}
+
+func writeArchive(
+	archive *archiveWriter,
+	manifest Manifest,
+	record db.VirtualMachine,
+	domainXML string,
+	hcl []byte,
+	diskPath, basePath string,
+) error {
+	if err := archive.addJSON(manifestFile, manifest); err != nil {
+		return err
+	}
+	if err := archive.addJSON(recordFile, record); err != nil {
+		return err
+	}
+	if err := archive.addBytes(domainFile, []byte(domainXML)); err != nil {
+		return err
+	}
+	if err := archive.addBytes(hclFile, hcl); err != nil {
+		return err
+	}
+	if err := archive.addFile(diskFile, diskPath); err != nil {
+		return err
+	}
+	if basePath != "" {
+		if err := archive.addFile(baseFile, basePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// liveBackup pushes a full copy of the running domain's disk into target
+// and waits for the backup job to finish.
+func liveBackup(ctx context.Context, conn *libvirt.Libvirt, dom libvirt.Domain, target string) error {
+	xmlConfig, err := templates.NewPushBackup(templates.TargetDevVDA, target).GenerateXML()
+	if err != nil {
+		return fmt.Errorf("generate backup XML for %q: %w", dom.Name, err)
+	}
+	if err := conn.DomainBackupBegin(dom, string(xmlConfig), nil, 0); err != nil {
+		return fmt.Errorf("begin backup of %q: %w", dom.Name, err)
+	}
+	log.Debugf("live backup of %s started into %s", dom.Name, target)
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.DomainAbortJob(dom)
+			return fmt.Errorf("backup of %q aborted: %w", dom.Name, ctx.Err())
+		case <-ticker.C:
+		}
+
+		jobType, _, _, _, _, _, _, _, _, _, _, _, err := conn.DomainGetJobInfo(dom)
+		if err != nil {
+			return fmt.Errorf("get backup job of %q: %w", dom.Name, err)
+		}
+		if libvirt.DomainJobType(jobType) == libvirt.DomainJobNone {
+			break
+		}
+	}
+
+	jobType, _, err := conn.DomainGetJobStats(dom, libvirt.DomainJobStatsCompleted)
+	if err != nil {
+		return fmt.Errorf("get backup result of %q: %w", dom.Name, err)
+	}
+	if libvirt.DomainJobType(jobType) != libvirt.DomainJobCompleted {
+		return fmt.Errorf("backup job of %q did not complete", dom.Name)
+	}
+	return nil
+}
+
+// shareWithQemu gives the staging directory the ownership and mode of the
+// pool directory it lives in, so that qemu, which runs as its own user, can
+// write the live backup there. MkdirTemp creates it 0700 for the caller.
+// When it cannot be handed over, the directory is opened to everyone like
+// /tmp, for as long as the backup lasts.
+func shareWithQemu(staging, pool string) error {
+	info, err := os.Stat(pool)
+	if err != nil {
+		return fmt.Errorf("stat pool directory %q: %w", pool, err)
+	}
+	mode := info.Mode().Perm()
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if err := os.Chown(staging, int(st.Uid), int(st.Gid)); err != nil {
+			log.Debugf("cannot give %s the ownership of %s: %v", staging, pool, err)
+			mode = os.ModeSticky | 0o777
+		}
+	}
+	if err := os.Chmod(staging, mode); err != nil {
+		return fmt.Errorf("set mode of staging directory: %w", err)
+	}
+	return nil
+}
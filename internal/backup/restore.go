@@ -0,0 +1,274 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/network"
+	"github.com/kebairia/kvmcli/internal/templates"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// RestoreOptions controls where a backup archive is restored.
+type RestoreOptions struct {
+	Archive string // path of the .tar.zst archive
+	Name    string // restore under a new name; defaults to the original
+	Store   string // target store; defaults to the original
+	Network string // target network; defaults to the original
+	Start   bool   // power on the VM once it is registered
+}
+
+// Restore re-registers a VM from a backup archive: the disk is placed in the
+// target store, the domain is defined on the target network and the VM
+// record is inserted. Returns the name of the restored VM.
+func Restore(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	opts RestoreOptions,
+) (string, error) {
+	archive, err := newArchiveReader(opts.Archive)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	var (
+		manifest  Manifest
+		record    db.VirtualMachine
+		domainXML []byte
+		cleanups  []func() error
+	)
+	rollback := func(step string, origin error) (string, error) {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if err := cleanups[i](); err != nil {
+				log.Warnf("rollback failed, step %s, err %s", step, err)
+			}
+		}
+		return "", fmt.Errorf("failed at %s: %w", step, origin)
+	}
+
+	// The manifest comes first and tells us where everything else goes.
+	if manifest, err = readManifest(archive, opts.Archive); err != nil {
+		return "", err
+	}
+
+	name := firstNonEmpty(opts.Name, manifest.VM)
+	storeName := firstNonEmpty(opts.Store, manifest.Store)
+	networkName := firstNonEmpty(opts.Network, manifest.Network)
+
+	if err := ensureAbsent(ctx, conn, database, name); err != nil {
+		return "", err
+	}
+	store, err := db.GetStoreByName(ctx, database, storeName)
+	if err != nil {
+		return "", err
+	}
+	networkID, err := db.GetNetworkIDByName(ctx, database, networkName)
+	if err != nil {
+		return "", err
+	}
+
+	diskPath := filepath.Join(store.ImagesPath, name+".qcow2")
+	basePath := vms.BaseImagePath(diskPath)
+
+	record, domainXML, cleanups, err = unpack(archive, diskPath, basePath)
+	if err != nil {
+		return "", err
+	}
+	if record.Name == "" {
+		return rollback("read archive", fmt.Errorf("%s is missing", recordFile))
+	}
+	// A renamed copy would share the original's MAC and reserved IP.
+	if name != record.Name {
+		var original db.VirtualMachine
+		if err := original.GetRecord(ctx, database, record.Name); err == nil {
+			return rollback("check original", fmt.Errorf(
+				"vm %q still exists and its MAC and IP would be reused", record.Name))
+		}
+	}
+
+	img, err := db.GetImage(ctx, database, record.Image)
+	if err != nil && !manifest.Flattened && !manifest.HasBase {
+		return rollback("fetch image", err)
+	}
+
+	// Point an unflattened overlay at its new backing file.
+	disk := &vms.QemuDiskManager{}
+	if !manifest.Flattened {
+		backing := basePath
+		if !manifest.HasBase {
+			backing = filepath.Join(img.ArtifactsPath, img.ImageFile)
+		}
+		if err := disk.Rebase(ctx, diskPath, backing); err != nil {
+			return rollback("rebase disk", err)
+		}
+	}
+
+	// Keep the original MAC so DHCP reservations and guest config still match,
+	// unless another VM took it in the meantime.
+	mac := record.MacAddress
+	if mac == "" && len(domainXML) > 0 {
+		if macs, err := vms.ParseInterfaceMACs(string(domainXML)); err == nil && len(macs) > 0 {
+			mac = macs[0]
+		}
+	}
+	macs := network.NewMACAllocator(conn, database)
+	if mac == "" || macs.Check(ctx, name, mac) != nil {
+		if mac, err = macs.Allocate(ctx, networkName, record.IP); err != nil {
+			return rollback("allocate mac", err)
+		}
+	}
+
+	var osProfile string
+	if img != nil {
+		osProfile = img.OsProfile
+	}
+	domain := templates.NewDomain(name, record.RAM, record.CPU, diskPath, networkName, mac, osProfile)
+	xmlConfig, err := domain.GenerateXML()
+	if err != nil {
+		return rollback("build XML", err)
+	}
+
+	domains := vms.NewLibvirtDomainManager(conn)
+	if err := domains.Define(ctx, xml.Header+string(xmlConfig)); err != nil {
+		return rollback("define domain", err)
+	}
+	cleanups = append(cleanups, func() error { return domains.Undefine(ctx, name) })
+	if record.Autostart {
+		if err := domains.SetAutostart(ctx, name, true); err != nil {
+			return rollback("enable autostart", err)
+		}
+	}
+
+	if record.IP != "" && mac != "" {
+		ipam := network.NewIPAM(conn, database)
+		inserted, err := ipam.Reserve(ctx, networkName, name, record.IP, mac)
+		if err != nil {
+			return rollback("reserve ip", err)
+		}
+		if inserted {
+			cleanups = append(cleanups, func() error { return ipam.Release(ctx, networkName, name) })
+		}
+
+		nm := network.NewLibvirtNetworkManager(conn, database)
+		if err := nm.SetStaticMapping(ctx, networkName, record.IP, mac, name); err != nil {
+			return rollback("add static ip mapping", err)
+		}
+		cleanups = append(cleanups, func() error { return nm.RemoveStaticMapping(ctx, networkName, record.IP, name) })
+	}
+	if record.IPv6 != "" {
+		if err := network.NewIPAM(conn, database).CheckIPv6(ctx, networkName, name, record.IPv6); err != nil {
+			return rollback("check ipv6", err)
+		}
+		nm := network.NewLibvirtNetworkManager(conn, database)
+		if err := nm.SetStaticMapping(ctx, networkName, record.IPv6, mac, name); err != nil {
+			return rollback("add static ipv6 mapping", err)
+		}
+		cleanups = append(cleanups, func() error { return nm.RemoveStaticMapping(ctx, networkName, record.IPv6, name) })
+	}
+
+	record.ID = 0
+	record.Name = name
+	record.MacAddress = mac
+	record.NetworkID = networkID
+	record.StoreID = store.ID
+	record.DiskPath = diskPath
+	record.CreatedAt = time.Now()
+	if err := record.Insert(ctx, database); err != nil {
+		return rollback("insert record", err)
+	}
+
+	if opts.Start {
+		if err := domains.Start(ctx, name); err != nil {
+			return name, fmt.Errorf("vm %q restored but failed to start: %w", name, err)
+		}
+	}
+	return name, nil
+}
+
+// unpack reads the entries that follow the manifest: it returns the VM
+// record and domain XML, and extracts the disk to diskPath and the backing
+// image, if shipped, to basePath. The returned cleanups remove the
+// extracted files; they have already run when unpack fails.
+func unpack(
+	archive *archiveReader,
+	diskPath, basePath string,
+) (db.VirtualMachine, []byte, []func() error, error) {
+	var (
+		record    db.VirtualMachine
+		domainXML []byte
+		cleanups  []func() error
+	)
+	fail := func(step string, origin error) (db.VirtualMachine, []byte, []func() error, error) {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if err := cleanups[i](); err != nil {
+				log.Warnf("rollback failed, step %s, err %s", step, err)
+			}
+		}
+		return record, nil, nil, fmt.Errorf("failed at %s: %w", step, origin)
+	}
+
+	for {
+		entry, err := archive.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail("read archive", err)
+		}
+
+		switch entry {
+		case recordFile:
+			if err := archive.readJSON(&record); err != nil {
+				return fail("read "+recordFile, err)
+			}
+		case domainFile:
+			if domainXML, err = io.ReadAll(archive.tr); err != nil {
+				return fail("read "+domainFile, err)
+			}
+		case diskFile:
+			if err := archive.extract(diskPath); err != nil {
+				return fail("extract disk", err)
+			}
+			cleanups = append(cleanups, func() error { return os.Remove(diskPath) })
+		case baseFile:
+			if err := archive.extract(basePath); err != nil {
+				return fail("extract base image", err)
+			}
+			cleanups = append(cleanups, func() error { return os.Remove(basePath) })
+		}
+	}
+	return record, domainXML, cleanups, nil
+}
+
+// ensureAbsent fails when a VM with that name is already known to kvmcli or libvirt.
+func ensureAbsent(ctx context.Context, conn *libvirt.Libvirt, database *sql.DB, name string) error {
+	var existing db.VirtualMachine
+	if err := existing.GetRecord(ctx, database, name); err == nil {
+		return fmt.Errorf("vm %q already exists", name)
+	}
+	if _, err := conn.DomainLookupByName(name); err == nil {
+		return fmt.Errorf("domain %q already exists in libvirt", name)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+// Package cluster groups VMs into roles and drives them as a whole.
+package cluster
+
+import "context"
+
+// Cluster represents a bound cluster resource (configuration + manager).
+// It implements resources.Resource.
+type Cluster struct {
+	Spec    Config
+	ctx     context.Context
+	manager ClusterManager
+}
+
+// NewCluster creates a new Cluster resource.
+func NewCluster(spec Config, manager ClusterManager, ctx context.Context) *Cluster {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Cluster{
+		Spec:    spec,
+		manager: manager,
+		ctx:     ctx,
+	}
+}
+
+// Create delegates to the manager.
+func (c *Cluster) Create() error {
+	return c.manager.Create(c.ctx, c.Spec)
+}
+
+// Delete delegates to the manager.
+func (c *Cluster) Delete() error {
+	return c.manager.Delete(c.ctx, c.Spec.Name, c.Spec.Namespace)
+}
+
+// Update delegates to the manager.
+func (c *Cluster) Update() error {
+	return c.manager.Update(c.ctx, c.Spec)
+}
+
+// Start does nothing; clusters are brought up with `start cluster`,
+// which orders the VMs by role.
+func (c *Cluster) Start() error {
+	return nil
+}
@@ -0,0 +1,309 @@
+package cluster
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/kebairia/kvmcli/internal/labels"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultRole is the role of the VMs listed in `vms` or picked by `select`.
+const DefaultRole = "vms"
+
+// decodedAttrs are the attributes of a cluster block that are not roles.
+var decodedAttrs = map[string]struct{}{
+	DefaultRole: {}, "select": {}, "namespace": {}, "labels": {},
+}
+
+// Config describes a logical grouping of VMs. Besides `vms` and `select`,
+// every attribute of the block is a role: either a list of VM references,
+// e.g. masters = [vm.a, vm.b], or a label selector, e.g. workers = "role=worker".
+type Config struct {
+	Name      string            `hcl:"name,label"`
+	Namespace string            `hcl:"namespace,optional"` // defaults to the namespace of its VMs
+	VMExprs   hcl.Expression    `hcl:"vms,optional"`       // List of VM references
+	Select    string            `hcl:"select,optional"`    // Label selector for the default role
+	Labels    map[string]string `hcl:"labels,optional"`
+	Lifecycle *Lifecycle        `hcl:"lifecycle,block"`
+	RoleBody  hcl.Body          `hcl:",remain"` // Role attributes
+
+	Roles   []Role   // Resolved roles, in declaration order
+	VMNames []string // Resolved VM names
+}
+
+// Lifecycle orders the roles of a cluster when it is started and stopped,
+// and bounds how many VMs of a role a rollout takes down at once.
+type Lifecycle struct {
+	StartOrder     []string `hcl:"start_order,optional"`
+	StopOrder      []string `hcl:"stop_order,optional"`
+	MaxUnavailable int      `hcl:"max_unavailable,optional"`
+}
+
+// Role is a named group of VMs inside a cluster.
+type Role struct {
+	Name     string
+	Refs     []string // VMs referenced by name
+	Selector string   // label selector picking more VMs, if any
+	VMs      []string // resolved members: Refs, then the VMs Selector matches
+}
+
+// Candidate is a VM that a role selector may pick.
+type Candidate struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// Resolve evaluates the roles of the cluster against evalCtx, where
+// vm.<name> evaluates to the VM name, and matches role selectors against
+// the candidate VMs.
+func (c *Config) Resolve(evalCtx *hcl.EvalContext, candidates []Candidate) error {
+	type roleExpr struct {
+		name string
+		expr hcl.Expression
+	}
+	var exprs []roleExpr
+	if body, ok := c.RoleBody.(*hclsyntax.Body); ok {
+		// JustAttributes refuses bodies holding blocks, and the remain body
+		// still holds the lifecycle block, so the attributes are read directly.
+		for _, block := range body.Blocks {
+			if block.Type != "lifecycle" {
+				return fmt.Errorf("cluster %q: unexpected %q block", c.Name, block.Type)
+			}
+		}
+		for name, attr := range body.Attributes {
+			if _, decoded := decodedAttrs[name]; decoded {
+				continue
+			}
+			if name == "discover" {
+				return fmt.Errorf(
+					"cluster %q: discover is not supported, pick VMs by label with select, e.g. select = \"app=kubernetes\"",
+					c.Name,
+				)
+			}
+			exprs = append(exprs, roleExpr{name, attr.Expr})
+		}
+		// Keep the roles in the order they are written in.
+		sort.Slice(exprs, func(i, j int) bool {
+			return exprs[i].expr.Range().Start.Byte < exprs[j].expr.Range().Start.Byte
+		})
+	}
+
+	known := make(map[string]Candidate, len(candidates))
+	for _, cand := range candidates {
+		known[cand.Name] = cand
+	}
+
+	var roles []Role
+	defaultRole := Role{Name: DefaultRole, Selector: c.Select}
+	if c.VMExprs != nil {
+		refs, err := c.evalRefs(DefaultRole, c.VMExprs, evalCtx)
+		if err != nil {
+			return err
+		}
+		defaultRole.Refs = refs
+	}
+	if defaultRole.Refs != nil || defaultRole.Selector != "" {
+		roles = append(roles, defaultRole)
+	}
+	for _, re := range exprs {
+		val, diags := re.expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return fmt.Errorf("cluster %q: invalid %s expression: %w", c.Name, re.name, diags)
+		}
+		role := Role{Name: re.name}
+		if !val.IsNull() && val.Type() == cty.String {
+			role.Selector = val.AsString()
+		} else if role.Refs, diags = refsOf(val); diags != nil {
+			return fmt.Errorf(
+				"cluster %q: %s must be a list of VM references or a label selector, got %s",
+				c.Name, re.name, val.Type().FriendlyName(),
+			)
+		}
+		roles = append(roles, role)
+	}
+	if len(roles) == 0 {
+		return fmt.Errorf("cluster %q has no VMs", c.Name)
+	}
+
+	for _, role := range roles {
+		for _, name := range role.Refs {
+			if _, ok := known[name]; !ok {
+				return fmt.Errorf("cluster %q: unknown vm %q in %s", c.Name, name, role.Name)
+			}
+		}
+	}
+	if c.Namespace == "" {
+		for _, role := range roles {
+			if len(role.Refs) > 0 {
+				c.Namespace = known[role.Refs[0]].Namespace
+				break
+			}
+		}
+	}
+
+	if err := ResolveRoles(roles, candidates, c.Namespace); err != nil {
+		return fmt.Errorf("cluster %q: %w", c.Name, err)
+	}
+	c.Roles = roles
+	c.VMNames = nil
+	for _, role := range roles {
+		c.VMNames = append(c.VMNames, role.VMs...)
+	}
+
+	if _, err := c.StartGroups(); err != nil {
+		return err
+	}
+	_, err := c.StopGroups()
+	return err
+}
+
+// evalRefs evaluates a list of VM references.
+func (c *Config) evalRefs(role string, expr hcl.Expression, evalCtx *hcl.EvalContext) ([]string, error) {
+	val, diags := expr.Value(evalCtx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("cluster %q: invalid %s expression: %w", c.Name, role, diags)
+	}
+	refs, diags := refsOf(val)
+	if diags != nil {
+		return nil, fmt.Errorf("cluster %q: %s must be a list of VM references", c.Name, role)
+	}
+	return refs, nil
+}
+
+// refsOf converts a list value to VM names. A null value is an empty list.
+func refsOf(val cty.Value) ([]string, hcl.Diagnostics) {
+	if val.IsNull() {
+		return nil, nil
+	}
+	invalid := hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Not a list of VM references"}}
+	if !val.Type().IsTupleType() && !val.Type().IsListType() {
+		return nil, invalid
+	}
+	refs := []string{}
+	for it := val.ElementIterator(); it.Next(); {
+		_, v := it.Element()
+		if v.IsNull() || v.Type() != cty.String {
+			return nil, invalid
+		}
+		refs = append(refs, v.AsString())
+	}
+	return refs, nil
+}
+
+// ResolveRoles fills the VMs of each role: its references first, then the
+// candidates its selector matches, in name order. A VM belongs to a single
+// role; the selector of the default role only picks up the VMs the other
+// roles left. When namespace is set, selectors only pick VMs in it.
+func ResolveRoles(roles []Role, candidates []Candidate, namespace string) error {
+	sorted := slices.Clone(candidates)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	owner := make(map[string]string)
+	for i := range roles {
+		roles[i].VMs = nil
+		for _, name := range roles[i].Refs {
+			if other, dup := owner[name]; dup {
+				return fmt.Errorf("vm %q is in both %s and %s", name, other, roles[i].Name)
+			}
+			owner[name] = roles[i].Name
+			roles[i].VMs = append(roles[i].VMs, name)
+		}
+	}
+
+	// Named roles pick first, the default role last.
+	order := make([]int, 0, len(roles))
+	for i, role := range roles {
+		if role.Name != DefaultRole {
+			order = append(order, i)
+		}
+	}
+	for i, role := range roles {
+		if role.Name == DefaultRole {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		if roles[i].Selector == "" {
+			continue
+		}
+		if strings.HasPrefix(roles[i].Selector, "tag:") {
+			return fmt.Errorf("%s: VMs have labels, not tags: use a label selector instead of %q",
+				roles[i].Name, roles[i].Selector)
+		}
+		sel, err := labels.Parse(roles[i].Selector)
+		if err != nil {
+			return fmt.Errorf("%s: %w", roles[i].Name, err)
+		}
+		for _, cand := range sorted {
+			if _, taken := owner[cand.Name]; taken {
+				continue
+			}
+			if namespace != "" && cand.Namespace != namespace {
+				continue
+			}
+			if sel.Matches(cand.Labels) {
+				owner[cand.Name] = roles[i].Name
+				roles[i].VMs = append(roles[i].VMs, cand.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// StartGroups returns the roles in the order they are started: start_order
+// first, then the roles it does not mention, in declaration order.
+func (c *Config) StartGroups() ([]Role, error) {
+	var order []string
+	if c.Lifecycle != nil {
+		order = c.Lifecycle.StartOrder
+	}
+	return c.ordered("start_order", order)
+}
+
+// StopGroups returns the roles in the order they are stopped: stop_order
+// when set, otherwise the reverse of the start order.
+func (c *Config) StopGroups() ([]Role, error) {
+	if c.Lifecycle != nil && len(c.Lifecycle.StopOrder) > 0 {
+		return c.ordered("stop_order", c.Lifecycle.StopOrder)
+	}
+	groups, err := c.StartGroups()
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(groups)
+	return groups, nil
+}
+
+func (c *Config) ordered(attr string, order []string) ([]Role, error) {
+	byName := make(map[string]Role, len(c.Roles))
+	for _, r := range c.Roles {
+		byName[r.Name] = r
+	}
+
+	groups := make([]Role, 0, len(c.Roles))
+	listed := make(map[string]struct{}, len(order))
+	for _, name := range order {
+		role, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("cluster %q: %s names unknown role %q", c.Name, attr, name)
+		}
+		if _, dup := listed[name]; dup {
+			return nil, fmt.Errorf("cluster %q: %s lists role %q twice", c.Name, attr, name)
+		}
+		listed[name] = struct{}{}
+		groups = append(groups, role)
+	}
+	for _, r := range c.Roles {
+		if _, ok := listed[r.Name]; !ok {
+			groups = append(groups, r)
+		}
+	}
+	return groups, nil
+}
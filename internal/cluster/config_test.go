@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func decodeCluster(t *testing.T, src string) *Config {
+	t.Helper()
+	c, err := resolveCluster(t, src)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	return c
+}
+
+// resolveCluster decodes the first cluster of src and resolves it against
+// a fixed set of VMs.
+func resolveCluster(t *testing.T, src string) (*Config, error) {
+	t.Helper()
+	file, diags := hclparse.NewParser().ParseHCL([]byte(src), "test.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("parse: %v", diags)
+	}
+	var root struct {
+		Clusters []Config `hcl:"cluster,block"`
+	}
+	if diags := gohcl.DecodeBody(file.Body, nil, &root); diags.HasErrors() {
+		t.Fatalf("decode: %v", diags)
+	}
+
+	candidates := []Candidate{
+		{Name: "m1", Namespace: "homelab", Labels: map[string]string{"role": "master"}},
+		{Name: "m2", Namespace: "homelab", Labels: map[string]string{"role": "master"}},
+		{Name: "w1", Namespace: "homelab", Labels: map[string]string{"role": "worker"}},
+		{Name: "w2", Namespace: "homelab", Labels: map[string]string{"role": "worker", "gpu": "true"}},
+		{Name: "lb1", Namespace: "homelab", Labels: map[string]string{"role": "lb"}},
+		{Name: "w3", Namespace: "other", Labels: map[string]string{"role": "worker"}},
+	}
+	vmMap := map[string]cty.Value{}
+	for _, cand := range candidates {
+		vmMap[cand.Name] = cty.StringVal(cand.Name)
+	}
+	evalCtx := &hcl.EvalContext{Variables: map[string]cty.Value{"vm": cty.ObjectVal(vmMap)}}
+
+	c := &root.Clusters[0]
+	return c, c.Resolve(evalCtx, candidates)
+}
+
+func roleNames(roles []Role) []string {
+	names := make([]string, len(roles))
+	for i, r := range roles {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func TestResolveRoles(t *testing.T) {
+	c := decodeCluster(t, `
+cluster "k8s" {
+  masters = [vm.m1, vm.m2]
+  workers = [vm.w1, vm.w2]
+  lb      = [vm.lb1]
+
+  lifecycle {
+    start_order = ["masters", "workers"]
+  }
+}`)
+
+	if got, want := c.VMNames, []string{"m1", "m2", "w1", "w2", "lb1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("VMNames = %v, want %v", got, want)
+	}
+
+	start, err := c.StartGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := roleNames(start), []string{"masters", "workers", "lb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("start groups = %v, want %v", got, want)
+	}
+
+	stop, err := c.StopGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := roleNames(stop), []string{"lb", "workers", "masters"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop groups = %v, want %v", got, want)
+	}
+}
+
+func TestResolveDefaultRole(t *testing.T) {
+	c := decodeCluster(t, `
+cluster "dns" {
+  vms = [vm.m1, vm.m2]
+}`)
+
+	start, err := c.StartGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(start) != 1 || start[0].Name != DefaultRole || len(start[0].VMs) != 2 {
+		t.Errorf("start groups = %+v, want one %q group of 2 VMs", start, DefaultRole)
+	}
+}
+
+func TestResolveSelectors(t *testing.T) {
+	c := decodeCluster(t, `
+cluster "k8s" {
+  namespace = "homelab"
+  masters   = [vm.m1]
+  gpu       = "gpu"
+  workers   = "role in (worker)"
+  select    = "role"
+}`)
+
+	want := map[string][]string{
+		"masters":   {"m1"},
+		"gpu":       {"w2"},
+		"workers":   {"w1"},        // w2 is taken by gpu, w3 is in another namespace
+		DefaultRole: {"lb1", "m2"}, // what the other roles left
+	}
+	for _, role := range c.Roles {
+		if !reflect.DeepEqual(role.VMs, want[role.Name]) {
+			t.Errorf("role %s = %v, want %v", role.Name, role.VMs, want[role.Name])
+		}
+	}
+	if got := roleNames(c.Roles); !reflect.DeepEqual(got, []string{DefaultRole, "masters", "gpu", "workers"}) {
+		t.Errorf("roles = %v", got)
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	tests := map[string]string{
+		"discover":      `cluster "k8s" { discover = "tag:k8s-prod" }`,
+		"tag role":      `cluster "k8s" { masters = "tag:master" }`,
+		"tag selection": `cluster "k8s" { select = "tag:k8s-prod" }`,
+	}
+	for name, src := range tests {
+		if _, err := resolveCluster(t, src); err == nil {
+			t.Errorf("%s: Resolve succeeded, want an error", name)
+		} else if strings.Contains(err.Error(), "invalid") {
+			t.Errorf("%s: got a selector parse error %q, want one about tags", name, err)
+		}
+	}
+}
+
+func TestOrderUnknownRole(t *testing.T) {
+	c := &Config{
+		Name:      "k8s",
+		Roles:     []Role{{Name: "masters", VMs: []string{"m1"}}},
+		Lifecycle: &Lifecycle{StopOrder: []string{"workers"}},
+	}
+	if _, err := c.StopGroups(); err == nil {
+		t.Error("StopGroups accepted an unknown role")
+	}
+}
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/kebairia/kvmcli/internal/common"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// ClusterInfo holds display information for a cluster.
+type ClusterInfo struct {
+	Name      string
+	Namespace string
+	Members   string // role=vm1,vm2 pairs
+	Running   int
+	Total     int
+	CPU       int
+	RAM       int // in MB
+	Labels    string
+	Age       string
+}
+
+// Header returns a tabwriter with column headers for cluster listing.
+func (info *ClusterInfo) Header() *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNAMESPACE\tSTATE\tCPU\tMEMORY\tMEMBERS\tLABELS\tAGE")
+	return w
+}
+
+// PrintInfo writes the cluster info as a row to the tabwriter.
+func (info *ClusterInfo) PrintInfo(w *tabwriter.Writer) {
+	fmt.Fprintf(w, "%s\t%s\t%d/%d running\t%d\t%d MB\t%s\t%s\t%s\n",
+		info.Name,
+		info.Namespace,
+		info.Running,
+		info.Total,
+		info.CPU,
+		info.RAM,
+		info.Members,
+		info.Labels,
+		info.Age,
+	)
+}
+
+// NewClusterInfo constructs a ClusterInfo from a database record, adding up
+// the resources of its VMs and counting those running.
+func NewClusterInfo(
+	ctx context.Context,
+	database *sql.DB,
+	conn *libvirt.Libvirt,
+	record db.Cluster,
+) (*ClusterInfo, error) {
+	roles, err := RecordRoles(ctx, database, record)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ClusterInfo{
+		Name:      record.Name,
+		Namespace: record.Namespace,
+		Labels:    formatLabels(record.Labels),
+		Age:       common.FormatAge(record.CreatedAt),
+	}
+
+	groups := make([]string, 0, len(roles))
+	for _, role := range roles {
+		groups = append(groups, role.Name+"="+strings.Join(role.VMs, ","))
+
+		for _, name := range role.VMs {
+			info.Total++
+			vm, err := db.GetVMByName(ctx, database, name, record.Namespace)
+			if err != nil {
+				log.Errorf("cluster %q: %v", record.Name, err)
+				continue
+			}
+			info.CPU += vm.CPU
+			info.RAM += vm.RAM
+
+			dom, err := conn.DomainLookupByName(name)
+			if err != nil {
+				continue
+			}
+			if status, err := vms.GetDomainStatus(conn, dom); err == nil && status.State == vms.StateRunning {
+				info.Running++
+			}
+		}
+	}
+	info.Members = strings.Join(groups, " ")
+	return info, nil
+}
+
+// GetClusters retrieves the clusters of a namespace (all when empty) and
+// returns their display information.
+func GetClusters(
+	ctx context.Context,
+	database *sql.DB,
+	conn *libvirt.Libvirt,
+	namespace string,
+) ([]ClusterInfo, error) {
+	records, err := db.GetClusters(ctx, database, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster records: %w", err)
+	}
+
+	clusters := make([]ClusterInfo, 0, len(records))
+	for _, rec := range records {
+		info, err := NewClusterInfo(ctx, database, conn, rec)
+		if err != nil {
+			log.Errorf("could not build cluster info for %q: %v", rec.Name, err)
+			continue
+		}
+		clusters = append(clusters, *info)
+	}
+	return clusters, nil
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// StartOptions controls how a cluster is brought up.
+type StartOptions struct {
+	ReadyTimeout time.Duration // how long each group may take to become ready
+}
+
+// Manager starts and stops the VMs of a cluster group by group.
+// VMs of the same group are handled in parallel; a group must be done
+// before the next one is touched.
+type Manager struct {
+	conn    *libvirt.Libvirt
+	db      *sql.DB
+	domains *vms.LibvirtDomainManager
+}
+
+// NewManager returns a Manager working on the given connections.
+func NewManager(conn *libvirt.Libvirt, database *sql.DB) *Manager {
+	return &Manager{
+		conn:    conn,
+		db:      database,
+		domains: vms.NewLibvirtDomainManager(conn),
+	}
+}
+
+// Start boots the groups in start order and waits for every VM of a group
+// to be ready before moving to the next group.
+func (m *Manager) Start(ctx context.Context, c *Config, opts StartOptions) error {
+	groups, err := c.StartGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		err := m.each(group, func(name string) error {
+			if err := m.domains.Start(ctx, name); err != nil {
+				return err
+			}
+			// A fresh boot picks up the persistent definition.
+			if err := db.SetRestartRequired(ctx, m.db, name, c.Namespace, false); err != nil {
+				return err
+			}
+			if err := vms.WaitReady(ctx, m.conn, m.db, name, opts.ReadyTimeout); err != nil {
+				return err
+			}
+			fmt.Printf("vm/%s ready\n", name)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("cluster %q: start %s: %w", c.Name, group.Name, err)
+		}
+	}
+	return nil
+}
+
+// Stop shuts the groups down in stop order. Each group must be shut off
+// before the next one is stopped.
+func (m *Manager) Stop(ctx context.Context, c *Config, opts vms.StopOptions) error {
+	groups, err := c.StopGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		err := m.each(group, func(name string) error {
+			if err := m.domains.Stop(ctx, name, opts); err != nil {
+				return err
+			}
+			fmt.Printf("vm/%s stopped\n", name)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("cluster %q: stop %s: %w", c.Name, group.Name, err)
+		}
+	}
+	return nil
+}
+
+// Restart stops the whole cluster, then starts it again.
+func (m *Manager) Restart(
+	ctx context.Context,
+	c *Config,
+	stopOpts vms.StopOptions,
+	startOpts StartOptions,
+) error {
+	if err := m.Stop(ctx, c, stopOpts); err != nil {
+		return err
+	}
+	return m.Start(ctx, c, startOpts)
+}
+
+// each runs fn for every VM of the group in parallel and joins the errors.
+func (m *Manager) each(group Role, fn func(name string) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, name := range group.VMs {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := fn(name); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// ClusterManager defines the interface for persisting clusters.
+type ClusterManager interface {
+	Create(ctx context.Context, spec Config) error
+	Update(ctx context.Context, spec Config) error
+	Delete(ctx context.Context, name, namespace string) error
+}
+
+// DBClusterManager implements ClusterManager using a SQL database.
+type DBClusterManager struct {
+	db *sql.DB
+}
+
+// NewDBClusterManager creates a new DBClusterManager.
+func NewDBClusterManager(db *sql.DB) *DBClusterManager {
+	return &DBClusterManager{db: db}
+}
+
+// Create persists the cluster and its members. Applying a manifest again
+// updates a cluster that already exists.
+func (m *DBClusterManager) Create(ctx context.Context, spec Config) error {
+	_, err := db.GetClusterByName(ctx, m.db, spec.Name, spec.Namespace)
+	switch {
+	case err == nil:
+		return m.Update(ctx, spec)
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	record := NewClusterRecord(spec)
+	if err := record.Insert(ctx, m.db); err != nil {
+		return fmt.Errorf("failed to insert cluster: %w", err)
+	}
+	fmt.Printf("cluster/%s created\n", spec.Name)
+	return nil
+}
+
+// Update rewrites the cluster record, so role changes are picked up.
+func (m *DBClusterManager) Update(ctx context.Context, spec Config) error {
+	record := NewClusterRecord(spec)
+	if err := record.Update(ctx, m.db); err != nil {
+		return err
+	}
+	fmt.Printf("cluster/%s updated\n", spec.Name)
+	return nil
+}
+
+// Delete removes the cluster record; its VMs are left alone.
+func (m *DBClusterManager) Delete(ctx context.Context, name, namespace string) error {
+	record := &db.Cluster{Name: name, Namespace: namespace}
+	if err := record.Delete(ctx, m.db); err != nil {
+		return err
+	}
+	fmt.Printf("cluster/%s deleted\n", name)
+	return nil
+}
+
+// NewClusterRecord creates a database record from the cluster configuration.
+func NewClusterRecord(spec Config) *db.Cluster {
+	record := &db.Cluster{
+		Name:      spec.Name,
+		Namespace: spec.Namespace,
+		Labels:    spec.Labels,
+		CreatedAt: time.Now(),
+	}
+	if spec.Lifecycle != nil {
+		record.StartOrder = spec.Lifecycle.StartOrder
+		record.StopOrder = spec.Lifecycle.StopOrder
+	}
+	// Only the references are stored as members: selectors are matched
+	// again on every read, so membership follows VM labels.
+	for _, role := range spec.Roles {
+		record.RoleSpecs = append(record.RoleSpecs, db.RoleSpec{Name: role.Name, Selector: role.Selector})
+		for _, vm := range role.Refs {
+			record.Members = append(record.Members, db.ClusterMember{Role: role.Name, VMName: vm})
+		}
+	}
+	return record
+}
+
+// RecordRoles resolves the roles of a stored cluster against the VMs
+// currently in the database.
+func RecordRoles(ctx context.Context, database *sql.DB, record db.Cluster) ([]Role, error) {
+	roles := make([]Role, 0, len(record.RoleSpecs))
+	index := make(map[string]int)
+	for _, name := range record.Roles() {
+		index[name] = len(roles)
+		roles = append(roles, Role{Name: name})
+	}
+	for _, spec := range record.RoleSpecs {
+		roles[index[spec.Name]].Selector = spec.Selector
+	}
+	for _, m := range record.Members {
+		i := index[m.Role]
+		roles[i].Refs = append(roles[i].Refs, m.VMName)
+	}
+
+	records, err := db.GetVMRecords(ctx, database, record.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("list vms of cluster %q: %w", record.Name, err)
+	}
+	candidates := make([]Candidate, 0, len(records))
+	for _, r := range records {
+		candidates = append(candidates, Candidate{Name: r.Name, Namespace: r.Namespace, Labels: r.Labels})
+	}
+	if err := ResolveRoles(roles, candidates, record.Namespace); err != nil {
+		return nil, fmt.Errorf("cluster %q: %w", record.Name, err)
+	}
+	return roles, nil
+}
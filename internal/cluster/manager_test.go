@@ -0,0 +1,54 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCreateTwice(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := db.EnsureClusterTable(ctx, database); err != nil {
+		t.Fatalf("EnsureClusterTable failed: %v", err)
+	}
+	m := NewDBClusterManager(database)
+	spec := Config{
+		Name:      "k8s",
+		Namespace: "homelab",
+		Roles:     []Role{{Name: "masters", Refs: []string{"m1"}}},
+	}
+	if err := m.Create(ctx, spec); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	created, err := db.GetClusterByName(ctx, database, "k8s", "homelab")
+	if err != nil {
+		t.Fatalf("GetClusterByName failed: %v", err)
+	}
+
+	// Applying the manifest again picks up role changes.
+	spec.Roles = []Role{{Name: "masters", Refs: []string{"m1", "m2"}}}
+	if err := m.Create(ctx, spec); err != nil {
+		t.Fatalf("second Create failed: %v", err)
+	}
+	got, err := db.GetClusterByName(ctx, database, "k8s", "homelab")
+	if err != nil {
+		t.Fatalf("GetClusterByName failed: %v", err)
+	}
+	if len(got.Members) != 2 {
+		t.Errorf("members = %v, want m1 and m2", got.Members)
+	}
+	// The cluster is updated in place, not recreated.
+	if got.ID != created.ID || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("id, created_at = %d, %v, want %d, %v", got.ID, got.CreatedAt, created.ID, created.CreatedAt)
+	}
+}
@@ -0,0 +1,273 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// Rollout actions.
+const (
+	ActionRestart = "restart" // stop and start each VM
+	ActionReimage = "reimage" // boot each VM from a fresh overlay on a new image
+	ActionResize  = "resize"  // change the vCPUs and memory of each VM
+)
+
+// ErrRolloutPaused is returned when a batch failed and the rollout stopped
+// before touching the next one.
+var ErrRolloutPaused = errors.New("rollout paused")
+
+// RolloutOptions describes a rolling operation over the VMs of a cluster.
+type RolloutOptions struct {
+	Action string `json:"action"`
+	Image  string `json:"image,omitempty"`  // reimage: store image to boot from
+	CPU    int    `json:"cpu,omitempty"`    // resize: vCPU count, 0 keeps it
+	Memory int    `json:"memory,omitempty"` // resize: memory in MiB, 0 keeps it
+	// MaxUnavailable is how many VMs of a role are taken down at once;
+	// lifecycle.max_unavailable, or 1, when zero.
+	MaxUnavailable int             `json:"max_unavailable,omitempty"`
+	Roles          []string        `json:"roles,omitempty"` // roles to roll, every role when empty
+	Stop           vms.StopOptions `json:"stop"`
+	ReadyTimeout   time.Duration   `json:"ready_timeout,omitempty"` // how long each VM may take to become ready
+}
+
+// Validate checks that the options describe a complete rollout.
+func (o RolloutOptions) Validate() error {
+	switch o.Action {
+	case ActionRestart:
+	case ActionReimage:
+		if o.Image == "" {
+			return fmt.Errorf("a reimage rollout needs an image")
+		}
+	case ActionResize:
+		if o.CPU < 0 || o.Memory < 0 {
+			return fmt.Errorf("cpu and memory must not be negative")
+		}
+		if o.CPU == 0 && o.Memory == 0 {
+			return fmt.Errorf("a resize rollout needs a cpu or memory value")
+		}
+	default:
+		return fmt.Errorf("unknown rollout action %q (want %s, %s or %s)",
+			o.Action, ActionRestart, ActionReimage, ActionResize)
+	}
+	if o.MaxUnavailable < 0 {
+		return fmt.Errorf("max_unavailable must not be negative")
+	}
+	return nil
+}
+
+// Batch is a set of VMs of one role rolled at the same time.
+type Batch struct {
+	Role string
+	VMs  []string
+}
+
+// RolloutBatches splits the given roles, taken in start order, into batches
+// of at most maxUnavailable VMs; every role when roles is empty. A batch
+// never spans two roles. VMs listed in done are left out.
+func (c *Config) RolloutBatches(roles []string, maxUnavailable int, done []string) ([]Batch, error) {
+	groups, err := c.StartGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range roles {
+		if !slices.ContainsFunc(groups, func(r Role) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("cluster %q has no role %q", c.Name, name)
+		}
+	}
+	if maxUnavailable <= 0 && c.Lifecycle != nil {
+		maxUnavailable = c.Lifecycle.MaxUnavailable
+	}
+	maxUnavailable = max(maxUnavailable, 1)
+
+	var batches []Batch
+	for _, group := range groups {
+		if len(roles) > 0 && !slices.Contains(roles, group.Name) {
+			continue
+		}
+		var pending []string
+		for _, name := range group.VMs {
+			if !slices.Contains(done, name) {
+				pending = append(pending, name)
+			}
+		}
+		for chunk := range slices.Chunk(pending, maxUnavailable) {
+			batches = append(batches, Batch{Role: group.Name, VMs: chunk})
+		}
+	}
+	return batches, nil
+}
+
+// Rollout applies the action to the VMs of the cluster batch by batch and
+// waits for every VM of a batch to pass its health check before taking
+// the next batch down. When a batch fails the rollout is paused: its
+// progress is kept so that ResumeRollout picks it up where it stopped.
+func (m *Manager) Rollout(ctx context.Context, c *Config, opts RolloutOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	record, err := db.GetRollout(ctx, m.db, c.Name, c.Namespace)
+	switch {
+	case errors.Is(err, db.ErrNoRollout):
+	case err != nil:
+		return err
+	case record.Status != db.RolloutCompleted:
+		return fmt.Errorf("cluster %q has a %s %s rollout; resume or abort it first",
+			c.Name, record.Status, record.Action)
+	}
+
+	params, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("encode rollout options: %w", err)
+	}
+	record = db.Rollout{
+		Cluster:   c.Name,
+		Namespace: c.Namespace,
+		Action:    opts.Action,
+		Params:    string(params),
+		Status:    db.RolloutRunning,
+	}
+	return m.roll(ctx, c, opts, &record)
+}
+
+// ResumeRollout continues the unfinished rollout of the cluster with the
+// options it was started with, skipping the VMs it already completed.
+func (m *Manager) ResumeRollout(ctx context.Context, c *Config) error {
+	record, err := db.GetRollout(ctx, m.db, c.Name, c.Namespace)
+	if err != nil {
+		return err
+	}
+	if record.Status == db.RolloutCompleted {
+		return fmt.Errorf("the last rollout of cluster %q is completed, nothing to resume", c.Name)
+	}
+	var opts RolloutOptions
+	if err := json.Unmarshal([]byte(record.Params), &opts); err != nil {
+		return fmt.Errorf("decode rollout options: %w", err)
+	}
+	record.Status = db.RolloutRunning
+	record.Error = ""
+	return m.roll(ctx, c, opts, &record)
+}
+
+// AbortRollout forgets the unfinished rollout of the cluster. VMs already
+// rolled keep their new state. An empty namespace matches any.
+func (m *Manager) AbortRollout(ctx context.Context, name, namespace string) error {
+	record, err := db.GetRollout(ctx, m.db, name, namespace)
+	if err != nil {
+		return err
+	}
+	if record.Status == db.RolloutCompleted {
+		return fmt.Errorf("the last rollout of cluster %q is completed, nothing to abort", name)
+	}
+	return db.DeleteRollout(ctx, m.db, name, record.Namespace)
+}
+
+// roll runs the pending batches of a rollout, saving its progress after each one.
+func (m *Manager) roll(ctx context.Context, c *Config, opts RolloutOptions, record *db.Rollout) error {
+	batches, err := c.RolloutBatches(opts.Roles, opts.MaxUnavailable, record.Done)
+	if err != nil {
+		return err
+	}
+	if err := record.Save(ctx, m.db); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	for i, batch := range batches {
+		log.Infof("cluster %s: %s batch %d/%d (%s)", c.Name, opts.Action, i+1, len(batches), batch.Role)
+		err := m.each(Role{Name: batch.Role, VMs: batch.VMs}, func(name string) error {
+			if err := m.rollVM(ctx, name, c.Namespace, opts); err != nil {
+				return err
+			}
+			mu.Lock()
+			record.Done = append(record.Done, name)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			record.Status = db.RolloutPaused
+			record.Error = err.Error()
+			if saveErr := record.Save(ctx, m.db); saveErr != nil {
+				log.Errorf("%v", saveErr)
+			}
+			return fmt.Errorf("cluster %q: %w at %s (%d/%d VMs done): %w",
+				c.Name, ErrRolloutPaused, batch.Role, len(record.Done), len(record.Done)+pending(batches[i:], record.Done), err)
+		}
+		if err := record.Save(ctx, m.db); err != nil {
+			return err
+		}
+	}
+
+	record.Status = db.RolloutCompleted
+	return record.Save(ctx, m.db)
+}
+
+// rollVM applies the rollout action to one VM and waits for it to be ready.
+func (m *Manager) rollVM(ctx context.Context, name, namespace string, opts RolloutOptions) error {
+	switch opts.Action {
+	case ActionRestart:
+		if err := m.domains.Restart(ctx, name, opts.Stop); err != nil {
+			return err
+		}
+	case ActionReimage:
+		err := vms.WhileStopped(ctx, m.domains, name, opts.Stop, func() error {
+			return vms.Reimage(ctx, m.conn, m.db, &vms.QemuDiskManager{}, name, opts.Image)
+		})
+		if err != nil {
+			return err
+		}
+	case ActionResize:
+		err := vms.WhileStopped(ctx, m.domains, name, opts.Stop, func() error {
+			return vms.ResizeStopped(ctx, m.conn, m.db, name, opts.CPU, opts.Memory)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// A fresh boot picks up the persistent definition.
+	if err := db.SetRestartRequired(ctx, m.db, name, namespace, false); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s %s\n", name, rolledVerb[opts.Action])
+
+	// WhileStopped leaves a VM that was shut off as it was.
+	status, err := m.domains.State(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !status.State.IsActive() {
+		return nil
+	}
+	if err := vms.WaitReady(ctx, m.conn, m.db, name, opts.ReadyTimeout); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s ready\n", name)
+	return nil
+}
+
+var rolledVerb = map[string]string{
+	ActionRestart: "restarted",
+	ActionReimage: "reimaged",
+	ActionResize:  "resized",
+}
+
+// pending counts the VMs of the batches that are not done yet.
+func pending(batches []Batch, done []string) int {
+	n := 0
+	for _, b := range batches {
+		for _, name := range b.VMs {
+			if !slices.Contains(done, name) {
+				n++
+			}
+		}
+	}
+	return n
+}
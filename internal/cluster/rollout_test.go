@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRolloutBatches(t *testing.T) {
+	c := decodeCluster(t, `
+cluster "k8s" {
+  masters = [vm.m1, vm.m2]
+  workers = "role=worker"
+  lb      = [vm.lb1]
+
+  lifecycle {
+    start_order     = ["masters", "workers"]
+    max_unavailable = 2
+  }
+}`)
+
+	tests := []struct {
+		name           string
+		roles          []string
+		maxUnavailable int
+		done           []string
+		want           []Batch
+	}{
+		{
+			name: "lifecycle max_unavailable",
+			want: []Batch{
+				{Role: "masters", VMs: []string{"m1", "m2"}},
+				{Role: "workers", VMs: []string{"w1", "w2"}},
+				{Role: "lb", VMs: []string{"lb1"}},
+			},
+		},
+		{
+			name:           "override and role filter",
+			roles:          []string{"workers", "masters"},
+			maxUnavailable: 1,
+			want: []Batch{
+				{Role: "masters", VMs: []string{"m1"}},
+				{Role: "masters", VMs: []string{"m2"}},
+				{Role: "workers", VMs: []string{"w1"}},
+				{Role: "workers", VMs: []string{"w2"}},
+			},
+		},
+		{
+			name: "resume skips done VMs",
+			done: []string{"m1", "m2", "w1"},
+			want: []Batch{
+				{Role: "workers", VMs: []string{"w2"}},
+				{Role: "lb", VMs: []string{"lb1"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.RolloutBatches(tt.roles, tt.maxUnavailable, tt.done)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.RolloutBatches([]string{"etcd"}, 0, nil); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+}
+
+func TestRolloutOptionsValidate(t *testing.T) {
+	valid := []RolloutOptions{
+		{Action: ActionRestart},
+		{Action: ActionReimage, Image: "rocky-9.6"},
+		{Action: ActionResize, Memory: 4096},
+	}
+	for _, opts := range valid {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", opts, err)
+		}
+	}
+	invalid := []RolloutOptions{
+		{Action: "upgrade"},
+		{Action: ActionReimage},
+		{Action: ActionResize},
+		{Action: ActionRestart, MaxUnavailable: -1},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
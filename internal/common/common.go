@@ -7,7 +7,12 @@ import (
 
 // FormatAge returns a human-friendly string for the time elapsed since t.
 func FormatAge(t time.Time) string {
-	duration := time.Since(t)
+	return FormatDuration(time.Since(t))
+}
+
+// FormatDuration returns a human-friendly string for a duration, in its
+// largest whole unit.
+func FormatDuration(duration time.Duration) string {
 	if duration < 0 {
 		duration = -duration
 	}
@@ -23,3 +28,11 @@ func FormatAge(t time.Time) string {
 	}
 	return fmt.Sprintf("%ds", int(duration.Seconds()))
 }
+
+// OnOff returns "on" or "off" for a boolean setting.
+func OnOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -10,9 +10,11 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/kebairia/kvmcli/internal/cluster"
 	"github.com/kebairia/kvmcli/internal/database"
 	"github.com/kebairia/kvmcli/internal/network"
 	"github.com/kebairia/kvmcli/internal/resources"
+	"github.com/kebairia/kvmcli/internal/ssh"
 	"github.com/kebairia/kvmcli/internal/store"
 	"github.com/kebairia/kvmcli/internal/vms"
 	"github.com/zclconf/go-cty/cty"
@@ -23,7 +25,8 @@ type Config struct {
 	Networks []network.Config `hcl:"network,block"`
 	VMs      []vms.Config     `hcl:"vm,block"`
 	Stores   []store.Config   `hcl:"store,block"`
-	Clusters []Cluster        `hcl:"cluster,block"`
+	SSH      []ssh.Config     `hcl:"ssh,block"`
+	Clusters []cluster.Config `hcl:"cluster,block"`
 	Data     []DataResource   `hcl:"data,block"`
 }
 
@@ -32,20 +35,6 @@ type DataResource struct {
 	Name string `hcl:"name,label"`
 }
 
-// Cluster describes a logical grouping of VMs.
-type Cluster struct {
-	Name      string            `hcl:"name,label"`
-	VMExprs   hcl.Expression    `hcl:"vms,attr"` // List of VM references
-	VMNames   []string          // Resolved VM names
-	Labels    map[string]string `hcl:"labels,optional"`
-	Lifecycle *Lifecycle        `hcl:"lifecycle,block"`
-}
-
-type Lifecycle struct {
-	StartOrder []string `hcl:"start_order,optional"`
-	StopOrder  []string `hcl:"stop_order,optional"`
-}
-
 // Load parses and decodes the configuration file at the given path.
 func Load(
 	path string,
@@ -53,28 +42,16 @@ func Load(
 	db *sql.DB,
 	conn *libvirt.Libvirt,
 ) ([]resources.Resource, error) {
-	src, err := os.ReadFile(path)
+	cfg, err := parse(path, ctx, db)
 	if err != nil {
-		return nil, fmt.Errorf("read config %q: %w", path, err)
-	}
-
-	parser := hclparse.NewParser()
-	file, diags := parser.ParseHCL(src, path)
-	if diags.HasErrors() {
-		return nil, fmt.Errorf("parse hcl %q: %w", path, diags)
-	}
-
-	var cfg Config
-	if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
-		return nil, fmt.Errorf("decode hcl %q: %w", path, diags)
-	}
-	if err := cfg.ResolveReferences(ctx, db); err != nil {
 		return nil, err
 	}
 
 	var networks []resources.Resource
 	var stores []resources.Resource
+	var sshSettings []resources.Resource
 	var vmsList []resources.Resource
+	var clusters []resources.Resource
 	// for _, n := range cfg.Networks {
 	// 	networks = append(networks, n)
 	// }
@@ -114,21 +91,88 @@ func Load(
 		stores = append(stores, stRes)
 	}
 
+	for _, s := range cfg.SSH {
+		manager := ssh.NewDBSettingsManager(db)
+		sshSettings = append(sshSettings, ssh.NewSettings(s, manager, ctx))
+	}
+
+	for _, c := range cfg.Clusters {
+		manager := cluster.NewDBClusterManager(db)
+		clusters = append(clusters, cluster.NewCluster(c, manager, ctx))
+	}
+
 	sorted := make([]resources.Resource, 0,
-		len(stores)+len(vmsList),
+		len(stores)+len(sshSettings)+len(networks)+len(vmsList)+len(clusters),
 	)
 	sorted = append(sorted, stores...)
+	sorted = append(sorted, sshSettings...)
 	sorted = append(sorted, networks...)
 	sorted = append(sorted, vmsList...)
+	sorted = append(sorted, clusters...)
 
 	return sorted, nil
 }
 
+// LoadCluster parses the configuration file at the given path and returns
+// the named cluster with its roles resolved to VM names.
+func LoadCluster(path, name string, ctx context.Context, db *sql.DB) (*cluster.Config, error) {
+	cfg, err := parse(path, ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cfg.Clusters {
+		if cfg.Clusters[i].Name == name {
+			return &cfg.Clusters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cluster %q not found in %q", name, path)
+}
+
+// parse reads, decodes and resolves the configuration file at the given path.
+func parse(path string, ctx context.Context, db *sql.DB) (*Config, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %q: %w", path, err)
+	}
+
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCL(src, path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parse hcl %q: %w", path, diags)
+	}
+
+	var cfg Config
+	if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
+		return nil, fmt.Errorf("decode hcl %q: %w", path, diags)
+	}
+	if err := cfg.ResolveReferences(ctx, db); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
 func (cfg *Config) ResolveReferences(ctx context.Context, db *sql.DB) error {
 	if cfg == nil {
 		return fmt.Errorf("config is nil")
 	}
 
+	// Derive the addressing of networks declared with a cidr and check
+	// that it fits their forward mode
+	for i := range cfg.Networks {
+		if err := cfg.Networks[i].ResolveAddress(); err != nil {
+			return err
+		}
+		if err := cfg.Networks[i].ResolveMACPrefix(); err != nil {
+			return err
+		}
+		if err := cfg.Networks[i].ValidateMode(); err != nil {
+			return err
+		}
+		if err := cfg.Networks[i].ValidateDNS(); err != nil {
+			return err
+		}
+	}
+
 	// Build evaluation context for existing config blocks
 	networksByName, err := cfg.indexNetworks()
 	if err != nil {
@@ -138,6 +182,10 @@ func (cfg *Config) ResolveReferences(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	vmsByName, err := cfg.indexVMs()
+	if err != nil {
+		return err
+	}
 
 	//  Process data blocks (data "store" "..." {})
 	// We'll verify they exist in the DB, then add them to our evaluation maps.
@@ -163,7 +211,7 @@ func (cfg *Config) ResolveReferences(ctx context.Context, db *sql.DB) error {
 	}
 
 	// Construct the shared EvalContext
-	evalCtx := cfg.evalContext(networksByName, storesByName, dataNetworks, dataStores)
+	evalCtx := cfg.evalContext(networksByName, storesByName, vmsByName, dataNetworks, dataStores)
 
 	// Resolve VM references
 	for i := range cfg.VMs {
@@ -180,9 +228,67 @@ func (cfg *Config) ResolveReferences(ctx context.Context, db *sql.DB) error {
 		}
 	}
 
+	// Resolve cluster roles to VM names
+	if len(cfg.Clusters) > 0 {
+		candidates, err := cfg.clusterCandidates(ctx, db)
+		if err != nil {
+			return err
+		}
+		for i := range cfg.Clusters {
+			if err := cfg.Clusters[i].Resolve(evalCtx, candidates); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+// clusterCandidates returns the VMs cluster roles can pick: those defined
+// in the config, then those already in the database.
+func (cfg *Config) clusterCandidates(ctx context.Context, db *sql.DB) ([]cluster.Candidate, error) {
+	candidates := make([]cluster.Candidate, 0, len(cfg.VMs))
+	defined := make(map[string]struct{}, len(cfg.VMs))
+	for _, v := range cfg.VMs {
+		candidates = append(candidates, cluster.Candidate{
+			Name:      v.Name,
+			Namespace: v.Namespace,
+			Labels:    v.Labels,
+		})
+		defined[v.Name] = struct{}{}
+	}
+
+	records, err := database.GetVMRecords(ctx, db, "")
+	if err != nil {
+		return nil, fmt.Errorf("list vms for cluster selectors: %w", err)
+	}
+	for _, r := range records {
+		if _, ok := defined[r.Name]; ok {
+			continue
+		}
+		candidates = append(candidates, cluster.Candidate{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Labels:    r.Labels,
+		})
+	}
+	return candidates, nil
+}
+
+func (cfg *Config) indexVMs() (map[string]struct{}, error) {
+	names := make(map[string]struct{}, len(cfg.VMs))
+	for _, v := range cfg.VMs {
+		if v.Name == "" {
+			return nil, fmt.Errorf("vm with empty name")
+		}
+		if _, exists := names[v.Name]; exists {
+			return nil, fmt.Errorf("duplicate vm %q", v.Name)
+		}
+		names[v.Name] = struct{}{}
+	}
+	return names, nil
+}
+
 func (cfg *Config) indexStores() (map[string]struct{}, error) {
 	stores := make(map[string]struct{}, len(cfg.Stores))
 	for _, s := range cfg.Stores {
@@ -201,10 +307,11 @@ func (cfg *Config) indexStores() (map[string]struct{}, error) {
 //
 //	network.<name>
 //	store.<name>
+//	vm.<name>
 //	data.network.<name>
 //	data.store.<name>
 func (cfg *Config) evalContext(
-	networks, stores, dataNetworks, dataStores map[string]struct{},
+	networks, stores, vmNames, dataNetworks, dataStores map[string]struct{},
 ) *hcl.EvalContext {
 	// Objects for 'network' and 'store'
 	netMap := make(map[string]cty.Value)
@@ -215,6 +322,10 @@ func (cfg *Config) evalContext(
 	for s := range stores {
 		storeMap[s] = cty.StringVal(s)
 	}
+	vmMap := make(map[string]cty.Value)
+	for v := range vmNames {
+		vmMap[v] = cty.StringVal(v)
+	}
 
 	// Objects for 'data.network' and 'data.store'
 	// In HCL, data variables are usually top-level `data` object containing types.
@@ -236,6 +347,7 @@ func (cfg *Config) evalContext(
 		Variables: map[string]cty.Value{
 			"network": cty.ObjectVal(netMap),
 			"store":   cty.ObjectVal(storeMap),
+			"vm":      cty.ObjectVal(vmMap),
 			"data":    dataObj,
 		},
 	}
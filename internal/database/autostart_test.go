@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAutostart(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	vm := VirtualMachine{Name: "web-01", Namespace: "homelab", Autostart: true, CreatedAt: time.Now()}
+	if err := vm.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert vm failed: %v", err)
+	}
+	record, err := GetVMByName(ctx, db, "web-01", "")
+	if err != nil {
+		t.Fatalf("GetVMByName failed: %v", err)
+	}
+	if !record.Autostart {
+		t.Error("GetVMByName lost the autostart of web-01")
+	}
+
+	network := VirtualNetwork{Name: "services", Namespace: "homelab"}
+	if err := network.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+	if err := SetNetworkAutostart(ctx, db, "services", true); err != nil {
+		t.Fatalf("SetNetworkAutostart failed: %v", err)
+	}
+	networks, err := GetNetworks(ctx, db, "homelab")
+	if err != nil {
+		t.Fatalf("GetNetworks failed: %v", err)
+	}
+	if len(networks) != 1 || !networks[0].Autostart {
+		t.Errorf("GetNetworks = %+v, want services with autostart on", networks)
+	}
+}
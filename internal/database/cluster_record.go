@@ -0,0 +1,404 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cluster is a named group of VMs split into roles.
+type Cluster struct {
+	ID         int
+	Name       string
+	Namespace  string
+	Labels     map[string]string
+	StartOrder []string
+	StopOrder  []string
+	RoleSpecs  []RoleSpec      // every role, in declaration order
+	Members    []ClusterMember // VMs referenced by name; selectors pick the rest
+	CreatedAt  time.Time
+}
+
+// ClusterMember places a VM in a role of a cluster.
+type ClusterMember struct {
+	Role   string
+	VMName string
+}
+
+// RoleSpec is a role of a cluster and the label selector picking its VMs, if any.
+type RoleSpec struct {
+	Name     string `json:"name"`
+	Selector string `json:"selector,omitempty"`
+}
+
+// Roles returns the role names of the cluster in declaration order.
+// Records written before roles were stored fall back to the member roles.
+func (c *Cluster) Roles() []string {
+	var roles []string
+	if len(c.RoleSpecs) > 0 {
+		for _, r := range c.RoleSpecs {
+			roles = append(roles, r.Name)
+		}
+		return roles
+	}
+	seen := make(map[string]struct{})
+	for _, m := range c.Members {
+		if _, ok := seen[m.Role]; ok {
+			continue
+		}
+		seen[m.Role] = struct{}{}
+		roles = append(roles, m.Role)
+	}
+	return roles
+}
+
+// EnsureClusterTable creates the clusters and cluster_members tables if they don't exist.
+func EnsureClusterTable(ctx context.Context, db *sql.DB) error {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS ` + clustersTable + ` (
+	  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	  name        TEXT NOT NULL,
+	  namespace   TEXT,
+	  labels      TEXT,
+	  start_order TEXT,
+	  stop_order  TEXT,
+	  created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_clusters_name_namespace
+	  ON ` + clustersTable + `(name, namespace);
+
+	CREATE TABLE IF NOT EXISTS ` + membersTable + ` (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  cluster_id INTEGER NOT NULL,
+	  role       TEXT    NOT NULL,
+	  vm_name    TEXT    NOT NULL,
+	  position   INTEGER NOT NULL,
+	  FOREIGN KEY(cluster_id) REFERENCES ` + clustersTable + `(id) ON DELETE CASCADE
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_cluster_members_vm
+	  ON ` + membersTable + `(cluster_id, vm_name);
+	`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create cluster tables: %w", err)
+	}
+	return ensureColumn(ctx, db, clustersTable, "roles", "TEXT")
+}
+
+// Insert persists the cluster and its members in a single transaction.
+func (c *Cluster) Insert(ctx context.Context, db *sql.DB) (err error) {
+	if db == nil {
+		return fmt.Errorf("DB is nil")
+	}
+	if err := EnsureClusterTable(ctx, db); err != nil {
+		return err
+	}
+
+	labelsJSON, err := json.Marshal(c.Labels)
+	if err != nil {
+		return fmt.Errorf("marshal labels: %w", err)
+	}
+	startJSON, err := json.Marshal(c.StartOrder)
+	if err != nil {
+		return fmt.Errorf("marshal start order: %w", err)
+	}
+	stopJSON, err := json.Marshal(c.StopOrder)
+	if err != nil {
+		return fmt.Errorf("marshal stop order: %w", err)
+	}
+	rolesJSON, err := json.Marshal(c.RoleSpecs)
+	if err != nil {
+		return fmt.Errorf("marshal roles: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	const clusterInsert = `
+		INSERT INTO ` + clustersTable + ` (
+			name, namespace, labels, start_order, stop_order, roles, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := tx.ExecContext(ctx, clusterInsert,
+		c.Name,
+		c.Namespace,
+		string(labelsJSON),
+		string(startJSON),
+		string(stopJSON),
+		string(rolesJSON),
+		c.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("cluster %q already exists in namespace %q", c.Name, c.Namespace)
+		}
+		return fmt.Errorf("insert cluster: %w", err)
+	}
+	clusterID, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+
+	if err = c.insertMembers(ctx, tx, clusterID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+	c.ID = int(clusterID)
+	return nil
+}
+
+// Update rewrites the cluster with the same name and namespace and replaces
+// its members, in a single transaction. Its id and creation time are kept.
+func (c *Cluster) Update(ctx context.Context, db *sql.DB) (err error) {
+	labelsJSON, err := json.Marshal(c.Labels)
+	if err != nil {
+		return fmt.Errorf("marshal labels: %w", err)
+	}
+	startJSON, err := json.Marshal(c.StartOrder)
+	if err != nil {
+		return fmt.Errorf("marshal start order: %w", err)
+	}
+	stopJSON, err := json.Marshal(c.StopOrder)
+	if err != nil {
+		return fmt.Errorf("marshal stop order: %w", err)
+	}
+	rolesJSON, err := json.Marshal(c.RoleSpecs)
+	if err != nil {
+		return fmt.Errorf("marshal roles: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	const clusterUpdate = `
+		UPDATE ` + clustersTable + `
+		SET labels = ?, start_order = ?, stop_order = ?, roles = ?
+		WHERE name = ? AND namespace = ?
+		RETURNING id, created_at
+	`
+	if err = tx.QueryRowContext(ctx, clusterUpdate,
+		string(labelsJSON),
+		string(startJSON),
+		string(stopJSON),
+		string(rolesJSON),
+		c.Name,
+		c.Namespace,
+	).Scan(&c.ID, &c.CreatedAt); err != nil {
+		return fmt.Errorf("update cluster %q: %w", c.Name, err)
+	}
+
+	const membersDelete = `DELETE FROM ` + membersTable + ` WHERE cluster_id = ?`
+	if _, err = tx.ExecContext(ctx, membersDelete, c.ID); err != nil {
+		return fmt.Errorf("failed to delete members of cluster %q: %w", c.Name, err)
+	}
+	if err = c.insertMembers(ctx, tx, int64(c.ID)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+	return nil
+}
+
+// insertMembers adds the members of the cluster with the given id, in order.
+func (c *Cluster) insertMembers(ctx context.Context, tx *sql.Tx, clusterID int64) error {
+	const memberInsert = `
+		INSERT INTO ` + membersTable + ` (cluster_id, role, vm_name, position)
+		VALUES (?, ?, ?, ?)
+	`
+	for i, m := range c.Members {
+		if _, err := tx.ExecContext(ctx, memberInsert, clusterID, m.Role, m.VMName, i); err != nil {
+			return fmt.Errorf("insert member %q of cluster %q: %w", m.VMName, c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the cluster and its members. The VMs themselves are kept.
+func (c *Cluster) Delete(ctx context.Context, db *sql.DB) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Foreign keys are not enforced by default in SQLite, so members are
+	// removed explicitly rather than through ON DELETE CASCADE.
+	const membersDelete = `
+		DELETE FROM ` + membersTable + `
+		WHERE cluster_id IN (
+			SELECT id FROM ` + clustersTable + ` WHERE name = ? AND namespace = ?
+		)
+	`
+	if _, err = tx.ExecContext(ctx, membersDelete, c.Name, c.Namespace); err != nil {
+		return fmt.Errorf("failed to delete members of cluster %q: %w", c.Name, err)
+	}
+	const clusterDelete = `DELETE FROM ` + clustersTable + ` WHERE name = ? AND namespace = ?`
+	if _, err = tx.ExecContext(ctx, clusterDelete, c.Name, c.Namespace); err != nil {
+		return fmt.Errorf("failed to delete cluster %q: %w", c.Name, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+	return nil
+}
+
+const clusterColumns = `id, name, namespace, labels, start_order, stop_order, roles, created_at`
+
+// GetClusters returns every cluster with its members, optionally filtered by namespace.
+func GetClusters(ctx context.Context, db *sql.DB, namespace string) ([]Cluster, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", clusterColumns, clustersTable)
+	args := []any{}
+	if namespace != "" {
+		query += " WHERE namespace = ?"
+		args = append(args, namespace)
+	}
+	query += " ORDER BY name"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query clusters: %w", err)
+	}
+	var clusters []Cluster
+	for rows.Next() {
+		c, err := scanCluster(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	for i := range clusters {
+		if clusters[i].Members, err = getClusterMembers(ctx, db, clusters[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return clusters, nil
+}
+
+// GetClusterByName returns a cluster with its members.
+// If namespace is non-empty, it will be included in the WHERE clause.
+func GetClusterByName(ctx context.Context, db *sql.DB, name, namespace string) (Cluster, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE name = ?", clusterColumns, clustersTable)
+	args := []any{name}
+	if namespace != "" {
+		query += " AND namespace = ?"
+		args = append(args, namespace)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Cluster{}, fmt.Errorf("query cluster %q: %w", name, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Cluster{}, fmt.Errorf("query cluster %q: %w", name, err)
+		}
+		return Cluster{}, fmt.Errorf("no cluster found with name %q: %w", name, sql.ErrNoRows)
+	}
+	c, err := scanCluster(rows)
+	if err != nil {
+		return c, err
+	}
+	rows.Close()
+
+	c.Members, err = getClusterMembers(ctx, db, c.ID)
+	return c, err
+}
+
+func scanCluster(rows *sql.Rows) (Cluster, error) {
+	var (
+		c                 Cluster
+		rawLabels         sql.NullString
+		rawStart, rawStop sql.NullString
+		rawRoles          sql.NullString
+	)
+	if err := rows.Scan(
+		&c.ID,
+		&c.Name,
+		&c.Namespace,
+		&rawLabels,
+		&rawStart,
+		&rawStop,
+		&rawRoles,
+		&c.CreatedAt,
+	); err != nil {
+		return c, fmt.Errorf("scan cluster: %w", err)
+	}
+	fields := []struct {
+		raw  sql.NullString
+		dest any
+	}{
+		{rawLabels, &c.Labels},
+		{rawStart, &c.StartOrder},
+		{rawStop, &c.StopOrder},
+		{rawRoles, &c.RoleSpecs},
+	}
+	for _, f := range fields {
+		if !f.raw.Valid || f.raw.String == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(f.raw.String), f.dest); err != nil {
+			return c, fmt.Errorf("invalid JSON for cluster %q: %w", c.Name, err)
+		}
+	}
+	return c, nil
+}
+
+func getClusterMembers(ctx context.Context, db *sql.DB, clusterID int) ([]ClusterMember, error) {
+	const query = `
+		SELECT role, vm_name FROM ` + membersTable + `
+		WHERE cluster_id = ?
+		ORDER BY position
+	`
+	rows, err := db.QueryContext(ctx, query, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("query cluster members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []ClusterMember
+	for rows.Next() {
+		var m ClusterMember
+		if err := rows.Scan(&m.Role, &m.VMName); err != nil {
+			return nil, fmt.Errorf("scan cluster member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return members, nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestClusterRecords(t *testing.T) {
+	// Setup in-memory DB
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// 1. Insert a cluster with two roles
+	cluster := Cluster{
+		Name:       "k8s",
+		Namespace:  "homelab",
+		Labels:     map[string]string{"app": "kubernetes"},
+		StartOrder: []string{"masters", "workers"},
+		RoleSpecs: []RoleSpec{
+			{Name: "masters"},
+			{Name: "workers", Selector: "role=worker"},
+		},
+		Members: []ClusterMember{
+			{Role: "masters", VMName: "master-01"},
+			{Role: "workers", VMName: "worker-01"},
+			{Role: "workers", VMName: "worker-02"},
+		},
+		CreatedAt: time.Now(),
+	}
+	if err := cluster.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert cluster failed: %v", err)
+	}
+
+	// Duplicate names are rejected per namespace
+	if err := cluster.Insert(ctx, db); err == nil {
+		t.Error("expected error for duplicate cluster, got nil")
+	}
+
+	// 2. Read it back, members in order
+	got, err := GetClusterByName(ctx, db, "k8s", "homelab")
+	if err != nil {
+		t.Fatalf("GetClusterByName failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.Members, cluster.Members) {
+		t.Errorf("members = %v, want %v", got.Members, cluster.Members)
+	}
+	if !reflect.DeepEqual(got.StartOrder, cluster.StartOrder) {
+		t.Errorf("start order = %v, want %v", got.StartOrder, cluster.StartOrder)
+	}
+	if got.Labels["app"] != "kubernetes" {
+		t.Errorf("labels = %v", got.Labels)
+	}
+	if !reflect.DeepEqual(got.RoleSpecs, cluster.RoleSpecs) {
+		t.Errorf("roles = %v, want %v", got.RoleSpecs, cluster.RoleSpecs)
+	}
+	if roles := got.Roles(); !reflect.DeepEqual(roles, []string{"masters", "workers"}) {
+		t.Errorf("roles = %v", roles)
+	}
+
+	// 3. List by namespace
+	listed, err := GetClusters(ctx, db, "other")
+	if err != nil {
+		t.Fatalf("GetClusters failed: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("expected no clusters in namespace other, got %d", len(listed))
+	}
+
+	// 4. Delete removes the members too
+	if err := cluster.Delete(ctx, db); err != nil {
+		t.Fatalf("Delete cluster failed: %v", err)
+	}
+	if _, err := GetClusterByName(ctx, db, "k8s", "homelab"); err == nil {
+		t.Error("expected error after delete, got nil")
+	}
+	var members int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ` + membersTable).Scan(&members); err != nil {
+		t.Fatal(err)
+	}
+	if members != 0 {
+		t.Errorf("expected members to be deleted, %d left", members)
+	}
+}
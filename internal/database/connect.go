@@ -13,13 +13,18 @@ import (
 //			 in /etc/kvmcli/kvmcli.conf for example
 
 const (
-	DBFilePath     = "/home/zakaria/dox/homelab/kvmcli/kvmcli.db"
-	databaseName   = "kvmcli"
-	storesTable    = "stores"
-	imagesTable    = "images"
-	vmsTable       = "vms"
-	networksTable  = "networks"
-	snapshotsTable = "snapshots"
+	DBFilePath       = "/home/zakaria/dox/homelab/kvmcli/kvmcli.db"
+	databaseName     = "kvmcli"
+	storesTable      = "stores"
+	imagesTable      = "images"
+	vmsTable         = "vms"
+	networksTable    = "networks"
+	snapshotsTable   = "snapshots"
+	sshSettingsTable = "ssh_settings"
+	clustersTable    = "clusters"
+	membersTable     = "cluster_members"
+	rolloutsTable    = "rollouts"
+	allocationsTable = "ip_allocations"
 )
 
 // InitDB opens a database handle and verifies the connection using context.
@@ -40,5 +45,31 @@ func InitDB(ctx context.Context) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
 
+	// Bring older database files up to the current schema
+	if err := EnsureSchema(ctx, db); err != nil {
+		return nil, fmt.Errorf("failed to ensure schema: %w", err)
+	}
+
 	return db, nil
 }
+
+// EnsureSchema creates every table kvmcli relies on and adds columns
+// introduced after a database file was first created.
+func EnsureSchema(ctx context.Context, db *sql.DB) error {
+	ensure := []func(context.Context, *sql.DB) error{
+		EnsureStoreTable,
+		EnsureNetworkTable,
+		EnsureVMTable,
+		EnsureSnapshotTable,
+		EnsureSSHSettingsTable,
+		EnsureClusterTable,
+		EnsureRolloutTable,
+		EnsureIPAllocationTable,
+	}
+	for _, fn := range ensure {
+		if err := fn(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
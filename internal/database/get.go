@@ -8,9 +8,9 @@ import (
 )
 
 const (
-	vmColumns = `id, name, namespace, cpu, ram, ip_address, mac_address, network_id, image, disk_size, disk_path, created_at, labels`
+	vmColumns = `id, name, namespace, cpu, ram, ip_address, mac_address, network_id, image, disk_size, disk_path, created_at, labels, COALESCE(restart_required, 0), COALESCE(healthcheck, ''), COALESCE(previous_image, ''), COALESCE(ipv6_address, ''), COALESCE(autostart, 0)`
 	// networkColumns must match the actual table schema order
-	networkColumns = `id, name, namespace, labels, mac_address, bridge, mode, net_address, netmask, dhcp, autostart, created_at`
+	networkColumns = `id, name, namespace, labels, mac_address, bridge, mode, net_address, netmask, dhcp, autostart, created_at, COALESCE(mac_prefix, ''), COALESCE(ips, ''), COALESCE(prefix, 0)`
 )
 
 // GetRecords retrieves all documents of type T from the specified collection
@@ -51,6 +51,11 @@ func GetVMRecords(
 			&vm.DiskPath,
 			&vm.CreatedAt,
 			&rawLabels,
+			&vm.RestartRequired,
+			&vm.HealthCheck,
+			&vm.PreviousImage,
+			&vm.IPv6,
+			&vm.Autostart,
 		); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
@@ -99,6 +104,7 @@ func GetVMByName(
 		&vm.Namespace,
 		&vm.CPU,
 		&vm.RAM,
+		&vm.IP,
 		&vm.MacAddress,
 		&vm.NetworkID,
 		&vm.Image,
@@ -106,9 +112,14 @@ func GetVMByName(
 		&vm.DiskPath,
 		&vm.CreatedAt,
 		&rawLabels,
+		&vm.RestartRequired,
+		&vm.HealthCheck,
+		&vm.PreviousImage,
+		&vm.IPv6,
+		&vm.Autostart,
 	); err != nil {
 		if err == sql.ErrNoRows {
-			return vm, fmt.Errorf("no VM found with name %q", name)
+			return vm, fmt.Errorf("no VM found with name %q: %w", name, err)
 		}
 		return vm, fmt.Errorf("failed to scan VM row: %w", err)
 	}
@@ -141,7 +152,7 @@ func GetNetworks(
 	defer rows.Close()
 	for rows.Next() {
 		var network VirtualNetwork
-		var rawLabels, rawDHCP string
+		var rawLabels, rawDHCP, rawIPs string
 		if err := rows.Scan(
 			&network.ID,
 			&network.Name,
@@ -155,6 +166,9 @@ func GetNetworks(
 			&rawDHCP,
 			&network.Autostart,
 			&network.CreatedAt,
+			&network.MACPrefix,
+			&rawIPs,
+			&network.Prefix,
 		); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
@@ -170,6 +184,9 @@ func GetNetworks(
 				return nil, fmt.Errorf("invalid DHCP JSON: %w", err)
 			}
 		}
+		if err := unmarshalIPs(rawIPs, &network.IPs); err != nil {
+			return nil, err
+		}
 		networks = append(networks, network)
 	}
 	if err := rows.Err(); err != nil {
@@ -298,7 +315,8 @@ func GetStoreByName(
 
 	// Fetch images for this store
 	imgQuery := `
-		SELECT id, name, display, version, os_profile, file, checksum, size, created_at 
+		SELECT id, name, display, version, os_profile, file, checksum, size,
+		       COALESCE(default_user, ''), created_at
 		FROM ` + imagesTable + ` 
 		WHERE store_id = ?
 	`
@@ -314,7 +332,7 @@ func GetStoreByName(
 		img.StoreID = int64(store.ID)
 		if err := imgRows.Scan(
 			&img.ID, &img.Name, &img.Display, &img.Version, &img.OsProfile,
-			&img.File, &img.Checksum, &img.Size, &img.CreatedAt,
+			&img.File, &img.Checksum, &img.Size, &img.User, &img.CreatedAt,
 		); err != nil {
 			return Store{}, fmt.Errorf("scan image failed: %w", err)
 		}
@@ -327,3 +345,29 @@ func GetStoreByName(
 
 	return store, nil
 }
+
+// GetUsedIPs returns the IP addresses assigned to VMs on the given network.
+func GetUsedIPs(ctx context.Context, db *sql.DB, networkID int) ([]string, error) {
+	query := fmt.Sprintf(
+		"SELECT ip_address FROM %s WHERE network_id = ? AND COALESCE(ip_address, '') != ''",
+		vmsTable,
+	)
+	rows, err := db.QueryContext(ctx, query, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var ips []string
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ips = append(ips, ip)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return ips, nil
+}
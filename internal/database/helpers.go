@@ -41,14 +41,19 @@ func (vm *VirtualMachine) ScanRow(row *sql.Row) error {
 func (net *VirtualNetwork) ScanRows(rows *sql.Rows) error {
 	var labelJSON string
 	var DHCPJSON string
+	var IPsJSON string
 	if err := rows.Scan(
 		&net.ID, &net.Name, &net.Namespace,
 		&labelJSON, &net.MacAddress, &net.Bridge,
 		&net.Mode, &net.NetAddress, &net.Netmask,
 		&DHCPJSON, &net.Autostart, &net.CreatedAt,
+		&net.MACPrefix, &IPsJSON, &net.Prefix,
 	); err != nil {
 		return err
 	}
+	if err := unmarshalIPs(IPsJSON, &net.IPs); err != nil {
+		return err
+	}
 
 	// Decode labels
 	if err := json.Unmarshal([]byte(labelJSON), &net.Labels); err != nil {
@@ -66,14 +71,19 @@ func (net *VirtualNetwork) ScanRows(rows *sql.Rows) error {
 func (net *VirtualNetwork) ScanRow(row *sql.Row) error {
 	var labelJSON string
 	var DHCPJSON string
+	var IPsJSON string
 	if err := row.Scan(
 		&net.ID, &net.Name, &net.Namespace,
 		&labelJSON, &net.MacAddress, &net.Bridge,
 		&net.Mode, &net.NetAddress, &net.Netmask,
 		&DHCPJSON, &net.Autostart, &net.CreatedAt,
+		&net.MACPrefix, &IPsJSON, &net.Prefix,
 	); err != nil {
 		return err
 	}
+	if err := unmarshalIPs(IPsJSON, &net.IPs); err != nil {
+		return err
+	}
 
 	// Decode labels
 	if err := json.Unmarshal([]byte(labelJSON), &net.Labels); err != nil {
@@ -117,3 +127,48 @@ func GetNetworkIDByName(ctx context.Context, db *sql.DB, networkName string) (in
 
 	return networkID, nil
 }
+
+// ensureColumn adds a column to an existing table when it is missing.
+// SQLite has no "ADD COLUMN IF NOT EXISTS", so the table info is checked first.
+func ensureColumn(ctx context.Context, db *sql.DB, table, column, definition string) error {
+	exists, err := hasColumn(ctx, db, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// hasColumn reports whether a table has the column. A missing table has none.
+func hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	return false, nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrIPTaken is returned when an address of a network is already allocated.
+var ErrIPTaken = errors.New("address already allocated")
+
+// IPAllocation reserves a static address of a network for a VM.
+type IPAllocation struct {
+	ID        int
+	NetworkID int
+	IP        string
+	VMName    string
+	CreatedAt time.Time
+}
+
+// EnsureIPAllocationTable creates the ip_allocations table if it doesn't exist.
+// The unique indexes make a reservation atomic: two VMs cannot hold the same
+// address, and a VM holds at most one address per network.
+func EnsureIPAllocationTable(ctx context.Context, db *sql.DB) error {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS ` + allocationsTable + ` (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  network_id INTEGER NOT NULL,
+	  ip         TEXT NOT NULL,
+	  vm_name    TEXT NOT NULL,
+	  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	  FOREIGN KEY (network_id) REFERENCES ` + networksTable + `(id) ON DELETE CASCADE
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_allocation_network_ip
+	  ON ` + allocationsTable + `(network_id, ip);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_allocation_network_vm
+	  ON ` + allocationsTable + `(network_id, vm_name);
+	`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create ip_allocations table: %w", err)
+	}
+	return nil
+}
+
+// Insert reserves the address. It returns ErrIPTaken when the address, or
+// another address of the network for the same VM, is already allocated.
+func (a *IPAllocation) Insert(ctx context.Context, db *sql.DB) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	const query = `
+		INSERT INTO ` + allocationsTable + ` (network_id, ip, vm_name, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := db.ExecContext(ctx, query, a.NetworkID, a.IP, a.VMName, a.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("%s for vm %q: %w", a.IP, a.VMName, ErrIPTaken)
+		}
+		return fmt.Errorf("failed to insert ip allocation: %w", err)
+	}
+	return nil
+}
+
+// GetIPAllocations retrieves the allocations of a network, ordered by VM name.
+func GetIPAllocations(ctx context.Context, db *sql.DB, networkID int) ([]IPAllocation, error) {
+	const query = `
+		SELECT id, network_id, ip, vm_name, created_at
+		FROM ` + allocationsTable + `
+		WHERE network_id = ?
+		ORDER BY vm_name
+	`
+	rows, err := db.QueryContext(ctx, query, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var allocations []IPAllocation
+	for rows.Next() {
+		var a IPAllocation
+		if err := rows.Scan(&a.ID, &a.NetworkID, &a.IP, &a.VMName, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return allocations, nil
+}
+
+// DeleteIPAllocation releases the address a VM holds on a network, if any.
+func DeleteIPAllocation(ctx context.Context, db *sql.DB, networkID int, vmName string) error {
+	const stmt = `DELETE FROM ` + allocationsTable + ` WHERE network_id = ? AND vm_name = ?`
+	if _, err := db.ExecContext(ctx, stmt, networkID, vmName); err != nil {
+		return fmt.Errorf("failed to release address of vm %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// DeleteIPAllocationsByNetwork releases every address of a network.
+func DeleteIPAllocationsByNetwork(ctx context.Context, db *sql.DB, networkID int) error {
+	const stmt = `DELETE FROM ` + allocationsTable + ` WHERE network_id = ?`
+	if _, err := db.ExecContext(ctx, stmt, networkID); err != nil {
+		return fmt.Errorf("failed to release addresses of network %d: %w", networkID, err)
+	}
+	return nil
+}
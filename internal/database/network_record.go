@@ -19,10 +19,24 @@ type VirtualNetwork struct {
 	Bridge     string
 	Mode       string
 	NetAddress string
-	Netmask    string
+	Netmask    string // IPv4 only
+	Prefix     int    // prefix length of the subnet of NetAddress
 	DHCP       map[string]string
 	Autostart  bool
-	CreatedAt  time.Time
+	MACPrefix  string // OUI prefix of the MAC addresses handed to its VMs
+	// IPs are the addresses of the network besides NetAddress, such as the
+	// IPv6 side of a dual-stack network.
+	IPs       []NetworkIP
+	CreatedAt time.Time
+}
+
+// NetworkIP is an additional address of a network.
+type NetworkIP struct {
+	Address string            `json:"address"`
+	Netmask string            `json:"netmask,omitempty"` // IPv4 only
+	Prefix  int               `json:"prefix"`
+	DHCP    map[string]string `json:"dhcp,omitempty"`
+	Hosts   []string          `json:"hosts,omitempty"` // addresses of the host entries declared in the config
 }
 
 // EnsureVMTable creates the vms table if it doesn't exist.
@@ -50,6 +64,23 @@ func EnsureNetworkTable(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to create networks table: %w", err)
 	}
+	if err := ensureColumn(ctx, db, networksTable, "mac_prefix", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, networksTable, "ips", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, networksTable, "prefix", "INTEGER"); err != nil {
+		return err
+	}
+	// IPv6 networks used to keep their prefix in the netmask column, as "/64".
+	const migrate = `
+	UPDATE ` + networksTable + `
+	SET prefix = CAST(substr(netmask, 2) AS INTEGER), netmask = ''
+	WHERE netmask LIKE '/%'`
+	if _, err := db.ExecContext(ctx, migrate); err != nil {
+		return fmt.Errorf("move network prefixes out of netmask: %w", err)
+	}
 	return nil
 }
 
@@ -76,6 +107,11 @@ func (net *VirtualNetwork) Insert(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("failed to marshal DHCP: %w", err)
 	}
 
+	IPsJSON, err := json.Marshal(net.IPs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IPs: %w", err)
+	}
+
 	const query = `
 		INSERT INTO networks (
 			name,
@@ -88,8 +124,11 @@ func (net *VirtualNetwork) Insert(ctx context.Context, db *sql.DB) error {
 		  netmask,
 		  dhcp,
 			autostart,
-			created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			created_at,
+			mac_prefix,
+			ips,
+			prefix
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// Execute the query using record values.
@@ -105,6 +144,9 @@ func (net *VirtualNetwork) Insert(ctx context.Context, db *sql.DB) error {
 		string(DHCPJSON),
 		net.Autostart,
 		net.CreatedAt,
+		net.MACPrefix,
+		string(IPsJSON),
+		net.Prefix,
 	); err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return fmt.Errorf("network %q already exists in namespace %q", net.Name, net.Namespace)
@@ -115,6 +157,54 @@ func (net *VirtualNetwork) Insert(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// Update rewrites the configuration of the network record with the given name.
+func (net *VirtualNetwork) Update(ctx context.Context, db *sql.DB) error {
+	labelsJSON, err := json.Marshal(net.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %w", err)
+	}
+	DHCPJSON, err := json.Marshal(net.DHCP)
+	if err != nil {
+		return fmt.Errorf("failed to marshal DHCP: %w", err)
+	}
+	IPsJSON, err := json.Marshal(net.IPs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IPs: %w", err)
+	}
+
+	const stmt = `
+	UPDATE ` + networksTable + `
+	SET labels = ?, bridge = ?, mode = ?, net_address = ?, netmask = ?,
+	    dhcp = ?, mac_prefix = ?, ips = ?, prefix = ?
+	WHERE name = ?
+	`
+	if _, err := db.ExecContext(ctx, stmt,
+		string(labelsJSON),
+		net.Bridge,
+		net.Mode,
+		net.NetAddress,
+		net.Netmask,
+		string(DHCPJSON),
+		net.MACPrefix,
+		string(IPsJSON),
+		net.Prefix,
+		net.Name,
+	); err != nil {
+		return fmt.Errorf("update network %q: %w", net.Name, err)
+	}
+	return nil
+}
+
+// SetNetworkAutostart records whether the named network starts along with
+// the host.
+func SetNetworkAutostart(ctx context.Context, db *sql.DB, name string, autostart bool) error {
+	const stmt = `UPDATE ` + networksTable + ` SET autostart = ? WHERE name = ?`
+	if _, err := db.ExecContext(ctx, stmt, autostart, name); err != nil {
+		return fmt.Errorf("update autostart of network %q: %w", name, err)
+	}
+	return nil
+}
+
 func (net *VirtualNetwork) Delete(ctx context.Context, db *sql.DB) error {
 	// Create a filter matching the record with the specified name
 	query := fmt.Sprintf("DELETE FROM %s WHERE name = ?", networksTable)
@@ -140,12 +230,14 @@ func (net *VirtualNetwork) GetRecord(
 		labels, mac_address, 
 		bridge, mode, 
 		net_address, netmask,
-		dhcp, autostart, created_at
+		dhcp, autostart, created_at,
+		COALESCE(mac_prefix, ''), COALESCE(ips, ''), COALESCE(prefix, 0)
 		FROM networks WHERE name = ?`
 
 	var (
 		rawLabels string
 		rawDHCP   string
+		rawIPs    string
 	)
 	err := db.QueryRowContext(ctx, query, name).Scan(
 		&net.ID,
@@ -160,6 +252,9 @@ func (net *VirtualNetwork) GetRecord(
 		&rawDHCP,
 		&net.Autostart,
 		&net.CreatedAt,
+		&net.MACPrefix,
+		&rawIPs,
+		&net.Prefix,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -174,6 +269,9 @@ func (net *VirtualNetwork) GetRecord(
 	if err := json.Unmarshal([]byte(rawLabels), &net.Labels); err != nil {
 		return fmt.Errorf("failed to parse labels JSON: %w", err)
 	}
+	if err := unmarshalIPs(rawIPs, &net.IPs); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -188,7 +286,8 @@ func (net *VirtualNetwork) GetRecordByNamespace(
 		labels, mac_address, 
 		bridge, mode, 
 		net_address, netmask,
-		dhcp, autostart, created_at
+		dhcp, autostart, created_at,
+		COALESCE(mac_prefix, ''), COALESCE(ips, ''), COALESCE(prefix, 0)
 		FROM %s WHERE namespace = ? AND name = ?`,
 		networksTable,
 	)
@@ -196,6 +295,7 @@ func (net *VirtualNetwork) GetRecordByNamespace(
 	var (
 		labelText string
 		DHCPText  string
+		IPsText   string
 	)
 	err := db.QueryRowContext(ctx, query, namespace, name).Scan(
 		&net.ID,
@@ -210,6 +310,9 @@ func (net *VirtualNetwork) GetRecordByNamespace(
 		&DHCPText,
 		&net.Autostart,
 		&net.CreatedAt,
+		&net.MACPrefix,
+		&IPsText,
+		&net.Prefix,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -229,5 +332,20 @@ func (net *VirtualNetwork) GetRecordByNamespace(
 	if err := json.Unmarshal([]byte(labelText), &net.Labels); err != nil {
 		return fmt.Errorf("failed to parse labels JSON: %w", err)
 	}
+	if err := unmarshalIPs(IPsText, &net.IPs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// unmarshalIPs decodes the ips column; networks recorded before it existed
+// have none.
+func unmarshalIPs(raw string, ips *[]NetworkIP) error {
+	if raw == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(raw), ips); err != nil {
+		return fmt.Errorf("failed to parse IPs JSON: %w", err)
+	}
 	return nil
 }
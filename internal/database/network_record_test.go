@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestNetworkPrefix(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	v6 := VirtualNetwork{Name: "lab6", Namespace: "homelab", NetAddress: "fd00:10::1", Prefix: 64, CreatedAt: time.Now()}
+	if err := v6.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	// A network recorded when the prefix was kept in the netmask column.
+	const legacy = `INSERT INTO ` + networksTable + ` 
+	(name, namespace, labels, mac_address, bridge, mode, net_address, netmask, dhcp, autostart, created_at)
+	VALUES ('old6', 'homelab', 'null', '', 'virbr6', 'nat', 'fd00:20::1', '/64', 'null', 0, CURRENT_TIMESTAMP)`
+	if _, err := db.ExecContext(ctx, legacy); err != nil {
+		t.Fatalf("insert legacy network: %v", err)
+	}
+	if err := EnsureNetworkTable(ctx, db); err != nil {
+		t.Fatalf("EnsureNetworkTable failed: %v", err)
+	}
+
+	for _, name := range []string{"lab6", "old6"} {
+		var got VirtualNetwork
+		if err := got.GetRecordByNamespace(ctx, db, name, "homelab"); err != nil {
+			t.Fatalf("GetRecordByNamespace(%s) failed: %v", name, err)
+		}
+		if got.Netmask != "" || got.Prefix != 64 {
+			t.Errorf("%s: netmask %q, prefix %d, want no netmask and prefix 64", name, got.Netmask, got.Prefix)
+		}
+	}
+}
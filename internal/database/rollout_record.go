@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rollout states.
+const (
+	RolloutRunning   = "running"
+	RolloutPaused    = "paused" // a batch failed; the rollout waits to be resumed
+	RolloutCompleted = "completed"
+)
+
+// ErrNoRollout is returned when a cluster has no recorded rollout.
+var ErrNoRollout = errors.New("no rollout recorded")
+
+// Rollout tracks the progress of a rolling operation over a cluster,
+// so that a rollout paused on a failure can be resumed where it stopped.
+type Rollout struct {
+	ID        int
+	Cluster   string
+	Namespace string
+	Action    string
+	Params    string   // JSON-encoded options the rollout was started with
+	Done      []string // VMs the action completed on, in order
+	Status    string
+	Error     string // why the rollout was paused
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// rolloutSchema is the rollouts table; a cluster is identified by its name
+// and namespace.
+const rolloutSchema = `
+	CREATE TABLE IF NOT EXISTS ` + rolloutsTable + ` (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  cluster    TEXT NOT NULL,
+	  namespace  TEXT NOT NULL DEFAULT '',
+	  action     TEXT NOT NULL,
+	  params     TEXT,
+	  done       TEXT,
+	  status     TEXT NOT NULL,
+	  error      TEXT,
+	  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	  UNIQUE (cluster, namespace)
+	);
+	`
+
+// EnsureRolloutTable creates the rollouts table if it doesn't exist.
+func EnsureRolloutTable(ctx context.Context, db *sql.DB) error {
+	if err := migrateRolloutNamespace(ctx, db); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, rolloutSchema); err != nil {
+		return fmt.Errorf("failed to create rollouts table: %w", err)
+	}
+	return nil
+}
+
+// migrateRolloutNamespace rebuilds a rollouts table keyed by the cluster
+// name only, which SQLite cannot alter in place. Existing rollouts take
+// the namespace of their cluster.
+func migrateRolloutNamespace(ctx context.Context, db *sql.DB) (err error) {
+	exists, err := hasColumn(ctx, db, rolloutsTable, "cluster")
+	if err != nil || !exists {
+		return err
+	}
+	migrated, err := hasColumn(ctx, db, rolloutsTable, "namespace")
+	if err != nil || migrated {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmts := []string{
+		`ALTER TABLE ` + rolloutsTable + ` RENAME TO ` + rolloutsTable + `_old`,
+		rolloutSchema,
+		`INSERT INTO ` + rolloutsTable + ` (
+			id, cluster, namespace, action, params, done, status, error, created_at, updated_at
+		)
+		SELECT o.id, o.cluster,
+		       COALESCE((SELECT c.namespace FROM ` + clustersTable + ` c WHERE c.name = o.cluster LIMIT 1), ''),
+		       o.action, o.params, o.done, o.status, o.error, o.created_at, o.updated_at
+		FROM ` + rolloutsTable + `_old o`,
+		`DROP TABLE ` + rolloutsTable + `_old`,
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to migrate rollouts table: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+	return nil
+}
+
+// Save inserts the rollout, replacing any earlier rollout of the same cluster
+// and namespace.
+func (r *Rollout) Save(ctx context.Context, db *sql.DB) error {
+	done, err := json.Marshal(r.Done)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rollout progress: %w", err)
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	r.UpdatedAt = time.Now()
+
+	const query = `
+		INSERT INTO ` + rolloutsTable + ` (
+			cluster, namespace, action, params, done, status, error, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(cluster, namespace) DO UPDATE SET
+			action = excluded.action,
+			params = excluded.params,
+			done = excluded.done,
+			status = excluded.status,
+			error = excluded.error,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at
+	`
+	if _, err := db.ExecContext(ctx, query,
+		r.Cluster,
+		r.Namespace,
+		r.Action,
+		r.Params,
+		string(done),
+		r.Status,
+		r.Error,
+		r.CreatedAt,
+		r.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save rollout of cluster %q: %w", r.Cluster, err)
+	}
+	return nil
+}
+
+// GetRollout retrieves the last rollout of a cluster. If namespace is
+// empty, the cluster may be in any namespace, as long as only one has a
+// rollout. It returns ErrNoRollout when the cluster was never rolled out.
+func GetRollout(ctx context.Context, db *sql.DB, cluster, namespace string) (Rollout, error) {
+	query := `
+		SELECT id, cluster, namespace, action, COALESCE(params, ''), COALESCE(done, ''),
+		       status, COALESCE(error, ''), created_at, updated_at
+		FROM ` + rolloutsTable + `
+		WHERE cluster = ?
+	`
+	args := []any{cluster}
+	if namespace != "" {
+		query += " AND namespace = ?"
+		args = append(args, namespace)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Rollout{}, fmt.Errorf("failed to fetch rollout of cluster %q: %w", cluster, err)
+	}
+	defer rows.Close()
+
+	var rollouts []Rollout
+	for rows.Next() {
+		var (
+			r    Rollout
+			done string
+		)
+		if err := rows.Scan(
+			&r.ID, &r.Cluster, &r.Namespace, &r.Action, &r.Params, &done,
+			&r.Status, &r.Error, &r.CreatedAt, &r.UpdatedAt,
+		); err != nil {
+			return Rollout{}, fmt.Errorf("failed to scan rollout of cluster %q: %w", cluster, err)
+		}
+		if done != "" {
+			if err := json.Unmarshal([]byte(done), &r.Done); err != nil {
+				return Rollout{}, fmt.Errorf("failed to unmarshal rollout progress: %w", err)
+			}
+		}
+		rollouts = append(rollouts, r)
+	}
+	if err := rows.Err(); err != nil {
+		return Rollout{}, fmt.Errorf("failed to fetch rollout of cluster %q: %w", cluster, err)
+	}
+
+	switch len(rollouts) {
+	case 0:
+		return Rollout{}, fmt.Errorf("cluster %q: %w", cluster, ErrNoRollout)
+	case 1:
+		return rollouts[0], nil
+	default:
+		return Rollout{}, fmt.Errorf("cluster %q has rollouts in several namespaces, pick one", cluster)
+	}
+}
+
+// DeleteRollout forgets the rollout of a cluster of a namespace.
+func DeleteRollout(ctx context.Context, db *sql.DB, cluster, namespace string) error {
+	const stmt = `DELETE FROM ` + rolloutsTable + ` WHERE cluster = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, cluster, namespace); err != nil {
+		return fmt.Errorf("failed to delete rollout of cluster %q: %w", cluster, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRolloutNamespaces(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := EnsureSchema(ctx, db); err != nil {
+		t.Fatalf("EnsureSchema failed: %v", err)
+	}
+
+	prod := Rollout{Cluster: "k8s", Namespace: "prod", Action: "restart", Status: RolloutPaused}
+	staging := Rollout{Cluster: "k8s", Namespace: "staging", Action: "reimage", Status: RolloutRunning}
+	for _, r := range []*Rollout{&prod, &staging} {
+		if err := r.Save(ctx, db); err != nil {
+			t.Fatalf("Save %s failed: %v", r.Namespace, err)
+		}
+	}
+
+	got, err := GetRollout(ctx, db, "k8s", "staging")
+	if err != nil {
+		t.Fatalf("GetRollout failed: %v", err)
+	}
+	if got.Action != "reimage" {
+		t.Errorf("rollout of staging/k8s = %s, want reimage", got.Action)
+	}
+	if _, err := GetRollout(ctx, db, "k8s", ""); err == nil {
+		t.Error("GetRollout without a namespace picked one of two rollouts")
+	}
+
+	if err := DeleteRollout(ctx, db, "k8s", "staging"); err != nil {
+		t.Fatalf("DeleteRollout failed: %v", err)
+	}
+	if got, err := GetRollout(ctx, db, "k8s", ""); err != nil || got.Namespace != "prod" {
+		t.Errorf("GetRollout = %+v, %v, want the rollout of prod/k8s", got, err)
+	}
+	if _, err := GetRollout(ctx, db, "k8s", "staging"); !errors.Is(err, ErrNoRollout) {
+		t.Errorf("GetRollout of a deleted rollout: got %v, want ErrNoRollout", err)
+	}
+}
+
+func TestMigrateRolloutNamespace(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := EnsureClusterTable(ctx, db); err != nil {
+		t.Fatalf("EnsureClusterTable failed: %v", err)
+	}
+	cluster := Cluster{Name: "k8s", Namespace: "homelab"}
+	if err := cluster.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert cluster failed: %v", err)
+	}
+	// The table as it was, keyed by the cluster name only.
+	const legacy = `
+	CREATE TABLE ` + rolloutsTable + ` (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  cluster    TEXT NOT NULL UNIQUE,
+	  action     TEXT NOT NULL,
+	  params     TEXT,
+	  done       TEXT,
+	  status     TEXT NOT NULL,
+	  error      TEXT,
+	  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO ` + rolloutsTable + ` (cluster, action, done, status) VALUES ('k8s', 'restart', '["m1"]', 'paused');
+	`
+	if _, err := db.ExecContext(ctx, legacy); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	if err := EnsureRolloutTable(ctx, db); err != nil {
+		t.Fatalf("EnsureRolloutTable failed: %v", err)
+	}
+	got, err := GetRollout(ctx, db, "k8s", "homelab")
+	if err != nil {
+		t.Fatalf("GetRollout failed: %v", err)
+	}
+	if got.Status != RolloutPaused || len(got.Done) != 1 {
+		t.Errorf("migrated rollout = %+v, want the paused rollout", got)
+	}
+	// The same cluster name in another namespace no longer collides.
+	other := Rollout{Cluster: "k8s", Namespace: "staging", Action: "restart", Status: RolloutRunning}
+	if err := other.Save(ctx, db); err != nil {
+		t.Errorf("Save in another namespace failed: %v", err)
+	}
+}
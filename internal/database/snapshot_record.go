@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Snapshot kinds.
+const (
+	SnapshotInternal = "internal" // stored inside the qcow2 overlay, may include memory
+	SnapshotExternal = "external" // disk-only, a new overlay on top of the current disk
+)
+
+// Snapshot represents a VM snapshot stored in the SQLite database.
+type Snapshot struct {
+	ID          int
+	VMID        int
+	VMName      string // filled from the vms table on read
+	Namespace   string // filled from the vms table on read
+	Name        string
+	Kind        string
+	Description string
+	State       string // domain state when the snapshot was taken
+	DiskPath    string // overlay created by an external snapshot
+	CreatedAt   time.Time
+}
+
+// EnsureSnapshotTable creates the snapshots table if it doesn't exist.
+func EnsureSnapshotTable(ctx context.Context, db *sql.DB) error {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS ` + snapshotsTable + ` (
+	  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	  vm_id       INTEGER NOT NULL,
+	  name        TEXT NOT NULL,
+	  kind        TEXT NOT NULL,
+	  description TEXT,
+	  state       TEXT,
+	  disk_path   TEXT,
+	  created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  FOREIGN KEY (vm_id) REFERENCES ` + vmsTable + `(id) ON DELETE CASCADE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_snapshot_vm_name
+	  ON ` + snapshotsTable + `(vm_id, name);
+	`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create snapshots table: %w", err)
+	}
+	return nil
+}
+
+// Insert persists the snapshot record.
+func (s *Snapshot) Insert(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return fmt.Errorf("DB is nil")
+	}
+	if err := EnsureSnapshotTable(ctx, db); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO ` + snapshotsTable + ` (
+			vm_id, name, kind, description, state, disk_path, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := db.ExecContext(ctx, query,
+		s.VMID,
+		s.Name,
+		s.Kind,
+		s.Description,
+		s.State,
+		s.DiskPath,
+		s.CreatedAt,
+	); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("snapshot %q already exists", s.Name)
+		}
+		return fmt.Errorf("failed to insert snapshot record: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the snapshot record identified by VM and name.
+func (s *Snapshot) Delete(ctx context.Context, db *sql.DB) error {
+	const query = `DELETE FROM ` + snapshotsTable + ` WHERE vm_id = ? AND name = ?`
+	if _, err := db.ExecContext(ctx, query, s.VMID, s.Name); err != nil {
+		return fmt.Errorf("failed to delete snapshot %q: %w", s.Name, err)
+	}
+	return nil
+}
+
+// DeleteSnapshotsByVM removes every snapshot record of a VM.
+func DeleteSnapshotsByVM(ctx context.Context, db *sql.DB, vmID int) error {
+	const query = `DELETE FROM ` + snapshotsTable + ` WHERE vm_id = ?`
+	if _, err := db.ExecContext(ctx, query, vmID); err != nil {
+		return fmt.Errorf("failed to delete snapshots of vm %d: %w", vmID, err)
+	}
+	return nil
+}
+
+const snapshotColumns = `s.id, s.vm_id, v.name, v.namespace, s.name, s.kind,
+	COALESCE(s.description, ''), COALESCE(s.state, ''), COALESCE(s.disk_path, ''), s.created_at`
+
+// GetSnapshots retrieves all snapshots, optionally filtered by the VM namespace.
+func GetSnapshots(ctx context.Context, db *sql.DB, namespace string) ([]Snapshot, error) {
+	query := `SELECT ` + snapshotColumns + `
+		FROM ` + snapshotsTable + ` AS s
+		JOIN ` + vmsTable + ` AS v ON s.vm_id = v.id`
+	args := []any{}
+	if namespace != "" {
+		query += " WHERE v.namespace = ?"
+		args = append(args, namespace)
+	}
+	query += " ORDER BY v.name, s.created_at"
+
+	return querySnapshots(ctx, db, query, args...)
+}
+
+// GetSnapshotsByVM retrieves the snapshots of a single VM, oldest first.
+func GetSnapshotsByVM(ctx context.Context, db *sql.DB, vmID int) ([]Snapshot, error) {
+	query := `SELECT ` + snapshotColumns + `
+		FROM ` + snapshotsTable + ` AS s
+		JOIN ` + vmsTable + ` AS v ON s.vm_id = v.id
+		WHERE s.vm_id = ?
+		ORDER BY s.created_at`
+
+	return querySnapshots(ctx, db, query, vmID)
+}
+
+// GetSnapshot retrieves a single snapshot of a VM by name.
+func GetSnapshot(ctx context.Context, db *sql.DB, vmID int, name string) (Snapshot, error) {
+	query := `SELECT ` + snapshotColumns + `
+		FROM ` + snapshotsTable + ` AS s
+		JOIN ` + vmsTable + ` AS v ON s.vm_id = v.id
+		WHERE s.vm_id = ? AND s.name = ?`
+
+	var snap Snapshot
+	err := db.QueryRowContext(ctx, query, vmID, name).Scan(
+		&snap.ID, &snap.VMID, &snap.VMName, &snap.Namespace, &snap.Name, &snap.Kind,
+		&snap.Description, &snap.State, &snap.DiskPath, &snap.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return snap, fmt.Errorf("snapshot %q not found", name)
+		}
+		return snap, fmt.Errorf("failed to fetch snapshot %q: %w", name, err)
+	}
+	return snap, nil
+}
+
+func querySnapshots(ctx context.Context, db *sql.DB, query string, args ...any) ([]Snapshot, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		var snap Snapshot
+		if err := rows.Scan(
+			&snap.ID, &snap.VMID, &snap.VMName, &snap.Namespace, &snap.Name, &snap.Kind,
+			&snap.Description, &snap.State, &snap.DiskPath, &snap.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return snapshots, nil
+}
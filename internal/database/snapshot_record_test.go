@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSnapshotRecords(t *testing.T) {
+	// Setup in-memory DB
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// 1. Create the VM the snapshots belong to
+	vm := VirtualMachine{
+		Name:      "web-01",
+		Namespace: "homelab",
+		Labels:    map[string]string{"app": "web"},
+		CreatedAt: time.Now(),
+	}
+	if err := vm.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert vm failed: %v", err)
+	}
+	record, err := GetVMByName(ctx, db, "web-01", "")
+	if err != nil {
+		t.Fatalf("GetVMByName failed: %v", err)
+	}
+
+	// 2. Insert one snapshot of each kind
+	snapshots := []Snapshot{
+		{VMID: record.ID, Name: "before-upgrade", Kind: SnapshotInternal, State: "Running"},
+		{
+			VMID:     record.ID,
+			Name:     "disk-only",
+			Kind:     SnapshotExternal,
+			DiskPath: "/tmp/images/web-01.disk-only.qcow2",
+		},
+	}
+	for i := range snapshots {
+		snapshots[i].CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := snapshots[i].Insert(ctx, db); err != nil {
+			t.Fatalf("Insert snapshot failed: %v", err)
+		}
+	}
+
+	// Duplicate names are rejected per VM
+	if err := snapshots[0].Insert(ctx, db); err == nil {
+		t.Error("expected error for duplicate snapshot, got nil")
+	}
+
+	// 3. List by namespace, joined with the VM
+	listed, err := GetSnapshots(ctx, db, "homelab")
+	if err != nil {
+		t.Fatalf("GetSnapshots failed: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(listed))
+	}
+	if listed[0].VMName != "web-01" || listed[0].Namespace != "homelab" {
+		t.Errorf("unexpected vm join: %+v", listed[0])
+	}
+
+	other, err := GetSnapshots(ctx, db, "other")
+	if err != nil {
+		t.Fatalf("GetSnapshots failed: %v", err)
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no snapshots in namespace other, got %d", len(other))
+	}
+
+	// 4. Get a single snapshot
+	snap, err := GetSnapshot(ctx, db, record.ID, "disk-only")
+	if err != nil {
+		t.Fatalf("GetSnapshot failed: %v", err)
+	}
+	if snap.Kind != SnapshotExternal || snap.DiskPath != snapshots[1].DiskPath {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+
+	// 5. Delete one, then the rest
+	if err := snapshots[0].Delete(ctx, db); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := GetSnapshot(ctx, db, record.ID, "before-upgrade"); err == nil {
+		t.Error("expected error for deleted snapshot, got nil")
+	}
+	if err := DeleteSnapshotsByVM(ctx, db, record.ID); err != nil {
+		t.Fatalf("DeleteSnapshotsByVM failed: %v", err)
+	}
+	remaining, err := GetSnapshotsByVM(ctx, db, record.ID)
+	if err != nil {
+		t.Fatalf("GetSnapshotsByVM failed: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected no snapshots left, got %d", len(remaining))
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SSHSettings holds the SSH connection defaults of a namespace.
+type SSHSettings struct {
+	ID           int
+	Namespace    string
+	User         string
+	IdentityFile string
+	Port         int
+	Options      []string
+	CreatedAt    time.Time
+}
+
+// EnsureSSHSettingsTable creates the ssh_settings table if it doesn't exist.
+func EnsureSSHSettingsTable(ctx context.Context, db *sql.DB) error {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS ` + sshSettingsTable + ` (
+	  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	  namespace     TEXT NOT NULL,
+	  user          TEXT,
+	  identity_file TEXT,
+	  port          INTEGER,
+	  options       TEXT,
+	  created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_ssh_settings_namespace
+	  ON ` + sshSettingsTable + `(namespace);
+	`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create ssh settings table: %w", err)
+	}
+	return nil
+}
+
+// Insert persists the SSH settings of a namespace, replacing the settings
+// it already has.
+func (s *SSHSettings) Insert(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return fmt.Errorf("DB is nil")
+	}
+	if err := EnsureSSHSettingsTable(ctx, db); err != nil {
+		return err
+	}
+
+	optionsJSON, err := json.Marshal(s.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ssh options: %w", err)
+	}
+
+	const query = `
+		INSERT INTO ` + sshSettingsTable + ` (
+			namespace, user, identity_file, port, options, created_at
+		) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(namespace) DO UPDATE SET
+			user          = excluded.user,
+			identity_file = excluded.identity_file,
+			port          = excluded.port,
+			options       = excluded.options
+	`
+	if _, err := db.ExecContext(ctx, query,
+		s.Namespace,
+		s.User,
+		s.IdentityFile,
+		s.Port,
+		string(optionsJSON),
+		s.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to insert ssh settings: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the SSH settings of the namespace.
+func (s *SSHSettings) Delete(ctx context.Context, db *sql.DB) error {
+	const query = `DELETE FROM ` + sshSettingsTable + ` WHERE namespace = ?`
+	if _, err := db.ExecContext(ctx, query, s.Namespace); err != nil {
+		return fmt.Errorf("failed to delete ssh settings for %q: %w", s.Namespace, err)
+	}
+	return nil
+}
+
+// GetSSHSettings returns the SSH settings of a namespace.
+// It returns sql.ErrNoRows (wrapped) when the namespace has none.
+func GetSSHSettings(ctx context.Context, db *sql.DB, namespace string) (SSHSettings, error) {
+	const query = `
+		SELECT id, namespace, user, identity_file, port, options, created_at
+		FROM ` + sshSettingsTable + `
+		WHERE namespace = ?
+	`
+	var (
+		s          SSHSettings
+		rawOptions string
+	)
+	err := db.QueryRowContext(ctx, query, namespace).Scan(
+		&s.ID,
+		&s.Namespace,
+		&s.User,
+		&s.IdentityFile,
+		&s.Port,
+		&rawOptions,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s, fmt.Errorf("no ssh settings for namespace %q: %w", namespace, err)
+		}
+		return s, fmt.Errorf("failed to fetch ssh settings: %w", err)
+	}
+	if rawOptions != "" {
+		if err := json.Unmarshal([]byte(rawOptions), &s.Options); err != nil {
+			return s, fmt.Errorf("invalid ssh options JSON: %w", err)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSSHSettingsInsertTwice(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	settings := SSHSettings{Namespace: "homelab", User: "admin", Port: 22, CreatedAt: time.Now()}
+	if err := settings.Insert(ctx, db); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	// Applying the manifest again replaces the settings.
+	settings.User = "ops"
+	settings.Options = []string{"StrictHostKeyChecking=no"}
+	if err := settings.Insert(ctx, db); err != nil {
+		t.Fatalf("second Insert failed: %v", err)
+	}
+
+	got, err := GetSSHSettings(ctx, db, "homelab")
+	if err != nil {
+		t.Fatalf("GetSSHSettings failed: %v", err)
+	}
+	if got.User != "ops" || !reflect.DeepEqual(got.Options, settings.Options) {
+		t.Errorf("GetSSHSettings = %+v, want user ops and the new options", got)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+sshSettingsTable).Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ssh settings rows = %d, want 1", count)
+	}
+}
@@ -31,6 +31,7 @@ type Image struct {
 	File      string
 	Checksum  string
 	Size      string
+	User      string // default SSH user for VMs booted from this image
 	CreatedAt time.Time
 }
 
@@ -49,6 +50,7 @@ type VMImageInfo struct {
 	ImageFile      string
 	Checksum       string
 	Size           string
+	DefaultUser    string
 }
 
 // NewStore creates a new store record from the provided store configuration.
@@ -94,6 +96,7 @@ func EnsureStoreTable(ctx context.Context, db *sql.DB) error {
 		  file       TEXT,
 		  checksum   TEXT,
 		  size       TEXT,
+		  default_user TEXT,
 		  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		  FOREIGN KEY(store_id) REFERENCES ` + storesTable + `(id) ON DELETE CASCADE
 		);
@@ -103,7 +106,7 @@ func EnsureStoreTable(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to create store table: %w", err)
 	}
-	return nil
+	return ensureColumn(ctx, db, imagesTable, "default_user", "TEXT")
 }
 
 // func getImage(
@@ -172,7 +175,8 @@ func GetImage(ctx context.Context, db *sql.DB, imgName string) (*VMImageInfo, er
 		SELECT 
 		store.id, store.name, store.namespace,
 		image.id, image.store_id, image.name, image.display, image.version, image.os_profile,
-		store.artifacts_path, store.images_path, image.file, image.checksum, image.size
+		store.artifacts_path, store.images_path, image.file, image.checksum, image.size,
+		COALESCE(image.default_user, '')
 		FROM ` + imagesTable + ` AS image
 		JOIN ` + storesTable + ` AS store ON image.store_id = store.id
 		WHERE image.name = ?;
@@ -184,6 +188,7 @@ func GetImage(ctx context.Context, db *sql.DB, imgName string) (*VMImageInfo, er
 		&rec.ImageID, &rec.ImageStoreID, &rec.ImageName, &rec.ImageDisplay, &rec.ImageVersion,
 		&rec.OsProfile, &rec.ArtifactsPath, &rec.ImagesPath,
 		&rec.ImageFile, &rec.Checksum, &rec.Size,
+		&rec.DefaultUser,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no image %q in store", imgName)
@@ -214,6 +219,21 @@ func GetStoreIDByName(ctx context.Context, db *sql.DB, name string) (int, error)
 	return id, nil
 }
 
+// GetStoreNameByID retrieves the name of a store by its database ID.
+func GetStoreNameByID(ctx context.Context, db *sql.DB, id int) (string, error) {
+	const query = `SELECT name FROM ` + storesTable + ` WHERE id = ?`
+
+	var name string
+	err := db.QueryRowContext(ctx, query, id).Scan(&name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("no store found with ID %d", id)
+		}
+		return "", fmt.Errorf("query store name by ID: %w", err)
+	}
+	return name, nil
+}
+
 func (store *Store) Insert(ctx context.Context, db *sql.DB) error {
 	// 1. Ensure tables exist (including the images table!)
 	if err := EnsureStoreTable(ctx, db); err != nil {
@@ -274,8 +294,8 @@ func (store *Store) Insert(ctx context.Context, db *sql.DB) error {
 	const imgInsert = `
 		INSERT INTO ` + imagesTable + ` (
 			store_id, name, display, version, os_profile,
-			file, checksum, size
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			file, checksum, size, default_user
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	for _, img := range store.Images {
 		_, err = tx.ExecContext(ctx, imgInsert,
@@ -287,6 +307,7 @@ func (store *Store) Insert(ctx context.Context, db *sql.DB) error {
 			img.File,
 			img.Checksum,
 			img.Size,
+			img.User,
 		)
 		if err != nil {
 			return fmt.Errorf("insert image %v: %w", img, err)
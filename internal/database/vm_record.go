@@ -18,6 +18,7 @@ type VirtualMachine struct {
 	CPU        int
 	RAM        int
 	IP         string
+	IPv6       string // static IPv6 address on a dual-stack network
 	MacAddress string
 	NetworkID  int
 	StoreID    int
@@ -25,6 +26,16 @@ type VirtualMachine struct {
 	DiskSize   string
 	DiskPath   string
 	CreatedAt  time.Time
+	// RestartRequired is set when the persistent definition changed but the
+	// running domain could not pick the change up live.
+	RestartRequired bool
+	// HealthCheck is the JSON-encoded readiness check of the VM, if any.
+	HealthCheck string
+	// PreviousImage is the image the VM ran before an unconfirmed re-image;
+	// its overlay is kept until the re-image is confirmed or rolled back.
+	PreviousImage string
+	// Autostart starts the VM along with the host.
+	Autostart bool
 	// SnapshotIDs []string we don't use snapshot id here, in the snapshot table we reference  t the vm
 }
 
@@ -57,7 +68,19 @@ func EnsureVMTable(ctx context.Context, db *sql.DB) error {
 	if _, err := db.ExecContext(ctx, schema); err != nil {
 		return fmt.Errorf("EnsureVMTable: failed to create table/index: %w", err)
 	}
-	return nil
+	if err := ensureColumn(ctx, db, vmsTable, "restart_required", "BOOLEAN DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, vmsTable, "healthcheck", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, vmsTable, "previous_image", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, vmsTable, "ipv6_address", "TEXT"); err != nil {
+		return err
+	}
+	return ensureColumn(ctx, db, vmsTable, "autostart", "BOOLEAN DEFAULT 0")
 }
 
 func (vmr *VirtualMachine) GetRecord(
@@ -70,7 +93,9 @@ func (vmr *VirtualMachine) GetRecord(
 		       cpu, ram, ip_address, mac_address, 
 		       network_id, store_id, image, 
 		       disk_size, disk_path, 
-		       created_at, labels
+		       created_at, labels, COALESCE(healthcheck, ''),
+		       COALESCE(previous_image, ''), COALESCE(ipv6_address, ''),
+		       COALESCE(autostart, 0)
 		FROM %s
 		WHERE name = ?`, vmsTable)
 
@@ -92,6 +117,10 @@ func (vmr *VirtualMachine) GetRecord(
 		&vmr.DiskPath,
 		&vmr.CreatedAt,
 		&labelText,
+		&vmr.HealthCheck,
+		&vmr.PreviousImage,
+		&vmr.IPv6,
+		&vmr.Autostart,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -199,8 +228,11 @@ func (vmr *VirtualMachine) Insert(ctx context.Context, db *sql.DB) error {
 			disk_size,
 			disk_path,
 			created_at,
-			labels
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			labels,
+			healthcheck,
+			ipv6_address,
+			autostart
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 
 	// Execute the query using record values.
@@ -218,6 +250,9 @@ func (vmr *VirtualMachine) Insert(ctx context.Context, db *sql.DB) error {
 		vmr.DiskPath,
 		vmr.CreatedAt,
 		string(labelsJSON),
+		vmr.HealthCheck,
+		vmr.IPv6,
+		vmr.Autostart,
 	); err != nil {
 		return fmt.Errorf("failed to insert VM record: %w", err)
 	}
@@ -237,3 +272,77 @@ func (v *VirtualMachine) Delete(ctx context.Context, db *sql.DB) error {
 	}
 	return nil
 }
+
+// Update persists the CPU, memory and restart flag of an existing VM record.
+func (vmr *VirtualMachine) Update(ctx context.Context, db *sql.DB) error {
+	const stmt = `
+	UPDATE ` + vmsTable + `
+	SET cpu = ?, ram = ?, restart_required = ?
+	WHERE name = ? AND namespace = ?
+	`
+	if _, err := db.ExecContext(ctx, stmt,
+		vmr.CPU,
+		vmr.RAM,
+		vmr.RestartRequired,
+		vmr.Name,
+		vmr.Namespace,
+	); err != nil {
+		return fmt.Errorf("update vm: %w", err)
+	}
+	return nil
+}
+
+// SetRestartRequired sets or clears the restart flag of the named VM of a namespace.
+func SetRestartRequired(ctx context.Context, db *sql.DB, name, namespace string, required bool) error {
+	const stmt = `UPDATE ` + vmsTable + ` SET restart_required = ? WHERE name = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, required, name, namespace); err != nil {
+		return fmt.Errorf("update restart flag of vm %q: %w", name, err)
+	}
+	return nil
+}
+
+// SetVMAutostart records whether the named VM of a namespace starts along
+// with the host.
+func SetVMAutostart(ctx context.Context, db *sql.DB, name, namespace string, autostart bool) error {
+	const stmt = `UPDATE ` + vmsTable + ` SET autostart = ? WHERE name = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, autostart, name, namespace); err != nil {
+		return fmt.Errorf("update autostart of vm %q: %w", name, err)
+	}
+	return nil
+}
+
+// SetHealthCheck replaces the JSON-encoded health check of the named VM of a namespace.
+func SetHealthCheck(ctx context.Context, db *sql.DB, name, namespace, check string) error {
+	const stmt = `UPDATE ` + vmsTable + ` SET healthcheck = ? WHERE name = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, check, name, namespace); err != nil {
+		return fmt.Errorf("update health check of vm %q: %w", name, err)
+	}
+	return nil
+}
+
+// SetImage records the store image and store the disk of the named VM of a
+// namespace is based on, and the image it ran before an unconfirmed
+// re-image, if any.
+func SetImage(
+	ctx context.Context,
+	db *sql.DB,
+	name, namespace, image string,
+	storeID int,
+	previous string,
+) error {
+	const stmt = `UPDATE ` + vmsTable + ` SET image = ?, store_id = ?, previous_image = ? WHERE name = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, image, storeID, previous, name, namespace); err != nil {
+		return fmt.Errorf("update image of vm %q: %w", name, err)
+	}
+	return nil
+}
+
+// SetPreviousImage records the image the named VM of a namespace ran before
+// an unconfirmed re-image; an empty image marks the re-image as settled.
+func SetPreviousImage(ctx context.Context, db *sql.DB, name, namespace, image string) error {
+	const stmt = `UPDATE ` + vmsTable + ` SET previous_image = ? WHERE name = ? AND namespace = ?`
+	if _, err := db.ExecContext(ctx, stmt, image, name, namespace); err != nil {
+		return fmt.Errorf("update previous image of vm %q: %w", name, err)
+	}
+	return nil
+}
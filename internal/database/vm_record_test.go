@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestVMSettersNamespace checks that the setters of a VM leave same-named
+// VMs of other namespaces alone.
+func TestVMSettersNamespace(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	for _, namespace := range []string{"homelab", "staging"} {
+		vm := VirtualMachine{Name: "web-01", Namespace: namespace, CreatedAt: time.Now()}
+		if err := vm.Insert(ctx, db); err != nil {
+			t.Fatalf("Insert vm failed: %v", err)
+		}
+	}
+
+	if err := SetRestartRequired(ctx, db, "web-01", "homelab", true); err != nil {
+		t.Fatalf("SetRestartRequired failed: %v", err)
+	}
+	if err := SetHealthCheck(ctx, db, "web-01", "homelab", `{"type":"tcp","port":22}`); err != nil {
+		t.Fatalf("SetHealthCheck failed: %v", err)
+	}
+	if err := SetVMAutostart(ctx, db, "web-01", "homelab", true); err != nil {
+		t.Fatalf("SetVMAutostart failed: %v", err)
+	}
+	if err := SetImage(ctx, db, "web-01", "homelab", "rocky-9", 0, "rocky-8"); err != nil {
+		t.Fatalf("SetImage failed: %v", err)
+	}
+
+	homelab, err := GetVMByName(ctx, db, "web-01", "homelab")
+	if err != nil {
+		t.Fatalf("GetVMByName failed: %v", err)
+	}
+	staging, err := GetVMByName(ctx, db, "web-01", "staging")
+	if err != nil {
+		t.Fatalf("GetVMByName failed: %v", err)
+	}
+	if !homelab.RestartRequired {
+		t.Error("restart flag of homelab/web-01 not set")
+	}
+	if staging.RestartRequired {
+		t.Error("restart flag of staging/web-01 set too")
+	}
+	if homelab.HealthCheck == "" {
+		t.Error("health check of homelab/web-01 not set")
+	}
+	if staging.HealthCheck != "" {
+		t.Errorf("health check of staging/web-01 set too: %s", staging.HealthCheck)
+	}
+	if !homelab.Autostart {
+		t.Error("autostart of homelab/web-01 not set")
+	}
+	if staging.Autostart {
+		t.Error("autostart of staging/web-01 set too")
+	}
+	if homelab.Image != "rocky-9" || homelab.PreviousImage != "rocky-8" {
+		t.Errorf("images of homelab/web-01 = %q, %q, want rocky-9, rocky-8", homelab.Image, homelab.PreviousImage)
+	}
+	if staging.Image != "" || staging.PreviousImage != "" {
+		t.Errorf("images of staging/web-01 set too: %q, %q", staging.Image, staging.PreviousImage)
+	}
+
+	if err := SetPreviousImage(ctx, db, "web-01", "staging", "rocky-8"); err != nil {
+		t.Fatalf("SetPreviousImage failed: %v", err)
+	}
+	if homelab, err = GetVMByName(ctx, db, "web-01", "homelab"); err != nil {
+		t.Fatalf("GetVMByName failed: %v", err)
+	}
+	if homelab.PreviousImage != "rocky-8" {
+		t.Errorf("previous image of homelab/web-01 = %q, want rocky-8", homelab.PreviousImage)
+	}
+}
@@ -0,0 +1,218 @@
+// Package labels implements Kubernetes-style label selectors.
+//
+// A selector is a comma-separated list of requirements that must all hold:
+//
+//	app=web, tier!=cache          equality and inequality (== is accepted too)
+//	env in (prod, staging)        set membership
+//	env notin (dev)               set exclusion, also true when env is unset
+//	gpu, !spot                    existence and absence of a key
+package labels
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Operator is the comparison a requirement applies to a label.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition on one label key.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector matches a set of labels when all of its requirements hold.
+// The empty selector matches everything.
+type Selector []Requirement
+
+// Parse parses a selector expression.
+func Parse(expr string) (Selector, error) {
+	var sel Selector
+	for _, part := range splitTopLevel(expr) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("selector %q: empty requirement", expr)
+		}
+		req, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", expr, err)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// Matches reports whether the labels satisfy every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the selector in its canonical form.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Matches reports whether the labels satisfy the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	case Equals, In:
+		return ok && slices.Contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	}
+	return false
+}
+
+// String renders the requirement in its canonical form.
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + string(r.Operator) + r.Values[0]
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	if key, ok := strings.CutPrefix(s, "!"); ok && !strings.Contains(key, "=") {
+		key = strings.TrimSpace(key)
+		return Requirement{Key: key, Operator: DoesNotExist}, validateKey(key)
+	}
+
+	for _, op := range []struct {
+		token string
+		op    Operator
+	}{
+		{"!=", NotEquals},
+		{"==", Equals},
+		{"=", Equals},
+	} {
+		key, value, found := strings.Cut(s, op.token)
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := validateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		if err := validateValue(value); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: op.op, Values: []string{value}}, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 1 {
+		return Requirement{Key: fields[0], Operator: Exists}, validateKey(fields[0])
+	}
+	if len(fields) < 3 || (fields[1] != string(In) && fields[1] != string(NotIn)) {
+		return Requirement{}, fmt.Errorf("invalid requirement %q", s)
+	}
+
+	key := fields[0]
+	if err := validateKey(key); err != nil {
+		return Requirement{}, err
+	}
+	set := strings.TrimSpace(strings.Join(fields[2:], " "))
+	if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+		return Requirement{}, fmt.Errorf("%s %s: values must be in parentheses", key, fields[1])
+	}
+	var values []string
+	for _, v := range strings.Split(set[1:len(set)-1], ",") {
+		v = strings.TrimSpace(v)
+		if err := validateValue(v); err != nil {
+			return Requirement{}, err
+		}
+		if v == "" {
+			return Requirement{}, fmt.Errorf("%s %s: empty value", key, fields[1])
+		}
+		values = append(values, v)
+	}
+	return Requirement{Key: key, Operator: Operator(fields[1]), Values: values}, nil
+}
+
+// splitTopLevel splits on the commas that are not inside parentheses.
+func splitTopLevel(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("empty label key")
+	}
+	if !validChars(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+func validateValue(value string) error {
+	if !validChars(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
+
+// validChars reports whether s only holds the characters allowed in label
+// keys and values: letters, digits, '-', '_', '.' and '/'.
+func validChars(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
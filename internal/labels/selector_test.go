@@ -0,0 +1,66 @@
+package labels
+
+import "testing"
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app": "kubernetes", "env": "prod", "role": "master"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"app=kubernetes", true},
+		{"app==kubernetes", true},
+		{"app=web", false},
+		{"app!=web", true},
+		{"missing!=x", true},
+		{"app=kubernetes,env=prod", true},
+		{"app=kubernetes, env=dev", false},
+		{"env in (prod, staging)", true},
+		{"env in (dev,staging)", false},
+		{"env notin (dev)", true},
+		{"missing notin (dev)", true},
+		{"role", true},
+		{"gpu", false},
+		{"!gpu", true},
+		{"!role", false},
+		{"role in (master,worker), !spot, app", true},
+	}
+	for _, tt := range tests {
+		sel, err := Parse(tt.selector)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.selector, err)
+			continue
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("%q.Matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"app=web,",
+		"=web",
+		"env in prod",
+		"env in ()",
+		"env within (prod)",
+		"app=we b",
+		"!",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	sel, err := Parse(" env in (prod, staging) ,app==web,!spot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sel.String(), "env in (prod,staging),app=web,!spot"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
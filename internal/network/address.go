@@ -0,0 +1,244 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// NextFreeIP returns the first IPv4 address in [start, end] that is not in used.
+func NextFreeIP(start, end string, used []string) (string, error) {
+	first := net.ParseIP(start).To4()
+	last := net.ParseIP(end).To4()
+	if first == nil || last == nil {
+		return "", fmt.Errorf("invalid IPv4 range %q - %q", start, end)
+	}
+
+	taken := make(map[string]bool, len(used))
+	for _, ip := range used {
+		taken[ip] = true
+	}
+
+	lo, hi := binary.BigEndian.Uint32(first), binary.BigEndian.Uint32(last)
+	for n := lo; n <= hi && n >= lo; n++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, n)
+		if !taken[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free address left in %s - %s", start, end)
+}
+
+// ResolveAddress derives the gateway address, netmask and prefix of the
+// network from its cidr. When cidr is the subnet address, the gateway is the
+// first address of the subnet; a netaddress inside the subnet overrides it.
+// Without a dhcp block, DHCP hands out the upper half of the subnet.
+// A netaddress outside cidr or a netmask that disagrees with it is rejected.
+// The ip blocks of the network are resolved the same way; a network has at
+// most one address per family.
+func (c *Config) ResolveAddress() error {
+	if err := c.resolveCIDR(); err != nil {
+		return err
+	}
+	ipv6 := map[bool]bool{}
+	if c.NetAddress != "" {
+		ipv6[c.IsIPv6()] = true
+	}
+	for i := range c.IPs {
+		b := &c.IPs[i]
+		if err := b.resolve(c.Name); err != nil {
+			return err
+		}
+		if ipv6[b.IsIPv6()] {
+			return fmt.Errorf("network %q: more than one %s address", c.Name, family(b.IsIPv6()))
+		}
+		ipv6[b.IsIPv6()] = true
+	}
+	return nil
+}
+
+func (c *Config) resolveCIDR() error {
+	if c.CIDR == "" {
+		return nil
+	}
+	ip, subnet, err := net.ParseCIDR(c.CIDR)
+	if err != nil {
+		return fmt.Errorf("network %q: invalid cidr %q", c.Name, c.CIDR)
+	}
+	ipv4 := ip.To4() != nil
+	if ipv4 {
+		ip = ip.To4()
+	}
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 2 {
+		return fmt.Errorf("network %q: cidr %q leaves no room for hosts", c.Name, c.CIDR)
+	}
+	first, last := subnetBounds(subnet)
+
+	gateway := ip
+	if ip.Equal(subnet.IP) {
+		gateway = addToIP(first, 1)
+	}
+	if c.NetAddress != "" {
+		addr := net.ParseIP(c.NetAddress)
+		switch {
+		case addr == nil || !subnet.Contains(addr):
+			return fmt.Errorf("network %q: netaddress %q is outside cidr %q", c.Name, c.NetAddress, c.CIDR)
+		case !ip.Equal(subnet.IP) && !addr.Equal(ip):
+			return fmt.Errorf("network %q: netaddress %q conflicts with cidr %q", c.Name, c.NetAddress, c.CIDR)
+		}
+		gateway = addr
+	}
+	if ipv4 {
+		gateway = gateway.To4()
+		if gateway.Equal(last) {
+			return fmt.Errorf("network %q: %s is the broadcast address of %s", c.Name, gateway, subnet)
+		}
+	}
+	if gateway.Equal(first) {
+		return fmt.Errorf("network %q: %s is the subnet address of %s", c.Name, gateway, subnet)
+	}
+
+	mask := net.IP(subnet.Mask).String()
+	if c.NetMask != "" {
+		if !ipv4 {
+			return fmt.Errorf("network %q: netmask does not apply to IPv6, use the cidr prefix", c.Name)
+		}
+		if m := net.ParseIP(c.NetMask); m == nil || !m.Equal(net.IP(subnet.Mask)) {
+			return fmt.Errorf("network %q: netmask %q conflicts with cidr %q (%s)", c.Name, c.NetMask, c.CIDR, mask)
+		}
+	}
+
+	c.NetAddress = gateway.String()
+	c.Prefix = ones
+	if ipv4 {
+		c.NetMask = mask
+	}
+
+	if c.DHCP == nil {
+		c.DHCP = defaultDHCPRange(subnet, gateway)
+		return nil
+	}
+	for _, bound := range []string{c.DHCP.Start, c.DHCP.End} {
+		if addr := net.ParseIP(bound); addr == nil || !subnet.Contains(addr) {
+			return fmt.Errorf("network %q: dhcp address %q is outside cidr %q", c.Name, bound, c.CIDR)
+		}
+	}
+	return nil
+}
+
+// IsIPv6 reports whether the network is addressed over IPv6.
+func (c *Config) IsIPv6() bool {
+	return isIPv6(c.NetAddress)
+}
+
+// IsIPv6 reports whether the ip block is an IPv6 subnet.
+func (b *IPBlock) IsIPv6() bool {
+	return isIPv6(b.Address)
+}
+
+// resolve derives the address, netmask, prefix and default DHCP range of the
+// ip block from its cidr and checks its host entries.
+func (b *IPBlock) resolve(network string) error {
+	if b.CIDR == "" {
+		return fmt.Errorf("network %q: an ip block needs a cidr", network)
+	}
+	c := Config{Name: network, CIDR: b.CIDR, NetAddress: b.Address, DHCP: b.DHCP}
+	if err := c.resolveCIDR(); err != nil {
+		return err
+	}
+	b.Address, b.NetMask, b.Prefix, b.DHCP = c.NetAddress, c.NetMask, c.Prefix, c.DHCP
+
+	_, subnet, _ := net.ParseCIDR(b.CIDR)
+	v6 := b.IsIPv6()
+	for _, h := range b.Hosts {
+		if addr := net.ParseIP(h.IP); addr == nil || !subnet.Contains(addr) {
+			return fmt.Errorf("network %q: host address %q is outside cidr %q", network, h.IP, b.CIDR)
+		}
+		switch {
+		case v6 && h.MAC != "":
+			return fmt.Errorf("network %q: IPv6 host %s is matched on id or name, not mac", network, h.IP)
+		case v6 && h.ID == "" && h.Name == "":
+			return fmt.Errorf("network %q: IPv6 host %s needs an id or name", network, h.IP)
+		case !v6 && h.ID != "":
+			return fmt.Errorf("network %q: IPv4 host %s is matched on mac or name, not id", network, h.IP)
+		case !v6 && h.MAC == "" && h.Name == "":
+			return fmt.Errorf("network %q: IPv4 host %s needs a mac or name", network, h.IP)
+		}
+		if h.MAC != "" {
+			if err := validateMAC(h.MAC); err != nil {
+				return fmt.Errorf("network %q: %w", network, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isIPv6(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
+
+// family names the address family.
+func family(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// maxIPv6DHCPRange is the largest IPv6 DHCP range libvirt accepts.
+const maxIPv6DHCPRange = 1 << 16
+
+// defaultDHCPRange covers the upper half of the subnet, leaving the lower
+// half for static addresses. The broadcast address of an IPv4 subnet and
+// the gateway are kept out of the range. An IPv6 range is capped at
+// maxIPv6DHCPRange addresses, from ::1:0 to ::1:ffff of a /64.
+func defaultDHCPRange(subnet *net.IPNet, gateway net.IP) *DHCP {
+	ones, bits := subnet.Mask.Size()
+	first, last := subnetBounds(subnet)
+
+	half := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones-1))
+	start := addToIPBig(first, half)
+	end := last
+	if subnet.IP.To4() != nil {
+		end = addToIP(last, -1)
+	} else if half.Cmp(big.NewInt(maxIPv6DHCPRange)) > 0 {
+		start = addToIP(first, maxIPv6DHCPRange)
+		end = addToIP(start, maxIPv6DHCPRange-1)
+	}
+	switch {
+	case gateway.Equal(start):
+		start = addToIP(start, 1)
+	case gateway.Equal(end):
+		end = addToIP(end, -1)
+	}
+	return &DHCP{Start: start.String(), End: end.String()}
+}
+
+// subnetBounds returns the first and last address of the subnet.
+func subnetBounds(subnet *net.IPNet) (net.IP, net.IP) {
+	first := subnet.IP
+	if v4 := first.To4(); v4 != nil {
+		first = v4
+	}
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^subnet.Mask[len(subnet.Mask)-len(first)+i]
+	}
+	return first, last
+}
+
+// addToIP returns ip shifted by n addresses.
+func addToIP(ip net.IP, n int64) net.IP {
+	return addToIPBig(ip, big.NewInt(n))
+}
+
+func addToIPBig(ip net.IP, n *big.Int) net.IP {
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip), n)
+	out := make(net.IP, len(ip))
+	sum.FillBytes(out)
+	return out
+}
@@ -0,0 +1,163 @@
+package network
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   Config
+	}{
+		{
+			name:   "subnet address",
+			config: Config{CIDR: "192.168.100.0/24"},
+			want: Config{
+				CIDR:       "192.168.100.0/24",
+				NetAddress: "192.168.100.1",
+				NetMask:    "255.255.255.0",
+				Prefix:     24,
+				DHCP:       &DHCP{Start: "192.168.100.128", End: "192.168.100.254"},
+			},
+		},
+		{
+			name: "gateway in cidr, explicit dhcp",
+			config: Config{
+				CIDR: "10.10.0.254/23",
+				DHCP: &DHCP{Start: "10.10.0.10", End: "10.10.0.100"},
+			},
+			want: Config{
+				CIDR:       "10.10.0.254/23",
+				NetAddress: "10.10.0.254",
+				NetMask:    "255.255.254.0",
+				Prefix:     23,
+				DHCP:       &DHCP{Start: "10.10.0.10", End: "10.10.0.100"},
+			},
+		},
+		{
+			name:   "matching netaddress and netmask",
+			config: Config{CIDR: "172.16.0.0/28", NetAddress: "172.16.0.14", NetMask: "255.255.255.240"},
+			want: Config{
+				CIDR:       "172.16.0.0/28",
+				NetAddress: "172.16.0.14",
+				NetMask:    "255.255.255.240",
+				Prefix:     28,
+				DHCP:       &DHCP{Start: "172.16.0.8", End: "172.16.0.13"},
+			},
+		},
+		{
+			name:   "ipv6",
+			config: Config{CIDR: "fd00:10::/64"},
+			want: Config{
+				CIDR:       "fd00:10::/64",
+				NetAddress: "fd00:10::1",
+				Prefix:     64,
+				DHCP:       &DHCP{Start: "fd00:10::1:0", End: "fd00:10::1:ffff"},
+			},
+		},
+		{
+			name:   "small ipv6 subnet",
+			config: Config{CIDR: "fd00:20::/112"},
+			want: Config{
+				CIDR:       "fd00:20::/112",
+				NetAddress: "fd00:20::1",
+				Prefix:     112,
+				DHCP:       &DHCP{Start: "fd00:20::8000", End: "fd00:20::ffff"},
+			},
+		},
+		{
+			name:   "legacy netaddress and netmask",
+			config: Config{NetAddress: "192.168.1.1", NetMask: "255.255.255.0"},
+			want:   Config{NetAddress: "192.168.1.1", NetMask: "255.255.255.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config
+			if err := got.ResolveAddress(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v (dhcp %+v), want %+v (dhcp %+v)", got, got.DHCP, tt.want, tt.want.DHCP)
+			}
+			// Resolving again must not change anything.
+			again := got
+			if err := again.ResolveAddress(); err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("second resolve: %+v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestResolveAddressConflicts(t *testing.T) {
+	invalid := map[string]Config{
+		"bad cidr":              {CIDR: "192.168.100.0/33"},
+		"no hosts":              {CIDR: "192.168.100.0/31"},
+		"netaddress outside":    {CIDR: "192.168.100.0/24", NetAddress: "192.168.101.1"},
+		"netaddress mismatch":   {CIDR: "192.168.100.1/24", NetAddress: "192.168.100.2"},
+		"netmask mismatch":      {CIDR: "192.168.100.0/24", NetMask: "255.255.0.0"},
+		"ipv6 netmask":          {CIDR: "fd00::/64", NetMask: "255.255.255.0"},
+		"broadcast gateway":     {CIDR: "192.168.100.255/24"},
+		"dhcp outside":          {CIDR: "192.168.100.0/24", DHCP: &DHCP{Start: "192.168.100.10", End: "192.168.200.10"}},
+		"ip block without cidr": {CIDR: "192.168.100.0/24", IPs: []IPBlock{{}}},
+		"two ipv4 addresses":    {CIDR: "192.168.100.0/24", IPs: []IPBlock{{CIDR: "192.168.200.0/24"}}},
+		"two ipv6 blocks":       {IPs: []IPBlock{{CIDR: "fd00:1::/64"}, {CIDR: "fd00:2::/64"}}},
+		"host outside":          {IPs: []IPBlock{{CIDR: "fd00:1::/64", Hosts: []Host{{Name: "web", IP: "fd00:2::5"}}}}},
+		"ipv6 host by mac":      {IPs: []IPBlock{{CIDR: "fd00:1::/64", Hosts: []Host{{MAC: "02:aa:bb:00:00:05", IP: "fd00:1::5"}}}}},
+		"ipv4 host by id":       {IPs: []IPBlock{{CIDR: "10.0.0.0/24", Hosts: []Host{{ID: "0:1:0:1:2c", IP: "10.0.0.5"}}}}},
+		"anonymous host":        {IPs: []IPBlock{{CIDR: "10.0.0.0/24", Hosts: []Host{{IP: "10.0.0.5"}}}}},
+	}
+	for name, config := range invalid {
+		if err := config.ResolveAddress(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestResolveAddressDualStack(t *testing.T) {
+	config := Config{
+		CIDR: "192.168.100.0/24",
+		IPs: []IPBlock{{
+			CIDR:  "fd00:100::/64",
+			Hosts: []Host{{Name: "web-01", IP: "fd00:100::10"}, {ID: "0:3:0:1:52:54:0:aa:bb:cc", IP: "fd00:100::11"}},
+		}},
+	}
+	if err := config.ResolveAddress(); err != nil {
+		t.Fatal(err)
+	}
+	want := IPBlock{
+		CIDR:    "fd00:100::/64",
+		Hosts:   config.IPs[0].Hosts,
+		Address: "fd00:100::1",
+		Prefix:  64,
+		DHCP:    &DHCP{Start: "fd00:100::1:0", End: "fd00:100::1:ffff"},
+	}
+	if !reflect.DeepEqual(config.IPs[0], want) {
+		t.Errorf("got %+v (dhcp %+v), want %+v", config.IPs[0], config.IPs[0].DHCP, want)
+	}
+	if config.NetAddress != "192.168.100.1" {
+		t.Errorf("primary address = %s", config.NetAddress)
+	}
+
+	got, err := (&LibvirtNetworkManager{}).prepareNetwork(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<ip address="192.168.100.1" netmask="255.255.255.0">`,
+		`<ip family="ipv6" address="fd00:100::1" prefix="64">`,
+		`<range start="fd00:100::1:0" end="fd00:100::1:ffff">`,
+		`<host name="web-01" ip="fd00:100::10">`,
+		`<host id="0:3:0:1:52:54:0:aa:bb:cc" ip="fd00:100::11">`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("XML lacks %s:\n%s", s, got)
+		}
+	}
+	if i, j := strings.Index(got, "192.168.100.1"), strings.Index(got, "fd00:100::1"); i > j {
+		t.Errorf("IPv4 address is not first:\n%s", got)
+	}
+}
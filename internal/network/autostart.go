@@ -0,0 +1,33 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// SetAutostart sets whether a network starts along with the host, in
+// libvirt and in its record.
+func (m *LibvirtNetworkManager) SetAutostart(ctx context.Context, name string, on bool) error {
+	nw, err := m.conn.NetworkLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup network %q: %w", name, err)
+	}
+	if err := setNetworkAutostart(m.conn, nw, on); err != nil {
+		return err
+	}
+	return db.SetNetworkAutostart(ctx, m.db, name, on)
+}
+
+func setNetworkAutostart(conn *libvirt.Libvirt, nw libvirt.Network, on bool) error {
+	var autostart int32
+	if on {
+		autostart = 1
+	}
+	if err := conn.NetworkSetAutostart(nw, autostart); err != nil {
+		return fmt.Errorf("set autostart of network %q: %w", nw.Name, err)
+	}
+	return nil
+}
@@ -7,13 +7,23 @@ package network
 type Config struct {
 	Name       string `hcl:"name,label"`
 	Namespace  string `hcl:"namespace"`
-	CIDR       string `hcl:"cidr,optional"`
-	NetAddress string `hcl:"netaddress,optional"`
-	NetMask    string `hcl:"netmask,optional"`
+	CIDR       string `hcl:"cidr,optional"`       // subnet, e.g. 192.168.100.0/24; sets netaddress and netmask
+	NetAddress string `hcl:"netaddress,optional"` // address of the host (gateway) on the network
+	NetMask    string `hcl:"netmask,optional"`    // IPv4 only
 	Bridge     string `hcl:"bridge,optional"`
-	Mode       string `hcl:"mode,optional"`
+	Mode       string `hcl:"mode,optional"` // forward mode, nat by default
+	// Dev is the host interface traffic is forwarded through (nat, route),
+	// or the SR-IOV physical function of a hostdev network.
+	Dev        string   `hcl:"dev,optional"`
+	Interfaces []string `hcl:"interfaces,optional"` // macvtap, passthrough: pool of host interfaces
+	NAT        *NAT     `hcl:"nat,block"`
+	MACPrefix  string   `hcl:"mac_prefix,optional"` // OUI of the MAC addresses given to VMs; 02:aa:bb by default
+
+	Prefix int // prefix length, derived from CIDR
 
 	DHCP      *DHCP             `hcl:"dhcp,block"`
+	IPs       []IPBlock         `hcl:"ip,block"` // further addresses, e.g. the IPv6 side of a dual-stack network
+	DNS       *DNS              `hcl:"dns,block"`
 	Autostart bool              `hcl:"autostart,optional"`
 	Labels    map[string]string `hcl:"labels,optional"`
 }
@@ -24,3 +34,64 @@ type DHCP struct {
 	Start string `hcl:"start"`
 	End   string `hcl:"end"`
 }
+
+// IPBlock describes an ip block inside a network: one more subnet, with its
+// own DHCP range and static host entries.
+type IPBlock struct {
+	CIDR  string `hcl:"cidr"` // subnet, e.g. fd00:100::/64; the gateway is derived as for the network's cidr
+	DHCP  *DHCP  `hcl:"dhcp,block"`
+	Hosts []Host `hcl:"host,block"`
+
+	Address string // derived from CIDR
+	NetMask string // derived from CIDR, IPv4 only
+	Prefix  int    // derived from CIDR
+}
+
+// Host describes a static DHCP host entry of an ip block. IPv4 entries are
+// matched on mac, IPv6 entries on id (the DHCP unique identifier of the
+// client) or name.
+type Host struct {
+	MAC  string `hcl:"mac,optional"`
+	ID   string `hcl:"id,optional"`
+	Name string `hcl:"name,optional"`
+	IP   string `hcl:"ip"`
+}
+
+// DNS describes the dns block inside a network. VMs with a static address
+// are added to it as <vm>.<domain>.
+type DNS struct {
+	Domain     string    `hcl:"domain,optional"`     // answered locally, never forwarded
+	Forwarders []string  `hcl:"forwarders,optional"` // upstream servers, by address
+	Hosts      []DNSHost `hcl:"host,block"`
+	SRV        []SRV     `hcl:"srv,block"`
+	TXT        []TXT     `hcl:"txt,block"`
+}
+
+// DNSHost describes a host block inside dns.
+type DNSHost struct {
+	Name string `hcl:"name,label"`
+	IP   string `hcl:"ip"`
+}
+
+// SRV describes an srv block inside dns.
+type SRV struct {
+	Service  string `hcl:"service"`
+	Protocol string `hcl:"protocol"` // tcp or udp
+	Domain   string `hcl:"domain,optional"`
+	Target   string `hcl:"target,optional"`
+	Port     int    `hcl:"port,optional"`
+	Priority int    `hcl:"priority,optional"`
+	Weight   int    `hcl:"weight,optional"`
+}
+
+// TXT describes a txt block inside dns.
+type TXT struct {
+	Name  string `hcl:"name,label"`
+	Value string `hcl:"value"`
+}
+
+// NAT describes the nat block inside a network in nat mode.
+type NAT struct {
+	PortRange    string `hcl:"port_range,optional"`    // e.g. "1024-65535"
+	AddressRange string `hcl:"address_range,optional"` // e.g. "203.0.113.10-203.0.113.20"
+}
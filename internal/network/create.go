@@ -32,7 +32,7 @@ func (m *LibvirtNetworkManager) Create(ctx context.Context, spec Config) error {
 	}
 
 	// Define and start the network in libvirt
-	if err := m.defineAndStartNetwork(xmlConfig); err != nil {
+	if err := m.defineAndStartNetwork(xmlConfig, spec.Autostart); err != nil {
 		return fmt.Errorf("failed to define/start network %q: %w", name, err)
 	}
 
@@ -39,6 +39,12 @@ func (m *LibvirtNetworkManager) Delete(ctx context.Context, name string) error {
 	// We can construct a dummy one with just the name to call Delete?
 	// Or better, network_record.go probably has a Delete method that uses Name/Namespace.
 	// Let's create a partial record.
+	// The addresses IPAM handed out on the network go with it.
+	if id, err := db.GetNetworkIDByName(ctx, m.db, name); err == nil {
+		if err := db.DeleteIPAllocationsByNetwork(ctx, m.db, id); err != nil {
+			return err
+		}
+	}
 	record := &db.VirtualNetwork{Name: name}
 	if err := record.Delete(ctx, m.db); err != nil {
 		return fmt.Errorf("failed to delete database record for network %q: %w", name, err)
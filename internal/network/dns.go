@@ -0,0 +1,189 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	templates "github.com/kebairia/kvmcli/internal/templates"
+)
+
+// ValidateDNS checks the dns block of the network, if any.
+func (c *Config) ValidateDNS() error {
+	if c.DNS == nil {
+		return nil
+	}
+	if err := c.validateDNS(); err != nil {
+		return fmt.Errorf("network %q: dns: %w", c.Name, err)
+	}
+	return nil
+}
+
+func (c *Config) validateDNS() error {
+	d := c.DNS
+	if !c.HasAddressing() {
+		return fmt.Errorf("mode %q has no DNS server of its own", c.Mode)
+	}
+	if d.Domain != "" && !validHostname(d.Domain) {
+		return fmt.Errorf("invalid domain %q", d.Domain)
+	}
+	for _, addr := range d.Forwarders {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid forwarder address %q", addr)
+		}
+	}
+	for _, h := range d.Hosts {
+		if !validHostname(h.Name) {
+			return fmt.Errorf("invalid host name %q", h.Name)
+		}
+		if net.ParseIP(h.IP) == nil {
+			return fmt.Errorf("host %q: invalid address %q", h.Name, h.IP)
+		}
+	}
+	for _, srv := range d.SRV {
+		switch {
+		case srv.Service == "":
+			return fmt.Errorf("an srv record needs a service")
+		case srv.Protocol != "tcp" && srv.Protocol != "udp":
+			return fmt.Errorf("srv %q: protocol must be tcp or udp", srv.Service)
+		case srv.Port < 0 || srv.Port > 65535:
+			return fmt.Errorf("srv %q: invalid port %d", srv.Service, srv.Port)
+		case srv.Priority < 0 || srv.Priority > 65535 || srv.Weight < 0 || srv.Weight > 65535:
+			return fmt.Errorf("srv %q: priority and weight must be within 0-65535", srv.Service)
+		}
+	}
+	for _, txt := range d.TXT {
+		if txt.Name == "" || txt.Value == "" {
+			return fmt.Errorf("a txt record needs a name and a value")
+		}
+	}
+	return nil
+}
+
+// dnsOption returns the template option describing the DNS of the network.
+func (d *DNS) dnsOption() templates.NetworkOption {
+	var dns templates.DNS
+	for _, addr := range d.Forwarders {
+		dns.Forwarders = append(dns.Forwarders, templates.DNSForwarder{Addr: addr})
+	}
+	for _, txt := range d.TXT {
+		dns.TXT = append(dns.TXT, templates.DNSTXT{Name: txt.Name, Value: txt.Value})
+	}
+	for _, h := range d.Hosts {
+		dns.Hosts = append(dns.Hosts, templates.DNSHost{IP: h.IP, Hostnames: []string{h.Name}})
+	}
+	for _, srv := range d.SRV {
+		dns.SRV = append(dns.SRV, templates.DNSSRV{
+			Service:  srv.Service,
+			Protocol: srv.Protocol,
+			Domain:   srv.Domain,
+			Target:   srv.Target,
+			Port:     srv.Port,
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+		})
+	}
+	return templates.WithDNS(d.Domain, dns)
+}
+
+// validHostname reports whether name is a DNS name made of letters,
+// digits and hyphens.
+func validHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// dnsName returns the name a VM is reachable by: <host>.<domain>, or the
+// bare host name on a network without a domain.
+func dnsName(host, domain string) string {
+	if domain == "" {
+		return host
+	}
+	return host + "." + domain
+}
+
+// dnsHostXML returns the DNS host record of a VM.
+func dnsHostXML(host, domain, ip string) (string, error) {
+	out, err := xml.Marshal(templates.DNSHost{IP: ip, Hostnames: []string{dnsName(host, domain)}})
+	if err != nil {
+		return "", fmt.Errorf("build DNS host XML: %w", err)
+	}
+	return string(out), nil
+}
+
+// setDNSHost adds the DNS host record of a VM, replacing a previous one for
+// the same address.
+func (m *LibvirtNetworkManager) setDNSHost(nw libvirt.Network, def networkXML, host, ip string) error {
+	entry, err := dnsHostXML(host, def.Domain.Name, ip)
+	if err != nil {
+		return err
+	}
+	flags := libvirt.NetworkUpdateAffectLive | libvirt.NetworkUpdateAffectConfig
+	if previous := def.dnsHost(ip); previous != nil {
+		if selector, err := xml.Marshal(previous); err == nil {
+			_ = m.updateDNSHost(nw, uint32(libvirt.NetworkUpdateCommandDelete), string(selector), flags)
+		}
+	}
+	if err := m.updateDNSHost(nw, uint32(libvirt.NetworkUpdateCommandAddLast), entry, flags); err != nil {
+		return fmt.Errorf("add DNS host %s (%s) on network %q: %w", host, ip, nw.Name, err)
+	}
+	return nil
+}
+
+// RemoveDNSHost removes the DNS host record a VM got for ip from the
+// network, if any. Records of other hosts on the same address are kept.
+func (m *LibvirtNetworkManager) RemoveDNSHost(ctx context.Context, networkName, host, ip string) error {
+	nw, err := m.conn.NetworkLookupByName(networkName)
+	if err != nil {
+		return fmt.Errorf("lookup network %q: %w", networkName, err)
+	}
+	def, err := m.definition(nw)
+	if err != nil {
+		return err
+	}
+	record := def.dnsHost(ip)
+	if record == nil || !slices.Contains(record.Hostnames, dnsName(host, def.Domain.Name)) {
+		return nil
+	}
+	selector, err := xml.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("build DNS host XML: %w", err)
+	}
+	flags := libvirt.NetworkUpdateAffectLive | libvirt.NetworkUpdateAffectConfig
+	if err := m.updateDNSHost(nw, uint32(libvirt.NetworkUpdateCommandDelete), string(selector), flags); err != nil {
+		return fmt.Errorf("remove DNS host %s from network %q: %w", ip, networkName, err)
+	}
+	return nil
+}
+
+func (m *LibvirtNetworkManager) updateDNSHost(
+	nw libvirt.Network,
+	command uint32,
+	xml string,
+	flags libvirt.NetworkUpdateFlags,
+) error {
+	return m.conn.NetworkUpdate(
+		nw,
+		command,
+		uint32(libvirt.NetworkSectionDNSHost),
+		-1,
+		xml,
+		flags,
+	)
+}
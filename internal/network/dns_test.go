@@ -0,0 +1,80 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDNS(t *testing.T) {
+	valid := &DNS{
+		Domain:     "lab.local",
+		Forwarders: []string{"1.1.1.1", "2606:4700:4700::1111"},
+		Hosts:      []DNSHost{{Name: "gateway", IP: "192.168.100.1"}},
+		SRV:        []SRV{{Service: "ldap", Protocol: "tcp", Target: "dc.lab.local", Port: 389}},
+		TXT:        []TXT{{Name: "lab", Value: "managed by kvmcli"}},
+	}
+	invalid := map[string]*DNS{
+		"domain":    {Domain: "lab_local"},
+		"forwarder": {Forwarders: []string{"dns.example.com"}},
+		"host name": {Hosts: []DNSHost{{Name: "-web", IP: "192.168.100.5"}}},
+		"host ip":   {Hosts: []DNSHost{{Name: "web", IP: "192.168.100"}}},
+		"srv proto": {SRV: []SRV{{Service: "ldap", Protocol: "sctp"}}},
+		"srv port":  {SRV: []SRV{{Service: "ldap", Protocol: "tcp", Port: 70000}}},
+		"txt":       {TXT: []TXT{{Name: "lab"}}},
+	}
+
+	cfg := Config{Name: "lab", Mode: ModeNAT, NetAddress: "192.168.100.1", DNS: valid}
+	if err := cfg.ValidateDNS(); err != nil {
+		t.Errorf("ValidateDNS() error = %v", err)
+	}
+	for name, dns := range invalid {
+		cfg := Config{Name: "lab", Mode: ModeNAT, NetAddress: "192.168.100.1", DNS: dns}
+		if err := cfg.ValidateDNS(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	bridged := Config{Name: "lan", Mode: ModeBridge, Bridge: "br0", DNS: &DNS{Domain: "lan"}}
+	if err := bridged.ValidateDNS(); err == nil {
+		t.Error("expected an error for dns on a host bridge")
+	}
+}
+
+func TestPrepareNetworkDNS(t *testing.T) {
+	cfg := Config{
+		Name:       "lab",
+		Mode:       ModeNAT,
+		NetAddress: "192.168.100.1",
+		NetMask:    "255.255.255.0",
+		DNS: &DNS{
+			Domain:     "lab.local",
+			Forwarders: []string{"1.1.1.1"},
+			Hosts:      []DNSHost{{Name: "gateway", IP: "192.168.100.1"}},
+			SRV:        []SRV{{Service: "ldap", Protocol: "tcp", Port: 389}},
+			TXT:        []TXT{{Name: "lab", Value: "v1"}},
+		},
+	}
+	got, err := (&LibvirtNetworkManager{}).prepareNetwork(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<domain name="lab.local" localOnly="yes">`,
+		`<forwarder addr="1.1.1.1">`,
+		`<txt name="lab" value="v1">`,
+		`<host ip="192.168.100.1">`,
+		`<hostname>gateway</hostname>`,
+		`<srv service="ldap" protocol="tcp" port="389">`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("XML lacks %s:\n%s", s, got)
+		}
+	}
+
+	entry, err := dnsHostXML("web-01", "lab.local", "192.168.100.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<host ip="192.168.100.10"><hostname>web-01.lab.local</hostname></host>`; entry != want {
+		t.Errorf("dnsHostXML() = %s, want %s", entry, want)
+	}
+}
@@ -0,0 +1,181 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	templates "github.com/kebairia/kvmcli/internal/templates"
+)
+
+// Forward modes of a network.
+const (
+	ModeNAT         = "nat"         // NAT to the outside through the host (default)
+	ModeRoute       = "route"       // routed to the outside without NAT
+	ModeOpen        = "open"        // routed, without any firewall rules from libvirt
+	ModeIsolated    = "isolated"    // guests and host only, no forward
+	ModeBridge      = "bridge"      // an existing host bridge, managed outside libvirt
+	ModeMacvtap     = "macvtap"     // macvtap on a pool of host interfaces
+	ModePassthrough = "passthrough" // a host interface of the pool per guest
+	ModeHostdev     = "hostdev"     // SR-IOV virtual functions of a physical function
+)
+
+var modes = []string{
+	ModeNAT, ModeRoute, ModeOpen, ModeIsolated,
+	ModeBridge, ModeMacvtap, ModePassthrough, ModeHostdev,
+}
+
+// HasAddressing reports whether libvirt addresses the network itself, with
+// an <ip> element, DHCP and static mappings. The other modes plug guests
+// straight into the host's network.
+func (c *Config) HasAddressing() bool {
+	return hasAddressing(c.Mode)
+}
+
+func hasAddressing(mode string) bool {
+	switch mode {
+	case ModeBridge, ModeMacvtap, ModePassthrough, ModeHostdev:
+		return false
+	}
+	return true
+}
+
+// ValidateMode defaults the forward mode of the network to nat and checks
+// that the attributes it was given fit its mode.
+func (c *Config) ValidateMode() error {
+	if c.Mode == "" {
+		c.Mode = ModeNAT
+	}
+	if err := c.validateMode(); err != nil {
+		return fmt.Errorf("network %q: %w", c.Name, err)
+	}
+	return nil
+}
+
+func (c *Config) validateMode() error {
+	if !slices.Contains(modes, c.Mode) {
+		return fmt.Errorf("unknown mode %q (want one of %s)", c.Mode, strings.Join(modes, ", "))
+	}
+	if c.NAT != nil {
+		if c.Mode != ModeNAT {
+			return fmt.Errorf("a nat block needs mode %q", ModeNAT)
+		}
+		if _, _, err := parsePortRange(c.NAT.PortRange); err != nil {
+			return err
+		}
+		if _, _, err := parseAddressRange(c.NAT.AddressRange); err != nil {
+			return err
+		}
+	}
+	if len(c.Interfaces) > 0 && c.Mode != ModeMacvtap && c.Mode != ModePassthrough {
+		return fmt.Errorf("interfaces need mode %q or %q", ModeMacvtap, ModePassthrough)
+	}
+
+	if c.HasAddressing() {
+		switch {
+		case c.Dev != "" && c.Mode != ModeNAT && c.Mode != ModeRoute:
+			return fmt.Errorf("dev needs mode %q or %q", ModeNAT, ModeRoute)
+		case c.NetAddress == "" && len(c.IPs) == 0 && (c.Mode == ModeNAT || c.Mode == ModeRoute):
+			return fmt.Errorf("mode %q needs a cidr, netaddress or ip block", c.Mode)
+		case c.DHCP != nil && c.NetAddress == "":
+			return fmt.Errorf("a dhcp block needs a cidr or netaddress")
+		}
+		return nil
+	}
+
+	// The host's network addresses the guests.
+	if c.CIDR != "" || c.NetAddress != "" || c.NetMask != "" || c.DHCP != nil || len(c.IPs) > 0 {
+		return fmt.Errorf("mode %q takes no cidr, netaddress, netmask, dhcp or ip block", c.Mode)
+	}
+	switch c.Mode {
+	case ModeBridge:
+		if c.Bridge == "" {
+			return fmt.Errorf("mode %q needs the name of an existing host bridge", c.Mode)
+		}
+		if c.Dev != "" {
+			return fmt.Errorf("mode %q takes no dev", c.Mode)
+		}
+	case ModeMacvtap, ModePassthrough:
+		if len(c.Interfaces) == 0 {
+			return fmt.Errorf("mode %q needs at least one host interface", c.Mode)
+		}
+		if c.Bridge != "" || c.Dev != "" {
+			return fmt.Errorf("mode %q takes no bridge or dev", c.Mode)
+		}
+	case ModeHostdev:
+		if c.Dev == "" {
+			return fmt.Errorf("mode %q needs the SR-IOV physical function as dev", c.Mode)
+		}
+		if c.Bridge != "" {
+			return fmt.Errorf("mode %q takes no bridge", c.Mode)
+		}
+	}
+	return nil
+}
+
+// forwardOptions returns the template options describing the forward of
+// the network, along with the libvirt forward mode; "" for an isolated
+// network.
+func (c *Config) forwardOptions() (string, []templates.NetworkOption) {
+	var opts []templates.NetworkOption
+	switch c.Mode {
+	case ModeIsolated:
+		return "", nil
+	case ModeNAT, ModeRoute:
+		if c.Dev != "" {
+			opts = append(opts, templates.WithForwardDev(c.Dev))
+		}
+		if c.NAT != nil {
+			portStart, portEnd, _ := parsePortRange(c.NAT.PortRange)
+			addrStart, addrEnd, _ := parseAddressRange(c.NAT.AddressRange)
+			opts = append(opts, templates.WithNAT(addrStart, addrEnd, portStart, portEnd))
+		}
+	case ModeMacvtap:
+		// libvirt names macvtap in bridge mode "bridge" as well; the
+		// interface pool tells it apart from a host bridge.
+		return ModeBridge, []templates.NetworkOption{templates.WithForwardInterfaces(c.Interfaces...)}
+	case ModePassthrough:
+		opts = append(opts, templates.WithForwardInterfaces(c.Interfaces...))
+	case ModeHostdev:
+		opts = append(opts, templates.WithHostdevPF(c.Dev))
+	}
+	return c.Mode, opts
+}
+
+// parsePortRange splits a "start-end" port range; "" is no range.
+func parsePortRange(r string) (string, string, error) {
+	if r == "" {
+		return "", "", nil
+	}
+	start, end, ok := strings.Cut(r, "-")
+	if !ok {
+		return "", "", fmt.Errorf("invalid port_range %q (want start-end)", r)
+	}
+	lo, err1 := strconv.Atoi(strings.TrimSpace(start))
+	hi, err2 := strconv.Atoi(strings.TrimSpace(end))
+	if err1 != nil || err2 != nil || lo < 1 || hi > 65535 || lo > hi {
+		return "", "", fmt.Errorf("invalid port_range %q (want start-end within 1-65535)", r)
+	}
+	return strconv.Itoa(lo), strconv.Itoa(hi), nil
+}
+
+// parseAddressRange splits a "start-end" IPv4 range, or a single address;
+// "" is no range.
+func parseAddressRange(r string) (string, string, error) {
+	if r == "" {
+		return "", "", nil
+	}
+	start, end, ok := strings.Cut(r, "-")
+	if !ok {
+		end = start
+	}
+	lo := net.ParseIP(strings.TrimSpace(start)).To4()
+	hi := net.ParseIP(strings.TrimSpace(end)).To4()
+	if lo == nil || hi == nil || bytes.Compare(lo, hi) > 0 {
+		return "", "", fmt.Errorf("invalid address_range %q (want start-end IPv4 addresses)", r)
+	}
+	return lo.String(), hi.String(), nil
+}
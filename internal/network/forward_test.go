@@ -0,0 +1,106 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default nat", cfg: Config{NetAddress: "10.0.0.1"}},
+		{name: "nat without address", cfg: Config{Mode: ModeNAT}, wantErr: true},
+		{name: "nat block", cfg: Config{NetAddress: "10.0.0.1", NAT: &NAT{PortRange: "1024-65535", AddressRange: "203.0.113.10-203.0.113.20"}}},
+		{name: "nat single address", cfg: Config{NetAddress: "10.0.0.1", NAT: &NAT{AddressRange: "203.0.113.10"}}},
+		{name: "bad port range", cfg: Config{NetAddress: "10.0.0.1", NAT: &NAT{PortRange: "0-70000"}}, wantErr: true},
+		{name: "reversed address range", cfg: Config{NetAddress: "10.0.0.1", NAT: &NAT{AddressRange: "203.0.113.20-203.0.113.10"}}, wantErr: true},
+		{name: "nat block on route", cfg: Config{Mode: ModeRoute, NetAddress: "10.0.0.1", NAT: &NAT{}}, wantErr: true},
+		{name: "route with dev", cfg: Config{Mode: ModeRoute, NetAddress: "10.0.0.1", Dev: "eth1"}},
+		{name: "open", cfg: Config{Mode: ModeOpen, NetAddress: "10.0.0.1"}},
+		{name: "open with dev", cfg: Config{Mode: ModeOpen, NetAddress: "10.0.0.1", Dev: "eth1"}, wantErr: true},
+		{name: "isolated without address", cfg: Config{Mode: ModeIsolated}},
+		{name: "dhcp without address", cfg: Config{Mode: ModeIsolated, DHCP: &DHCP{}}, wantErr: true},
+		{name: "host bridge", cfg: Config{Mode: ModeBridge, Bridge: "br0"}},
+		{name: "host bridge without name", cfg: Config{Mode: ModeBridge}, wantErr: true},
+		{name: "host bridge with cidr", cfg: Config{Mode: ModeBridge, Bridge: "br0", CIDR: "10.0.0.0/24"}, wantErr: true},
+		{name: "macvtap", cfg: Config{Mode: ModeMacvtap, Interfaces: []string{"eth0", "eth1"}}},
+		{name: "macvtap without pool", cfg: Config{Mode: ModeMacvtap}, wantErr: true},
+		{name: "passthrough with bridge", cfg: Config{Mode: ModePassthrough, Interfaces: []string{"eth0"}, Bridge: "br0"}, wantErr: true},
+		{name: "interfaces on nat", cfg: Config{NetAddress: "10.0.0.1", Interfaces: []string{"eth0"}}, wantErr: true},
+		{name: "hostdev", cfg: Config{Mode: ModeHostdev, Dev: "enp3s0f0"}},
+		{name: "hostdev without pf", cfg: Config{Mode: ModeHostdev}, wantErr: true},
+		{name: "unknown", cfg: Config{Mode: "vepa"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.ValidateMode()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareNetworkForward(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "nat",
+			cfg: Config{NetAddress: "10.0.0.1", NetMask: "255.255.255.0", Dev: "eth0",
+				NAT: &NAT{PortRange: "1024-65535", AddressRange: "203.0.113.10-203.0.113.20"}},
+			want: []string{`<forward mode="nat" dev="eth0">`, `<address start="203.0.113.10" end="203.0.113.20">`, `<port start="1024" end="65535">`},
+		},
+		{
+			name:    "isolated",
+			cfg:     Config{Mode: ModeIsolated, NetAddress: "10.0.0.1", NetMask: "255.255.255.0"},
+			want:    []string{`<ip address="10.0.0.1"`},
+			notWant: []string{"<forward"},
+		},
+		{
+			name:    "host bridge",
+			cfg:     Config{Mode: ModeBridge, Bridge: "br0"},
+			want:    []string{`<bridge name="br0">`, `<forward mode="bridge">`},
+			notWant: []string{"<ip"},
+		},
+		{
+			name:    "macvtap",
+			cfg:     Config{Mode: ModeMacvtap, Interfaces: []string{"eth0", "eth1"}},
+			want:    []string{`<forward mode="bridge">`, `<interface dev="eth0">`, `<interface dev="eth1">`},
+			notWant: []string{"<bridge", "<ip"},
+		},
+		{
+			name: "hostdev",
+			cfg:  Config{Mode: ModeHostdev, Dev: "enp3s0f0"},
+			want: []string{`<forward mode="hostdev" managed="yes">`, `<pf dev="enp3s0f0">`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name = "test"
+			if err := tt.cfg.ValidateMode(); err != nil {
+				t.Fatalf("ValidateMode() error = %v", err)
+			}
+			got, err := (&LibvirtNetworkManager{}).prepareNetwork(tt.cfg)
+			if err != nil {
+				t.Fatalf("prepareNetwork() error = %v", err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("XML lacks %s:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("XML has %s:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
@@ -27,23 +27,25 @@ type VirtualNetworkInfo struct {
 	Subnet    string
 	Gateway   string
 	DHCPRange string
+	Autostart string
 	Age       string
 }
 
 func (info *VirtualNetworkInfo) Header() *tabwriter.Writer {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tSTATE\tBRIDGE\tSUBNET\tGATEWAY\tDHCP RANGE\tAGE")
+	fmt.Fprintln(w, "NAME\tSTATE\tBRIDGE\tSUBNET\tGATEWAY\tDHCP RANGE\tAUTOSTART\tAGE")
 	return w
 }
 
 func (info *VirtualNetworkInfo) PrintInfo(w *tabwriter.Writer) {
-	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 		info.Name,
 		info.State,
 		info.Bridge,
 		info.Subnet,
 		info.Gateway,
 		info.DHCPRange,
+		info.Autostart,
 		info.Age,
 	)
 }
@@ -68,19 +70,34 @@ func NewVirtualNetworkInfo(
 		state = networkStateUnknown
 	}
 
+	// Autostart, as libvirt has it
+	autostart := networkStateUnknown
+	if on, err := conn.NetworkGetAutostart(net); err != nil {
+		log.Errorf("cannot get autostart for %q: %v", record.Name, err)
+	} else {
+		autostart = common.OnOff(on == 1)
+	}
+
 	// Format the DHCP range.
 	var dhcpRange string
 	if record.DHCP != nil {
 		dhcpRange = record.DHCP["start"] + " → " + record.DHCP["end"]
 	}
 
+	// An IPv6 network has a prefix and no netmask.
+	subnet := record.Netmask
+	if subnet == "" && record.Prefix > 0 {
+		subnet = fmt.Sprintf("/%d", record.Prefix)
+	}
+
 	return &VirtualNetworkInfo{
 		Name:      record.Name,
 		State:     state,
 		Bridge:    record.Bridge,
-		Subnet:    record.Netmask,
+		Subnet:    subnet,
 		Gateway:   record.NetAddress,
 		DHCPRange: dhcpRange,
+		Autostart: autostart,
 		Age:       common.FormatAge(record.CreatedAt),
 	}, nil
 }
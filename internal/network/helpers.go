@@ -9,49 +9,72 @@ import (
 	templates "github.com/kebairia/kvmcli/internal/templates"
 )
 
-// prepareNetwork generates the libvirt-compatible XML configuration
-// for a virtual network, using optional parameters like DHCP and bridge.
 // prepareNetwork generates the libvirt-compatible XML configuration
 // for a virtual network, using optional parameters like DHCP and bridge.
 func (m *LibvirtNetworkManager) prepareNetwork(spec Config) (string, error) {
+	return renderNetwork(spec.template())
+}
+
+// renderNetwork returns the XML document of a network definition.
+func renderNetwork(netXML *templates.Network) (string, error) {
+	xmlConfig, err := netXML.GenerateXML()
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to generate XML for network %s: %v",
+			netXML.Name,
+			err,
+		)
+	}
+
+	return xml.Header + string(xmlConfig), nil
+}
+
+// template returns the libvirt definition of the network.
+func (c *Config) template() *templates.Network {
 	var opts []templates.NetworkOption
 
 	// Append DHCP config if defined in the YAML
-	// Append DHCP config if defined in the YAML
-	if spec.DHCP != nil {
-		opts = append(opts, templates.WithDHCP(spec.DHCP.Start, spec.DHCP.End))
+	if c.DHCP != nil {
+		opts = append(opts, templates.WithDHCP(c.DHCP.Start, c.DHCP.End))
+	}
+
+	if c.IsIPv6() {
+		opts = append(opts, templates.WithIPv6(c.Prefix))
 	}
 
 	// Append bridge name if provided
-	if spec.Bridge != "" {
-		opts = append(opts, templates.WithBridge(spec.Bridge))
+	if c.Bridge != "" {
+		opts = append(opts, templates.WithBridge(c.Bridge))
 	}
 
-	// Create the network definition with all options
-	netXML := templates.NewNetwork(
-		spec.Name,
-		spec.Mode,
-		spec.NetAddress,
-		spec.NetMask,
-		spec.Autostart,
-		opts...,
-	)
+	// Describe how the network reaches the outside, if at all
+	mode, forward := c.forwardOptions()
+	opts = append(opts, forward...)
 
-	xmlConfig, err := netXML.GenerateXML()
-	if err != nil {
-		return "", fmt.Errorf(
-			"failed to generate XML for network %s: %v",
-			spec.Name,
-			err,
-		)
+	// Append the DNS domain and records
+	if c.DNS != nil {
+		opts = append(opts, c.DNS.dnsOption())
 	}
 
-	return xml.Header + string(xmlConfig), nil
+	// Append the further addresses, after the primary one
+	for _, block := range c.IPs {
+		opts = append(opts, templates.WithIP(block.template()))
+	}
+
+	// Create the network definition with all options
+	return templates.NewNetwork(
+		c.Name,
+		mode,
+		c.NetAddress,
+		c.NetMask,
+		c.Autostart,
+		opts...,
+	)
 }
 
-// defineAndStartNetwork defines and starts the virtual network using libvirt.
-// defineAndStartNetwork defines and starts the virtual network using libvirt.
-func (m *LibvirtNetworkManager) defineAndStartNetwork(xmlConfig string) error {
+// defineAndStartNetwork defines and starts the virtual network using libvirt,
+// and marks it to start along with the host when autostart is set.
+func (m *LibvirtNetworkManager) defineAndStartNetwork(xmlConfig string, autostart bool) error {
 	// Define the network from the generated XML
 	netInstance, err := m.conn.NetworkDefineXML(xmlConfig)
 	if err != nil {
@@ -62,23 +85,13 @@ func (m *LibvirtNetworkManager) defineAndStartNetwork(xmlConfig string) error {
 	if err := m.conn.NetworkCreate(netInstance); err != nil {
 		return fmt.Errorf("failed to start network: %w", err)
 	}
-	// TODO: Autostart handling needs the spec or to be passed in.
-	// For now omitting autostart logic here or assuming it's done elsewhere?
-	// The original code used `net.Spec.Autostart`.
-	// Let's rely on the caller to handle autostart or pass it in.
-	// Actually, create.go logic was: prepare -> defineAndStart.
-	// I should update defineAndStartNetwork to take the autostart flag or name to lookup spec?
-	// Or simpler: just take the flag.
-
-	return nil
-}
 
-func (m *LibvirtNetworkManager) EnableAutostart(name string) error {
-	netInstance, err := m.conn.NetworkLookupByName(name)
-	if err != nil {
-		return err
+	if autostart {
+		if err := setNetworkAutostart(m.conn, netInstance, true); err != nil {
+			return err
+		}
 	}
-	return m.conn.NetworkSetAutostart(netInstance, 1)
+	return nil
 }
 
 func NewNetworkRecord(net *Network) *db.VirtualNetwork {
@@ -91,6 +104,18 @@ func NewNetworkRecord(net *Network) *db.VirtualNetwork {
 		}
 	}
 
+	var ips []db.NetworkIP
+	for _, block := range net.Spec.IPs {
+		ip := db.NetworkIP{Address: block.Address, Netmask: block.NetMask, Prefix: block.Prefix}
+		if block.DHCP != nil {
+			ip.DHCP = map[string]string{"start": block.DHCP.Start, "end": block.DHCP.End}
+		}
+		for _, h := range block.Hosts {
+			ip.Hosts = append(ip.Hosts, h.IP)
+		}
+		ips = append(ips, ip)
+	}
+
 	// Create network record out of infos
 	return &db.VirtualNetwork{
 		Name:      net.Spec.Name,
@@ -101,8 +126,30 @@ func NewNetworkRecord(net *Network) *db.VirtualNetwork {
 		Mode:       net.Spec.Mode,
 		NetAddress: net.Spec.NetAddress,
 		Netmask:    net.Spec.NetMask,
+		Prefix:     net.Spec.Prefix,
 		DHCP:       dhcpMap,
 		Autostart:  net.Spec.Autostart,
+		MACPrefix:  net.Spec.MACPrefix,
+		IPs:        ips,
 		CreatedAt:  time.Now(),
 	}
 }
+
+// template returns the <ip> element of the ip block.
+func (b IPBlock) template() templates.IP {
+	ip := templates.IP{Address: b.Address, Netmask: b.NetMask}
+	if b.IsIPv6() {
+		ip.Family = "ipv6"
+		ip.Prefix = b.Prefix
+	}
+	if b.DHCP != nil || len(b.Hosts) > 0 {
+		ip.DHCP = &templates.DHCP{}
+		if b.DHCP != nil {
+			ip.DHCP.Range = &templates.Range{Start: b.DHCP.Start, End: b.DHCP.End}
+		}
+		for _, h := range b.Hosts {
+			ip.DHCP.Hosts = append(ip.DHCP.Hosts, templates.DHCPHost{MAC: h.MAC, ID: h.ID, Name: h.Name, IP: h.IP})
+		}
+	}
+	return ip
+}
@@ -0,0 +1,382 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// AutoIP, as the ip of a VM, asks IPAM to pick its static address.
+const AutoIP = "auto"
+
+// ErrNoSubnet is returned when a network is recorded without the subnet
+// IPAM allocates from.
+var ErrNoSubnet = errors.New("network has no subnet to allocate from; set its cidr")
+
+// IPAM hands out the static addresses of a network: addresses of its subnet
+// outside the DHCP range, reserved in the ip_allocations table so that no
+// two VMs claim the same one.
+type IPAM struct {
+	conn *libvirt.Libvirt
+	db   *sql.DB
+}
+
+// NewIPAM returns an IPAM working on the given connections.
+func NewIPAM(conn *libvirt.Libvirt, database *sql.DB) *IPAM {
+	return &IPAM{conn: conn, db: database}
+}
+
+// pool is the address space of a network as IPAM sees it.
+type pool struct {
+	name      string
+	id        int
+	mode      string
+	addressed bool       // false when the host's network addresses the guests
+	subnet    *net.IPNet // nil for networks recorded without a netmask
+	gateway   net.IP
+	dhcpStart net.IP
+	dhcpEnd   net.IP
+}
+
+// owner is what holds an address: a VM, a MAC address, or both.
+type owner struct {
+	vm  string
+	mac string
+}
+
+func (o owner) String() string {
+	switch {
+	case o.vm != "":
+		return "vm " + o.vm
+	case o.mac != "":
+		return "mac " + o.mac
+	}
+	return "the network"
+}
+
+// Allocate reserves the first free static address of the network for the
+// VM and returns it. A VM that already holds an address keeps it; inserted
+// reports whether the address was reserved by this call, and with it
+// whether the caller owns the reservation to release on failure.
+func (p *IPAM) Allocate(ctx context.Context, networkName, vmName string) (ip string, inserted bool, err error) {
+	pl, err := p.pool(ctx, networkName)
+	if err != nil {
+		return "", false, err
+	}
+	if pl.subnet == nil {
+		return "", false, fmt.Errorf("network %q: %w", networkName, ErrNoSubnet)
+	}
+	if held, err := p.held(ctx, pl.id, vmName); err != nil || held != "" {
+		return held, false, err
+	}
+	taken, err := p.taken(ctx, pl)
+	if err != nil {
+		return "", false, err
+	}
+
+	first, last := subnetBounds(pl.subnet)
+	for addr := addToIP(first, 1); bytes.Compare(addr, last) <= 0; addr = addToIP(addr, 1) {
+		if pl.reserved(addr) || pl.inDHCPRange(addr) {
+			continue
+		}
+		if _, ok := taken[addr.String()]; ok {
+			continue
+		}
+		alloc := db.IPAllocation{NetworkID: pl.id, IP: addr.String(), VMName: vmName}
+		if err := alloc.Insert(ctx, p.db); err != nil {
+			if errors.Is(err, db.ErrIPTaken) {
+				// Another VM reserved it in the meantime.
+				continue
+			}
+			return "", false, err
+		}
+		return alloc.IP, true, nil
+	}
+	return "", false, fmt.Errorf("no free static address left on network %q outside its DHCP range", networkName)
+}
+
+// Reserve reserves the given address of the network for the VM with the
+// given MAC address. It fails when the address lies outside the subnet or
+// is held by another VM, DHCP host entry or lease. inserted reports whether
+// the address was reserved by this call rather than already held by the VM.
+func (p *IPAM) Reserve(ctx context.Context, networkName, vmName, ip, mac string) (inserted bool, err error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid IP address %q", ip)
+	}
+	pl, err := p.pool(ctx, networkName)
+	if err != nil {
+		return false, err
+	}
+	if !pl.addressed {
+		return false, fmt.Errorf("network %q is in %s mode; its guests are addressed by the host's network", networkName, pl.mode)
+	}
+	if pl.subnet != nil {
+		if !pl.subnet.Contains(addr) {
+			return false, fmt.Errorf("%s is outside network %q (%s)", ip, networkName, pl.subnet)
+		}
+		if pl.reserved(normalize(addr)) {
+			return false, fmt.Errorf("%s is reserved on network %q", ip, networkName)
+		}
+	}
+	held, err := p.held(ctx, pl.id, vmName)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case held == addr.String():
+		return false, nil
+	case held != "":
+		return false, fmt.Errorf("vm %q already holds %s on network %q", vmName, held, networkName)
+	}
+
+	taken, err := p.taken(ctx, pl)
+	if err != nil {
+		return false, err
+	}
+	for _, o := range taken[addr.String()] {
+		if o.vm == vmName || (o.mac != "" && strings.EqualFold(o.mac, mac)) {
+			continue
+		}
+		return false, fmt.Errorf("%s on network %q is already used by %s", ip, networkName, o)
+	}
+
+	alloc := db.IPAllocation{NetworkID: pl.id, IP: addr.String(), VMName: vmName}
+	if err := alloc.Insert(ctx, p.db); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckIPv6 checks that ip is an address of an IPv6 subnet of the network,
+// other than its subnet address and gateway, that no other VM or DHCP host
+// entry holds.
+func (p *IPAM) CheckIPv6(ctx context.Context, networkName, vmName, ip string) error {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.To4() != nil {
+		return fmt.Errorf("invalid IPv6 address %q", ip)
+	}
+	var vnet db.VirtualNetwork
+	if err := vnet.GetRecord(ctx, p.db, networkName); err != nil {
+		return err
+	}
+	subnet, gateway := ipv6Subnet(vnet)
+	if subnet == nil {
+		return fmt.Errorf("network %q has no IPv6 subnet; add an ip block with an IPv6 cidr", networkName)
+	}
+	if !subnet.Contains(addr) {
+		return fmt.Errorf("%s is outside network %q (%s)", ip, networkName, subnet)
+	}
+	if addr.Equal(subnet.IP) || addr.Equal(gateway) {
+		return fmt.Errorf("%s is reserved on network %q", ip, networkName)
+	}
+
+	records, err := db.GetVMRecords(ctx, p.db, "")
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if r.Name != vmName && r.NetworkID == vnet.ID && addr.Equal(net.ParseIP(r.IPv6)) {
+			return fmt.Errorf("%s on network %q is already used by vm %s", ip, networkName, r.Name)
+		}
+	}
+	if p.conn == nil {
+		return nil
+	}
+	nw, err := p.conn.NetworkLookupByName(networkName)
+	if err != nil {
+		return fmt.Errorf("lookup network %q: %w", networkName, err)
+	}
+	hosts, err := dhcpHosts(p.conn, nw)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		if h.Name != vmName && addr.Equal(net.ParseIP(h.IP)) {
+			return fmt.Errorf("%s on network %q is already used by %s", ip, networkName, owner{vm: h.Name, mac: h.MAC})
+		}
+	}
+	return nil
+}
+
+// ipv6Subnet returns the IPv6 subnet of the network and its gateway, or nil.
+func ipv6Subnet(vnet db.VirtualNetwork) (*net.IPNet, net.IP) {
+	addresses := []db.NetworkIP{{Address: vnet.NetAddress, Prefix: vnet.Prefix}}
+	for _, a := range append(addresses, vnet.IPs...) {
+		gateway := net.ParseIP(a.Address)
+		if gateway == nil || gateway.To4() != nil || a.Prefix == 0 {
+			continue
+		}
+		mask := net.CIDRMask(a.Prefix, 8*net.IPv6len)
+		return &net.IPNet{IP: gateway.Mask(mask), Mask: mask}, gateway
+	}
+	return nil, nil
+}
+
+// Release frees the address the VM holds on the network. Releasing on a
+// network kvmcli does not know is not an error.
+func (p *IPAM) Release(ctx context.Context, networkName, vmName string) error {
+	id, err := db.GetNetworkIDByName(ctx, p.db, networkName)
+	if err != nil {
+		return nil
+	}
+	return db.DeleteIPAllocation(ctx, p.db, id, vmName)
+}
+
+// held returns the address the VM holds on the network, or "".
+func (p *IPAM) held(ctx context.Context, networkID int, vmName string) (string, error) {
+	allocations, err := db.GetIPAllocations(ctx, p.db, networkID)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range allocations {
+		if a.VMName == vmName {
+			return a.IP, nil
+		}
+	}
+	return "", nil
+}
+
+// pool loads the address space of the network from its record.
+func (p *IPAM) pool(ctx context.Context, networkName string) (*pool, error) {
+	var vnet db.VirtualNetwork
+	if err := vnet.GetRecord(ctx, p.db, networkName); err != nil {
+		return nil, err
+	}
+	pl := &pool{
+		name:      networkName,
+		id:        vnet.ID,
+		mode:      vnet.Mode,
+		addressed: hasAddressing(vnet.Mode),
+		gateway:   net.ParseIP(vnet.NetAddress),
+	}
+	if pl.gateway == nil {
+		return pl, nil
+	}
+	pl.gateway = normalize(pl.gateway)
+
+	var mask net.IPMask
+	if vnet.Prefix > 0 {
+		mask = net.CIDRMask(vnet.Prefix, len(pl.gateway)*8)
+	} else if m := net.ParseIP(vnet.Netmask).To4(); m != nil && len(pl.gateway) == net.IPv4len {
+		mask = net.IPMask(m)
+	}
+	if mask != nil {
+		pl.subnet = &net.IPNet{IP: pl.gateway.Mask(mask), Mask: mask}
+	}
+	if start, end := net.ParseIP(vnet.DHCP["start"]), net.ParseIP(vnet.DHCP["end"]); start != nil && end != nil {
+		pl.dhcpStart, pl.dhcpEnd = normalize(start), normalize(end)
+	}
+	return pl, nil
+}
+
+// taken maps the addresses in use on the network to what holds them: VM
+// records, allocations, DHCP host entries and DHCP leases.
+func (p *IPAM) taken(ctx context.Context, pl *pool) (map[string][]owner, error) {
+	taken := make(map[string][]owner)
+	add := func(ip string, o owner) {
+		if addr := net.ParseIP(ip); addr != nil {
+			taken[addr.String()] = append(taken[addr.String()], o)
+		}
+	}
+
+	records, err := db.GetVMRecords(ctx, p.db, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.NetworkID == pl.id && r.IP != "" {
+			add(r.IP, owner{vm: r.Name, mac: r.MacAddress})
+		}
+	}
+	allocations, err := db.GetIPAllocations(ctx, p.db, pl.id)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allocations {
+		add(a.IP, owner{vm: a.VMName})
+	}
+
+	if p.conn == nil {
+		return taken, nil
+	}
+	nw, err := p.conn.NetworkLookupByName(pl.name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup network %q: %w", pl.name, err)
+	}
+	hosts, err := dhcpHosts(p.conn, nw)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hosts {
+		add(h.IP, owner{vm: h.Name, mac: h.MAC})
+	}
+	leases, _, err := p.conn.NetworkGetDhcpLeases(nw, nil, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get dhcp leases for network %q: %w", pl.name, err)
+	}
+	for _, lease := range leases {
+		o := owner{}
+		if len(lease.Mac) > 0 {
+			o.mac = lease.Mac[0]
+		}
+		if len(lease.Hostname) > 0 {
+			o.vm = lease.Hostname[0]
+		}
+		add(lease.Ipaddr, o)
+	}
+	return taken, nil
+}
+
+// reserved reports whether ip is the subnet address, the gateway or the
+// IPv4 broadcast address.
+func (pl *pool) reserved(ip net.IP) bool {
+	first, last := subnetBounds(pl.subnet)
+	if ip.Equal(first) || ip.Equal(pl.gateway) {
+		return true
+	}
+	return len(ip) == net.IPv4len && ip.Equal(last)
+}
+
+// inDHCPRange reports whether the DHCP server may hand ip out dynamically.
+func (pl *pool) inDHCPRange(ip net.IP) bool {
+	if pl.dhcpStart == nil || len(ip) != len(pl.dhcpStart) {
+		return false
+	}
+	return bytes.Compare(ip, pl.dhcpStart) >= 0 && bytes.Compare(ip, pl.dhcpEnd) <= 0
+}
+
+// DHCPHost is a static DHCP reservation of a libvirt network.
+type DHCPHost struct {
+	MAC  string `xml:"mac,attr"`
+	Name string `xml:"name,attr"`
+	IP   string `xml:"ip,attr"`
+}
+
+// dhcpHosts returns the DHCP host entries of the network definition.
+func dhcpHosts(conn *libvirt.Libvirt, nw libvirt.Network) ([]DHCPHost, error) {
+	def, err := readDefinition(conn, nw)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []DHCPHost
+	for _, ip := range def.IPs {
+		hosts = append(hosts, ip.Hosts...)
+	}
+	return hosts, nil
+}
+
+// normalize returns the 4-byte form of an IPv4 address.
+func normalize(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
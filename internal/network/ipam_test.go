@@ -0,0 +1,149 @@
+package network
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestIPAM(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := db.EnsureSchema(ctx, database); err != nil {
+		t.Fatalf("EnsureSchema failed: %v", err)
+	}
+	vnet := db.VirtualNetwork{
+		Name:       "services",
+		Namespace:  "homelab",
+		NetAddress: "192.168.100.1",
+		Netmask:    "255.255.255.0",
+		DHCP:       map[string]string{"start": "192.168.100.3", "end": "192.168.100.254"},
+	}
+	if err := vnet.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+
+	// No libvirt connection: only records and allocations are checked.
+	ipam := NewIPAM(nil, database)
+
+	allocate := func(vm, want string, wantInserted bool) {
+		t.Helper()
+		got, inserted, err := ipam.Allocate(ctx, "services", vm)
+		if err != nil {
+			t.Fatalf("Allocate(%s) failed: %v", vm, err)
+		}
+		if got != want || inserted != wantInserted {
+			t.Errorf("Allocate(%s) = %s, %v, want %s, %v", vm, got, inserted, want, wantInserted)
+		}
+	}
+
+	// The network address and the gateway are skipped.
+	allocate("web-01", "192.168.100.2", true)
+	// A VM keeps the address it holds, which this call did not reserve.
+	allocate("web-01", "192.168.100.2", false)
+	// Everything else is in the DHCP range.
+	if _, _, err := ipam.Allocate(ctx, "services", "web-02"); err == nil {
+		t.Error("expected an exhausted pool, got an address")
+	}
+
+	reserveErrors := map[string]string{
+		"192.168.100.2":   "held by web-01",
+		"192.168.100.1":   "gateway",
+		"192.168.100.255": "broadcast",
+		"10.0.0.5":        "outside the subnet",
+		"not-an-ip":       "invalid",
+	}
+	for ip, why := range reserveErrors {
+		if _, err := ipam.Reserve(ctx, "services", "web-02", ip, ""); err == nil {
+			t.Errorf("Reserve(%s) succeeded, want an error (%s)", ip, why)
+		}
+	}
+	if inserted, err := ipam.Reserve(ctx, "services", "web-02", "192.168.100.50", ""); err != nil || !inserted {
+		t.Fatalf("Reserve = %v, %v, want a new reservation", inserted, err)
+	}
+	// Reserving the same address again for the same VM is a no-op.
+	if inserted, err := ipam.Reserve(ctx, "services", "web-02", "192.168.100.50", ""); err != nil || inserted {
+		t.Errorf("Reserve again = %v, %v, want no new reservation", inserted, err)
+	}
+
+	// A released address is handed out again.
+	if err := ipam.Release(ctx, "services", "web-01"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	allocate("web-03", "192.168.100.2", true)
+
+	// Networks without a subnet cannot allocate.
+	legacy := db.VirtualNetwork{Name: "legacy", Namespace: "homelab"}
+	if err := legacy.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+	if _, _, err := ipam.Allocate(ctx, "legacy", "web-04"); !errors.Is(err, ErrNoSubnet) {
+		t.Errorf("Allocate on legacy network: got %v, want ErrNoSubnet", err)
+	}
+}
+
+func TestIPAMCheckIPv6(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := db.EnsureSchema(ctx, database); err != nil {
+		t.Fatalf("EnsureSchema failed: %v", err)
+	}
+	dual := db.VirtualNetwork{
+		Name:       "dual",
+		Namespace:  "homelab",
+		NetAddress: "192.168.100.1",
+		Netmask:    "255.255.255.0",
+		IPs:        []db.NetworkIP{{Address: "fd00:100::1", Prefix: 64}},
+	}
+	if err := dual.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+	v4 := db.VirtualNetwork{Name: "v4", Namespace: "homelab", NetAddress: "10.0.0.1", Netmask: "255.255.255.0"}
+	if err := v4.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+	if err := dual.GetRecord(ctx, database, "dual"); err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	vm := db.VirtualMachine{Name: "web-01", Namespace: "homelab", NetworkID: dual.ID, IPv6: "fd00:100::10"}
+	if err := vm.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert vm failed: %v", err)
+	}
+
+	ipam := NewIPAM(nil, database)
+	if err := ipam.CheckIPv6(ctx, "dual", "web-02", "fd00:100::11"); err != nil {
+		t.Errorf("CheckIPv6 failed: %v", err)
+	}
+	// A VM may keep its own address.
+	if err := ipam.CheckIPv6(ctx, "dual", "web-01", "fd00:100::10"); err != nil {
+		t.Errorf("CheckIPv6 of the holder failed: %v", err)
+	}
+	invalid := []struct{ network, ip string }{
+		{"dual", "fd00:100::10"},  // held by web-01
+		{"dual", "fd00:100::1"},   // gateway
+		{"dual", "fd00:200::5"},   // outside the subnet
+		{"dual", "192.168.100.5"}, // not IPv6
+		{"v4", "fd00:100::11"},    // no IPv6 subnet
+	}
+	for _, tt := range invalid {
+		if err := ipam.CheckIPv6(ctx, tt.network, "web-02", tt.ip); err == nil {
+			t.Errorf("CheckIPv6(%s, %s) succeeded, want an error", tt.network, tt.ip)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/kebairia/kvmcli/internal/common"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+)
+
+// Lease types.
+const (
+	LeaseStatic  = "static"  // a DHCP host entry of the network
+	LeaseDynamic = "dynamic" // handed out from the DHCP range
+)
+
+// LeaseInfo holds everything we need to print one lease row: a DHCP lease,
+// a static reservation, or a reservation along with its current lease.
+type LeaseInfo struct {
+	Network  string
+	IP       string
+	MAC      string
+	Hostname string
+	VM       string
+	Type     string
+	Expiry   string
+}
+
+func (info *LeaseInfo) Header() *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NETWORK\tIP\tMAC\tHOSTNAME\tVM\tTYPE\tEXPIRES")
+	return w
+}
+
+func (info *LeaseInfo) PrintInfo(w *tabwriter.Writer) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		info.Network,
+		info.IP,
+		orDash(info.MAC),
+		orDash(info.Hostname),
+		orDash(info.VM),
+		info.Type,
+		info.Expiry,
+	)
+}
+
+// GetLeases returns the DHCP leases and static reservations of the networks
+// of a namespace, or of a single network when networkName is set.
+func GetLeases(
+	ctx context.Context,
+	database *sql.DB,
+	conn *libvirt.Libvirt,
+	namespace, networkName string,
+) ([]LeaseInfo, error) {
+	records, err := db.GetNetworks(ctx, database, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network records: %w", err)
+	}
+	vmRecords, err := db.GetVMRecords(ctx, database, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm records: %w", err)
+	}
+
+	var leases []LeaseInfo
+	found := false
+	for _, rec := range records {
+		if networkName != "" && rec.Name != networkName {
+			continue
+		}
+		found = true
+		nw, err := conn.NetworkLookupByName(rec.Name)
+		if err != nil {
+			log.Errorf("lookup network %q: %v", rec.Name, err)
+			continue
+		}
+		hosts, err := dhcpHosts(conn, nw)
+		if err != nil {
+			log.Errorf("%v", err)
+			continue
+		}
+		// An inactive network has no leases, its reservations are still shown.
+		active, _, err := conn.NetworkGetDhcpLeases(nw, nil, 1, 0)
+		if err != nil {
+			log.Errorf("get dhcp leases for network %q: %v", rec.Name, err)
+		}
+		leases = append(leases, joinLeases(rec.Name, active, hosts, vmsOn(vmRecords, rec.ID), time.Now())...)
+	}
+	if networkName != "" && !found {
+		return nil, fmt.Errorf("network %q not found", networkName)
+	}
+	return leases, nil
+}
+
+// vmsOn returns the VM records attached to the network.
+func vmsOn(records []db.VirtualMachine, networkID int) []db.VirtualMachine {
+	var on []db.VirtualMachine
+	for _, r := range records {
+		if r.NetworkID == networkID {
+			on = append(on, r)
+		}
+	}
+	return on
+}
+
+// joinLeases merges the leases of a network with its DHCP host entries, and
+// names the VM each one belongs to: by MAC address, or by address for
+// entries without one. Rows are ordered by address.
+func joinLeases(
+	networkName string,
+	leases []libvirt.NetworkDhcpLease,
+	hosts []DHCPHost,
+	records []db.VirtualMachine,
+	now time.Time,
+) []LeaseInfo {
+	vmByMAC := make(map[string]string)
+	vmByIP := make(map[string]string)
+	for _, r := range records {
+		if mac, err := net.ParseMAC(r.MacAddress); err == nil {
+			vmByMAC[mac.String()] = r.Name
+		}
+		for _, ip := range []string{r.IP, r.IPv6} {
+			if addr := net.ParseIP(ip); addr != nil {
+				vmByIP[addr.String()] = r.Name
+			}
+		}
+	}
+	vmOf := func(mac, ip string) string {
+		if m, err := net.ParseMAC(mac); err == nil {
+			if vm, ok := vmByMAC[m.String()]; ok {
+				return vm
+			}
+		}
+		if addr := net.ParseIP(ip); addr != nil {
+			return vmByIP[addr.String()]
+		}
+		return ""
+	}
+
+	static := make(map[string]DHCPHost)
+	for _, h := range hosts {
+		if addr := net.ParseIP(h.IP); addr != nil {
+			static[addr.String()] = h
+		}
+	}
+
+	var rows []LeaseInfo
+	leased := make(map[string]bool)
+	for _, lease := range leases {
+		addr := net.ParseIP(lease.Ipaddr)
+		if addr == nil {
+			continue
+		}
+		row := LeaseInfo{
+			Network: networkName,
+			IP:      addr.String(),
+			Type:    LeaseDynamic,
+			Expiry:  formatExpiry(lease.Expirytime, now),
+		}
+		if len(lease.Mac) > 0 {
+			row.MAC = lease.Mac[0]
+		}
+		if len(lease.Hostname) > 0 {
+			row.Hostname = lease.Hostname[0]
+		}
+		if h, ok := static[row.IP]; ok {
+			row.Type = LeaseStatic
+			if row.Hostname == "" {
+				row.Hostname = h.Name
+			}
+		}
+		row.VM = vmOf(row.MAC, row.IP)
+		leased[row.IP] = true
+		rows = append(rows, row)
+	}
+	for ip, h := range static {
+		if leased[ip] {
+			continue
+		}
+		rows = append(rows, LeaseInfo{
+			Network:  networkName,
+			IP:       ip,
+			MAC:      h.MAC,
+			Hostname: h.Name,
+			VM:       vmOf(h.MAC, ip),
+			Type:     LeaseStatic,
+			Expiry:   "-", // not leased
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b LeaseInfo) int {
+		return bytes.Compare(net.ParseIP(a.IP), net.ParseIP(b.IP))
+	})
+	return rows
+}
+
+// formatExpiry returns how long a lease has left; libvirt reports leases
+// that never expire with an expiry time of 0.
+func formatExpiry(expiry int64, now time.Time) string {
+	if expiry == 0 {
+		return "never"
+	}
+	t := time.Unix(expiry, 0)
+	if !t.After(now) {
+		return "expired"
+	}
+	return common.FormatDuration(t.Sub(now))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
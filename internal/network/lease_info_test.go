@@ -0,0 +1,49 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+func TestJoinLeases(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	leases := []libvirt.NetworkDhcpLease{
+		{Ipaddr: "192.168.100.10", Mac: []string{"02:AA:BB:A8:64:0A"}, Expirytime: now.Add(time.Hour).Unix()},
+		{Ipaddr: "192.168.100.120", Mac: []string{"52:54:00:12:34:56"}, Hostname: []string{"laptop"}, Expirytime: now.Add(-time.Minute).Unix()},
+	}
+	hosts := []DHCPHost{
+		{MAC: "02:aa:bb:a8:64:0a", IP: "192.168.100.10"},
+		{MAC: "02:aa:bb:a8:64:0b", IP: "192.168.100.11"},
+		{Name: "web-02", IP: "fd00:100::11"},
+	}
+	records := []db.VirtualMachine{
+		{Name: "web-01", MacAddress: "02:aa:bb:a8:64:0a", IP: "192.168.100.10"},
+		{Name: "web-02", MacAddress: "02:aa:bb:a8:64:0c", IPv6: "fd00:100::11"},
+	}
+
+	rows := joinLeases("services", leases, hosts, records, now)
+	want := []struct{ ip, vm, typ, expiry string }{
+		{"192.168.100.10", "web-01", LeaseStatic, "1h"},
+		{"192.168.100.11", "", LeaseStatic, "-"},
+		{"192.168.100.120", "", LeaseDynamic, "expired"},
+		{"fd00:100::11", "web-02", LeaseStatic, "-"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("joinLeases returned %d rows, want %d: %v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.IP != w.ip || r.VM != w.vm || r.Type != w.typ || r.Expiry != w.expiry {
+			t.Errorf("row %d = %+v, want ip=%s vm=%q type=%s expiry=%q", i, r, w.ip, w.vm, w.typ, w.expiry)
+		}
+	}
+	if rows[2].Hostname != "laptop" {
+		t.Errorf("dynamic lease hostname = %q, want laptop", rows[2].Hostname)
+	}
+	if got := formatExpiry(0, now); got != "never" {
+		t.Errorf("formatExpiry(0) = %q, want never", got)
+	}
+}
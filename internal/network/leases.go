@@ -0,0 +1,34 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// LeaseIP returns the IPv4 address currently leased by the network's DHCP
+// server to one of the given MAC addresses.
+func LeaseIP(conn *libvirt.Libvirt, networkName string, macs []string) (string, error) {
+	nw, err := conn.NetworkLookupByName(networkName)
+	if err != nil {
+		return "", fmt.Errorf("lookup network %q: %w", networkName, err)
+	}
+
+	leases, _, err := conn.NetworkGetDhcpLeases(nw, nil, 1, 0)
+	if err != nil {
+		return "", fmt.Errorf("get dhcp leases for network %q: %w", networkName, err)
+	}
+
+	for _, lease := range leases {
+		if libvirt.IPAddrType(lease.Type) != libvirt.IPAddrTypeIpv4 || len(lease.Mac) == 0 {
+			continue
+		}
+		for _, mac := range macs {
+			if strings.EqualFold(lease.Mac[0], mac) {
+				return lease.Ipaddr, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no dhcp lease on network %q for %s", networkName, strings.Join(macs, ", "))
+}
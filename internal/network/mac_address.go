@@ -1,41 +1,182 @@
 package network
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/xml"
 	"fmt"
 	"net"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
 )
 
+// DefaultMACPrefix is the OUI prefix of networks without a mac_prefix. Its
+// first octet marks the addresses as locally administered.
+const DefaultMACPrefix = "02:aa:bb"
+
+// macAttempts bounds the random draws of MACAllocator.Allocate.
+const macAttempts = 64
+
+// MacFromIP derives a MAC address from the prefix and the last three bytes
+// of the IP address.
 func MacFromIP(macPrefix, ipStr string) (string, error) {
-	ip := net.ParseIP(ipStr).To4()
+	ip := net.ParseIP(ipStr)
 	if ip == nil {
-		return "", fmt.Errorf("invalid IPv4 address %q", ipStr)
+		return "", fmt.Errorf("invalid IP address %q", ipStr)
 	}
-	m, err := net.ParseMAC(macPrefix + ":00:00:00")
+	m, err := parseMACPrefix(macPrefix)
 	if err != nil {
-		return "", fmt.Errorf("invalid MAC prefix %q", macPrefix)
+		return "", err
 	}
+	b := ip[len(ip)-3:]
 	return fmt.Sprintf(
-		"%02x:%02x:%02x:00:%02x:%02x",
+		"%02x:%02x:%02x:%02x:%02x:%02x",
 		m[0], m[1], m[2],
-		ip[2], ip[3],
+		b[0], b[1], b[2],
 	), nil
 }
 
-func ResolveMAC(prefix, ip, specMAC string) (string, error) {
-	// Explicit MAC always wins
-	if specMAC != "" {
-		return specMAC, nil
+// ResolveMACPrefix defaults the MAC prefix of the network and checks that it
+// is a unicast OUI.
+func (c *Config) ResolveMACPrefix() error {
+	if c.MACPrefix == "" {
+		c.MACPrefix = DefaultMACPrefix
+	}
+	if _, err := parseMACPrefix(c.MACPrefix); err != nil {
+		return fmt.Errorf("network %q: %w", c.Name, err)
+	}
+	return nil
+}
+
+// parseMACPrefix parses a three-octet unicast MAC prefix.
+func parseMACPrefix(prefix string) (net.HardwareAddr, error) {
+	m, err := net.ParseMAC(prefix + ":00:00:00")
+	if err != nil || len(m) != 6 {
+		return nil, fmt.Errorf("invalid MAC prefix %q (want three octets, e.g. %s)", prefix, DefaultMACPrefix)
 	}
+	if m[0]&1 != 0 {
+		return nil, fmt.Errorf("invalid MAC prefix %q: multicast bit is set", prefix)
+	}
+	return m, nil
+}
 
-	// No MAC, no IP → nothing to resolve
-	if ip == "" {
-		return "", nil
+// MACAllocator hands out MAC addresses that no VM record and no libvirt
+// domain already uses.
+type MACAllocator struct {
+	conn *libvirt.Libvirt
+	db   *sql.DB
+}
+
+// NewMACAllocator returns a MACAllocator working on the given connections.
+func NewMACAllocator(conn *libvirt.Libvirt, database *sql.DB) *MACAllocator {
+	return &MACAllocator{conn: conn, db: database}
+}
+
+// Allocate returns a free MAC address under the prefix of the network: the
+// one derived from ip when it is free, a random one otherwise.
+func (a *MACAllocator) Allocate(ctx context.Context, networkName, ip string) (string, error) {
+	prefix, err := a.prefix(ctx, networkName)
+	if err != nil {
+		return "", err
+	}
+	used, err := a.used(ctx)
+	if err != nil {
+		return "", err
+	}
+	if ip != "" {
+		mac, err := MacFromIP(prefix, ip)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := used[mac]; !ok {
+			return mac, nil
+		}
 	}
+	m, _ := parseMACPrefix(prefix)
+	for range macAttempts {
+		if _, err := rand.Read(m[3:]); err != nil {
+			return "", fmt.Errorf("generate mac: %w", err)
+		}
+		if _, ok := used[m.String()]; !ok {
+			return m.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free MAC address found under prefix %s", prefix)
+}
 
-	mac, err := MacFromIP(prefix, ip)
+// Check returns an error when mac is invalid or used by a VM or domain
+// other than vmName.
+func (a *MACAllocator) Check(ctx context.Context, vmName, mac string) error {
+	m, err := net.ParseMAC(mac)
 	if err != nil {
+		return fmt.Errorf("invalid MAC address %q", mac)
+	}
+	used, err := a.used(ctx)
+	if err != nil {
+		return err
+	}
+	if owner, ok := used[m.String()]; ok && owner != vmName {
+		return fmt.Errorf("MAC address %s is already used by vm %q", mac, owner)
+	}
+	return nil
+}
+
+// prefix returns the MAC prefix recorded for the network.
+func (a *MACAllocator) prefix(ctx context.Context, networkName string) (string, error) {
+	var vnet db.VirtualNetwork
+	if err := vnet.GetRecord(ctx, a.db, networkName); err != nil {
 		return "", err
 	}
+	if vnet.MACPrefix == "" {
+		return DefaultMACPrefix, nil
+	}
+	return vnet.MACPrefix, nil
+}
+
+// used maps the MAC addresses of VM records and of every libvirt domain,
+// lowercased, to the VM or domain using them.
+func (a *MACAllocator) used(ctx context.Context) (map[string]string, error) {
+	used := make(map[string]string)
+	add := func(mac, owner string) {
+		if m, err := net.ParseMAC(mac); err == nil {
+			used[m.String()] = owner
+		}
+	}
+
+	records, err := db.GetVMRecords(ctx, a.db, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		add(r.MacAddress, r.Name)
+	}
 
-	return mac, nil
+	if a.conn == nil {
+		return used, nil
+	}
+	domains, _, err := a.conn.ConnectListAllDomains(1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list domains: %w", err)
+	}
+	for _, dom := range domains {
+		desc, err := a.conn.DomainGetXMLDesc(dom, libvirt.DomainXMLInactive)
+		if err != nil {
+			return nil, fmt.Errorf("get XML for %q: %w", dom.Name, err)
+		}
+		var def struct {
+			MACs []struct {
+				Address string `xml:"address,attr"`
+			} `xml:"devices>interface>mac"`
+		}
+		if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+			return nil, fmt.Errorf("parse XML of %q: %w", dom.Name, err)
+		}
+		for _, m := range def.MACs {
+			add(strings.TrimSpace(m.Address), dom.Name)
+		}
+	}
+	return used, nil
 }
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMacFromIP(t *testing.T) {
+	tests := []struct {
+		prefix, ip, want string
+		wantErr          bool
+	}{
+		{prefix: "02:aa:bb", ip: "10.0.1.5", want: "02:aa:bb:00:01:05"},
+		{prefix: "02:aa:bb", ip: "10.0.2.5", want: "02:aa:bb:00:02:05"},
+		{prefix: "52:54:00", ip: "192.168.100.10", want: "52:54:00:a8:64:0a"},
+		{prefix: "02:aa:bb", ip: "fd00:100::1:2", want: "02:aa:bb:01:00:02"},
+		{prefix: "02:aa:bb", ip: "not-an-ip", wantErr: true},
+		{prefix: "02:aa", ip: "10.0.1.5", wantErr: true},
+		{prefix: "03:aa:bb", ip: "10.0.1.5", wantErr: true}, // multicast
+	}
+	for _, tt := range tests {
+		got, err := MacFromIP(tt.prefix, tt.ip)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("MacFromIP(%s, %s) error = %v, wantErr %v", tt.prefix, tt.ip, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MacFromIP(%s, %s) = %s, want %s", tt.prefix, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestMACAllocator(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := db.EnsureSchema(ctx, database); err != nil {
+		t.Fatalf("EnsureSchema failed: %v", err)
+	}
+	vnet := db.VirtualNetwork{Name: "services", Namespace: "homelab", MACPrefix: "52:54:00"}
+	if err := vnet.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert network failed: %v", err)
+	}
+	vm := db.VirtualMachine{Name: "web-01", Namespace: "homelab", IP: "10.1.1.5", MacAddress: "52:54:00:01:01:05"}
+	if err := vm.Insert(ctx, database); err != nil {
+		t.Fatalf("Insert vm failed: %v", err)
+	}
+
+	// No libvirt connection: only VM records are checked.
+	macs := NewMACAllocator(nil, database)
+
+	got, err := macs.Allocate(ctx, "services", "10.1.2.5")
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if got != "52:54:00:01:02:05" {
+		t.Errorf("Allocate = %s, want the MAC derived from the IP", got)
+	}
+
+	// 11.1.1.5 derives the MAC of web-01, so a random one is drawn.
+	got, err = macs.Allocate(ctx, "services", "11.1.1.5")
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if got == vm.MacAddress || !strings.HasPrefix(got, "52:54:00:") {
+		t.Errorf("Allocate = %s, want a free MAC under 52:54:00", got)
+	}
+
+	// Without an IP the MAC is random but never blank.
+	if got, err := macs.Allocate(ctx, "services", ""); err != nil || got == "" {
+		t.Errorf("Allocate without ip = %q, %v", got, err)
+	}
+
+	if err := macs.Check(ctx, "web-02", "52:54:00:01:01:05"); err == nil {
+		t.Error("Check accepted the MAC of web-01 for web-02")
+	}
+	if err := macs.Check(ctx, "web-01", "52:54:00:01:01:05"); err != nil {
+		t.Errorf("Check rejected the MAC of web-01 for itself: %v", err)
+	}
+	if err := macs.Check(ctx, "web-02", "not-a-mac"); err == nil {
+		t.Error("Check accepted an invalid MAC")
+	}
+}
@@ -11,8 +11,10 @@ import (
 type NetworkManager interface {
 	Create(ctx context.Context, spec Config) error
 	Delete(ctx context.Context, name string) error
+	Update(ctx context.Context, spec Config, confirm ConfirmFunc) error
 	Start(ctx context.Context, name string) error
-	SetStaticMapping(ctx context.Context, name, ip, mac string) error
+	SetStaticMapping(ctx context.Context, name, ip, mac, host string) error
+	RemoveStaticMapping(ctx context.Context, name, ip, host string) error
 }
 
 // LibvirtNetworkManager implements NetworkManager using libvirt and a SQL database.
@@ -2,11 +2,13 @@ package network
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
 	"net"
 	"regexp"
 
 	"github.com/digitalocean/go-libvirt"
+	templates "github.com/kebairia/kvmcli/internal/templates"
 )
 
 var macRe = regexp.MustCompile(`^([0-9a-fA-F]{2}:){5}[0-9a-fA-F]{2}$`)
@@ -17,19 +19,28 @@ const (
 	networkUpdateCommandModify     uint32 = 2 // VIR_NETWORK_UPDATE_COMMAND_MODIFY
 )
 
-// SetStaticMapping ensures a DHCP reservation (MAC → IP) exists on a libvirt network.
+// SetStaticMapping ensures a DHCP reservation exists on a libvirt network:
+// MAC → IP for an IPv4 address, and host name → IP for an IPv6 address,
+// since DHCPv6 clients are not identified by their MAC address.
 //
 // Semantics:
 // - If mapping exists: update it to the requested IP (Modify; fallback Delete+Add)
 // - If mapping does not exist: create it
+// - The DNS host record of host is added once the DHCP entry is in place
 func (m *LibvirtNetworkManager) SetStaticMapping(
 	ctx context.Context,
-	networkName, ip, mac string,
+	networkName, ip, mac, host string,
 ) error {
 	if err := validateIP(ip); err != nil {
 		return err
 	}
-	if err := validateMAC(mac); err != nil {
+	entry, selector := dhcpHostXML(mac, ip), dhcpHostSelectorXML(mac)
+	if isIPv6(ip) {
+		if host == "" {
+			return fmt.Errorf("an IPv6 mapping needs a host name")
+		}
+		entry, selector = dhcpHostV6XML(host, ip), dhcpHostV6SelectorXML(host)
+	} else if err := validateMAC(mac); err != nil {
 		return err
 	}
 
@@ -37,80 +48,118 @@ func (m *LibvirtNetworkManager) SetStaticMapping(
 	if err != nil {
 		return fmt.Errorf("lookup network %q: %w", networkName, err)
 	}
+	def, err := m.definition(nw)
+	if err != nil {
+		return err
+	}
+	// A dual-stack network has an <ip> element per family; libvirt needs
+	// to be told which one the entry belongs to.
+	parent, err := def.parentIndex(ip)
+	if err != nil {
+		return err
+	}
 
 	flags := libvirt.NetworkUpdateAffectLive | libvirt.NetworkUpdateAffectConfig
 
 	// 1) Modify (clean path if host entry exists)
-	if err := m.modifyDHCPHost(nw, mac, ip, flags); err == nil {
-		return nil
+	if err := m.updateDHCPHost(nw, networkUpdateCommandModify, parent, entry, flags); err != nil {
+		// 2) Fallback: Delete (ignore if missing) then Add
+		// Delete by MAC (IPv4) or name (IPv6) selector only.
+		_ = m.updateDHCPHost(nw, uint32(libvirt.NetworkUpdateCommandDelete), parent, selector, flags)
+
+		if err := m.updateDHCPHost(nw, uint32(libvirt.NetworkUpdateCommandAddLast), parent, entry, flags); err != nil {
+			return fmt.Errorf(
+				"set dhcp mapping on network %q (mac=%s host=%s ip=%s): %w",
+				networkName,
+				mac,
+				host,
+				ip,
+				err,
+			)
+		}
 	}
 
-	// 2) Fallback: Delete (ignore if missing) then Add
-	// Delete by MAC selector only.
-	_ = m.deleteDHCPHost(nw, mac, flags)
-
-	if err := m.addDHCPHost(nw, mac, ip, flags); err != nil {
-		return fmt.Errorf(
-			"set dhcp mapping on network %q (mac=%s ip=%s): %w",
-			networkName,
-			mac,
-			ip,
-			err,
-		)
+	// Make the VM reachable by name as well, once it has its address, so
+	// that a failed mapping leaves neither entry behind.
+	if host != "" {
+		if err := m.setDNSHost(nw, def, host, ip); err != nil {
+			_ = m.updateDHCPHost(nw, uint32(libvirt.NetworkUpdateCommandDelete), parent, selector, flags)
+			return err
+		}
 	}
-
 	return nil
 }
 
-func (m *LibvirtNetworkManager) modifyDHCPHost(
+func (m *LibvirtNetworkManager) updateDHCPHost(
 	nw libvirt.Network,
-	mac, ip string,
+	command uint32,
+	parent int32,
+	xml string,
 	flags libvirt.NetworkUpdateFlags,
 ) error {
-	xml := dhcpHostXML(mac, ip)
-
 	return m.conn.NetworkUpdate(
 		nw,
-		networkUpdateCommandModify,
+		command,
 		networkUpdateSectionIPDhcpHost,
-		-1,
+		parent,
 		xml,
 		flags,
 	)
 }
 
-func (m *LibvirtNetworkManager) deleteDHCPHost(
-	nw libvirt.Network,
-	mac string,
-	flags libvirt.NetworkUpdateFlags,
-) error {
-	selector := dhcpHostSelectorXML(mac)
+// networkXML is the part of a network definition kvmcli reads back.
+type networkXML struct {
+	Domain struct {
+		Name string `xml:"name,attr"`
+	} `xml:"domain"`
+	DNSHosts []templates.DNSHost `xml:"dns>host"`
+	IPs      []struct {
+		Family  string     `xml:"family,attr"`
+		Address string     `xml:"address,attr"`
+		Hosts   []DHCPHost `xml:"dhcp>host"`
+	} `xml:"ip"`
+}
 
-	return m.conn.NetworkUpdate(
-		nw,
-		uint32(libvirt.NetworkUpdateCommandDelete),
-		networkUpdateSectionIPDhcpHost,
-		-1,
-		selector,
-		flags,
-	)
+// definition reads the current definition of the network.
+func (m *LibvirtNetworkManager) definition(nw libvirt.Network) (networkXML, error) {
+	return readDefinition(m.conn, nw)
 }
 
-func (m *LibvirtNetworkManager) addDHCPHost(
-	nw libvirt.Network,
-	mac, ip string,
-	flags libvirt.NetworkUpdateFlags,
-) error {
-	xml := dhcpHostXML(mac, ip)
+func readDefinition(conn *libvirt.Libvirt, nw libvirt.Network) (networkXML, error) {
+	var def networkXML
+	desc, err := conn.NetworkGetXMLDesc(nw, 0)
+	if err != nil {
+		return def, fmt.Errorf("get XML for network %q: %w", nw.Name, err)
+	}
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		return def, fmt.Errorf("parse XML of network %q: %w", nw.Name, err)
+	}
+	return def, nil
+}
 
-	return m.conn.NetworkUpdate(
-		nw,
-		uint32(libvirt.NetworkUpdateCommandAddLast),
-		networkUpdateSectionIPDhcpHost,
-		-1,
-		xml,
-		flags,
-	)
+// parentIndex returns the index of the <ip> element that has the family of
+// ip, or -1 to let libvirt pick when there is only one.
+func (d networkXML) parentIndex(ip string) (int32, error) {
+	if len(d.IPs) < 2 {
+		return -1, nil
+	}
+	for i, e := range d.IPs {
+		if (e.Family == "ipv6" || isIPv6(e.Address)) == isIPv6(ip) {
+			return int32(i), nil
+		}
+	}
+	return 0, fmt.Errorf("network has no %s address for %s", family(isIPv6(ip)), ip)
+}
+
+// dnsHost returns the DNS host record of ip, or nil.
+func (d networkXML) dnsHost(ip string) *templates.DNSHost {
+	addr := net.ParseIP(ip)
+	for i, h := range d.DNSHosts {
+		if addr.Equal(net.ParseIP(h.IP)) {
+			return &d.DNSHosts[i]
+		}
+	}
+	return nil
 }
 
 func dhcpHostXML(mac, ip string) string {
@@ -121,6 +170,14 @@ func dhcpHostSelectorXML(mac string) string {
 	return fmt.Sprintf(`<host mac='%s'/>`, mac)
 }
 
+func dhcpHostV6XML(name, ip string) string {
+	return fmt.Sprintf(`<host name='%s' ip='%s'/>`, name, ip)
+}
+
+func dhcpHostV6SelectorXML(name string) string {
+	return fmt.Sprintf(`<host name='%s'/>`, name)
+}
+
 func validateIP(ip string) error {
 	if net.ParseIP(ip) == nil {
 		return fmt.Errorf("invalid IP address %q", ip)
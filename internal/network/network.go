@@ -18,7 +18,10 @@ var (
 // Network represents a bound network resource (configuration + manager).
 // It implements resources.Resource.
 type Network struct {
-	Spec    Config
+	Spec Config
+	// Confirm is asked before an update redefines or restarts the network;
+	// without it, such updates are refused.
+	Confirm ConfirmFunc
 	ctx     context.Context
 	manager NetworkManager
 }
@@ -60,7 +63,17 @@ func (n *Network) Start() error {
 	return nil
 }
 
+// Update delegates to manager.
+func (n *Network) Update() error {
+	return n.manager.Update(n.ctx, n.Spec, n.Confirm)
+}
+
 // AddStaticMapping delegates to manager.
-func (n *Network) SetStaticMapping(ip, mac string) error {
-	return n.manager.SetStaticMapping(n.ctx, n.Spec.Name, ip, mac)
+func (n *Network) SetStaticMapping(ip, mac, host string) error {
+	return n.manager.SetStaticMapping(n.ctx, n.Spec.Name, ip, mac, host)
+}
+
+// RemoveStaticMapping delegates to manager.
+func (n *Network) RemoveStaticMapping(ip, host string) error {
+	return n.manager.RemoveStaticMapping(n.ctx, n.Spec.Name, ip, host)
 }
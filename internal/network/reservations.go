@@ -0,0 +1,172 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// RemoveStaticMapping removes the DHCP reservation of ip from a libvirt
+// network, along with the DNS record host got for it. A reservation that
+// does not exist is not an error.
+func (m *LibvirtNetworkManager) RemoveStaticMapping(
+	ctx context.Context,
+	networkName, ip, host string,
+) error {
+	if err := validateIP(ip); err != nil {
+		return err
+	}
+	if host != "" {
+		if err := m.RemoveDNSHost(ctx, networkName, host, ip); err != nil {
+			return err
+		}
+	}
+
+	nw, err := m.conn.NetworkLookupByName(networkName)
+	if err != nil {
+		return fmt.Errorf("lookup network %q: %w", networkName, err)
+	}
+	def, err := m.definition(nw)
+	if err != nil {
+		return err
+	}
+	parent, entry := def.dhcpHost(ip)
+	if entry == nil {
+		return nil
+	}
+	return m.removeDHCPHost(nw, parent, *entry)
+}
+
+// PruneReservations removes the DHCP host entries of a network that belong
+// to no VM record, and returns them. Entries declared in the ip blocks of
+// the network are kept. With dryRun, nothing is removed.
+func (m *LibvirtNetworkManager) PruneReservations(
+	ctx context.Context,
+	networkName string,
+	dryRun bool,
+) ([]DHCPHost, error) {
+	var vnet db.VirtualNetwork
+	if err := vnet.GetRecord(ctx, m.db, networkName); err != nil {
+		return nil, err
+	}
+	records, err := db.GetVMRecords(ctx, m.db, "")
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool)
+	for _, r := range records {
+		if r.NetworkID != vnet.ID {
+			continue
+		}
+		for _, ip := range []string{r.IP, r.IPv6} {
+			if addr := net.ParseIP(ip); addr != nil {
+				keep[addr.String()] = true
+			}
+		}
+	}
+	for _, block := range vnet.IPs {
+		for _, ip := range block.Hosts {
+			if addr := net.ParseIP(ip); addr != nil {
+				keep[addr.String()] = true
+			}
+		}
+	}
+
+	nw, err := m.conn.NetworkLookupByName(networkName)
+	if err != nil {
+		return nil, fmt.Errorf("lookup network %q: %w", networkName, err)
+	}
+	def, err := m.definition(nw)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []DHCPHost
+	for _, stale := range def.staleHosts(keep) {
+		if !dryRun {
+			if err := m.removeDHCPHost(nw, stale.parent, stale.host); err != nil {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, stale.host)
+	}
+	return pruned, nil
+}
+
+// removeDHCPHost deletes a DHCP host entry from the <ip> element at parent.
+func (m *LibvirtNetworkManager) removeDHCPHost(nw libvirt.Network, parent int32, host DHCPHost) error {
+	flags := libvirt.NetworkUpdateAffectLive | libvirt.NetworkUpdateAffectConfig
+	err := m.updateDHCPHost(nw, uint32(libvirt.NetworkUpdateCommandDelete), parent, host.xml(), flags)
+	if err != nil {
+		return fmt.Errorf("remove dhcp mapping %s from network %q: %w", host.IP, nw.Name, err)
+	}
+	return nil
+}
+
+// placedHost is a DHCP host entry along with the index of its <ip> element,
+// as NetworkUpdate wants it.
+type placedHost struct {
+	parent int32
+	host   DHCPHost
+}
+
+// dhcpHost returns the DHCP host entry of ip and the index of its <ip>
+// element, or a nil entry.
+func (d networkXML) dhcpHost(ip string) (int32, *DHCPHost) {
+	addr := net.ParseIP(ip)
+	for _, h := range d.placedHosts() {
+		if addr.Equal(net.ParseIP(h.host.IP)) {
+			return h.parent, &h.host
+		}
+	}
+	return -1, nil
+}
+
+// staleHosts returns the DHCP host entries whose address is not in keep,
+// which holds addresses in their canonical form.
+func (d networkXML) staleHosts(keep map[string]bool) []placedHost {
+	var stale []placedHost
+	for _, h := range d.placedHosts() {
+		addr := net.ParseIP(h.host.IP)
+		if addr == nil || !keep[addr.String()] {
+			stale = append(stale, h)
+		}
+	}
+	return stale
+}
+
+func (d networkXML) placedHosts() []placedHost {
+	var hosts []placedHost
+	for i, e := range d.IPs {
+		// libvirt picks the only <ip> element by itself.
+		parent := int32(i)
+		if len(d.IPs) < 2 {
+			parent = -1
+		}
+		for _, h := range e.Hosts {
+			hosts = append(hosts, placedHost{parent: parent, host: h})
+		}
+	}
+	return hosts
+}
+
+// xml returns the entry as a <host> element, with the attributes it has.
+func (h DHCPHost) xml() string {
+	var b strings.Builder
+	b.WriteString("<host")
+	for _, attr := range []struct{ name, value string }{
+		{"mac", h.MAC},
+		{"name", h.Name},
+		{"ip", h.IP},
+	} {
+		if attr.value != "" {
+			fmt.Fprintf(&b, " %s='%s'", attr.name, attr.value)
+		}
+	}
+	b.WriteString("/>")
+	return b.String()
+}
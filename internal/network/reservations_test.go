@@ -0,0 +1,52 @@
+package network
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestStaleHosts(t *testing.T) {
+	desc := `<network>
+  <ip address='192.168.100.1' netmask='255.255.255.0'>
+    <dhcp>
+      <host mac='02:aa:bb:a8:64:0a' ip='192.168.100.10'/>
+      <host mac='02:aa:bb:a8:64:0b' ip='192.168.100.11'/>
+    </dhcp>
+  </ip>
+  <ip family='ipv6' address='fd00:100::1' prefix='64'>
+    <dhcp>
+      <host name='web-01' ip='fd00:100::0010'/>
+      <host name='web-02' ip='fd00:100::11'/>
+    </dhcp>
+  </ip>
+</network>`
+	var def networkXML
+	if err := xml.Unmarshal([]byte(desc), &def); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	keep := map[string]bool{"192.168.100.10": true, "fd00:100::10": true}
+	stale := def.staleHosts(keep)
+	if len(stale) != 2 {
+		t.Fatalf("staleHosts = %v, want the entries of .11 and ::11", stale)
+	}
+	want := []struct {
+		parent int32
+		xml    string
+	}{
+		{0, `<host mac='02:aa:bb:a8:64:0b' ip='192.168.100.11'/>`},
+		{1, `<host name='web-02' ip='fd00:100::11'/>`},
+	}
+	for i, w := range want {
+		if stale[i].parent != w.parent || stale[i].host.xml() != w.xml {
+			t.Errorf("stale[%d] = %d %s, want %d %s", i, stale[i].parent, stale[i].host.xml(), w.parent, w.xml)
+		}
+	}
+
+	if parent, host := def.dhcpHost("fd00:100::10"); host == nil || parent != 1 {
+		t.Errorf("dhcpHost(fd00:100::10) = %d, %v", parent, host)
+	}
+	if _, host := def.dhcpHost("192.168.100.12"); host != nil {
+		t.Errorf("dhcpHost(192.168.100.12) = %v, want none", host)
+	}
+}
@@ -0,0 +1,624 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	templates "github.com/kebairia/kvmcli/internal/templates"
+)
+
+// ConfirmFunc asks whether to go ahead with a disruptive change, described
+// by prompt, and reports the answer.
+type ConfirmFunc func(prompt string) bool
+
+// recordTimeout bounds the writes to the network record once the network
+// itself has been changed.
+const recordTimeout = 30 * time.Second
+
+// networkChange is a change NetworkUpdate applies to a network in place.
+type networkChange struct {
+	desc    string // e.g. "+ dns host gw.lab 192.168.100.1"
+	command uint32
+	section uint32
+	parent  int32
+	xml     string
+}
+
+// undo returns the change that reverts c: liveChanges only adds and deletes.
+func (c networkChange) undo() networkChange {
+	if c.command == uint32(libvirt.NetworkUpdateCommandDelete) {
+		c.command = uint32(libvirt.NetworkUpdateCommandAddLast)
+	} else {
+		c.command = uint32(libvirt.NetworkUpdateCommandDelete)
+	}
+	return c
+}
+
+// applyChanges applies changes in order through update. When one fails,
+// the changes applied before it are undone, latest first, so that the
+// network is left as it was; changes that could not be undone are reported
+// along with the failure.
+func applyChanges(changes []networkChange, update func(networkChange) error) error {
+	for i, c := range changes {
+		err := update(c)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s: %w", c.desc, err)
+		for _, done := range slices.Backward(changes[:i]) {
+			if undoErr := update(done.undo()); undoErr != nil {
+				err = errors.Join(err, fmt.Errorf("undo %s (still applied): %w", done.desc, undoErr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Update reconciles an existing network with its configuration. DHCP
+// ranges, static hosts and DNS records are changed in place, without
+// disturbing the VMs on the network. Any other change redefines the
+// network and, when it is running, restarts it; both only once confirm
+// agrees.
+func (m *LibvirtNetworkManager) Update(ctx context.Context, spec Config, confirm ConfirmFunc) error {
+	if m.conn == nil {
+		return ErrNilLibvirtConn
+	}
+	name := spec.Name
+
+	var record db.VirtualNetwork
+	if err := record.GetRecord(ctx, m.db, name); err != nil {
+		return err
+	}
+	nw, err := m.conn.NetworkLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup network %q: %w", name, err)
+	}
+	live, err := m.liveTemplate(nw)
+	if err != nil {
+		return err
+	}
+	want := spec.template()
+
+	vms, err := db.GetVMRecords(ctx, m.db, "")
+	if err != nil {
+		return err
+	}
+	held := heldAddresses(vmsOn(vms, record.ID))
+	declared := declaredHosts(record)
+
+	updated := NewNetworkRecord(&Network{Spec: spec})
+	recordChanges := recordDiff(record, *updated)
+
+	reasons := restartReasons(live, want)
+	var changes []networkChange
+	if len(reasons) == 0 {
+		changes = liveChanges(live, want, declared, held)
+	}
+	if len(reasons) == 0 && len(changes) == 0 && len(recordChanges) == 0 {
+		fmt.Printf("network/%s unchanged\n", name)
+		return nil
+	}
+
+	for _, desc := range append(reasons, recordChanges...) {
+		fmt.Printf("network/%s %s\n", name, desc)
+	}
+	for _, c := range changes {
+		fmt.Printf("network/%s %s\n", name, c.desc)
+	}
+
+	active, err := m.conn.NetworkIsActive(nw)
+	if err != nil {
+		return fmt.Errorf("get state for network %q: %w", name, err)
+	}
+	restarted := false
+	if len(reasons) > 0 {
+		prompt := fmt.Sprintf("network/%s must be redefined to apply these changes. Continue?", name)
+		if active == 1 {
+			prompt = fmt.Sprintf(
+				"network/%s must be redefined and restarted to apply these changes, "+
+					"which cuts its running VMs off the network. Continue?", name)
+		}
+		if confirm == nil || !confirm(prompt) {
+			return fmt.Errorf("network %q not updated: the change was not confirmed", name)
+		}
+		if err := m.redefine(nw, live, want, declared, held, active == 1); err != nil {
+			return err
+		}
+		restarted = active == 1
+	} else {
+		flags := libvirt.NetworkUpdateAffectConfig
+		if active == 1 {
+			flags |= libvirt.NetworkUpdateAffectLive
+		}
+		update := func(c networkChange) error {
+			return m.conn.NetworkUpdate(nw, c.command, c.section, c.parent, c.xml, flags)
+		}
+		if err := applyChanges(changes, update); err != nil {
+			return fmt.Errorf("update network %q: %w", name, err)
+		}
+	}
+
+	// Waiting for confirm may have used up the deadline of ctx, which must
+	// not leave the record behind the network.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	if err := updated.Update(ctx, m.db); err != nil {
+		return err
+	}
+	if updated.Autostart != record.Autostart {
+		if err := setNetworkAutostart(m.conn, nw, updated.Autostart); err != nil {
+			return err
+		}
+		if err := db.SetNetworkAutostart(ctx, m.db, name, updated.Autostart); err != nil {
+			return err
+		}
+	}
+	if restarted {
+		fmt.Printf("network/%s updated (restarted)\n", name)
+	} else {
+		fmt.Printf("network/%s updated\n", name)
+	}
+	return nil
+}
+
+// liveTemplate reads the current definition of the network.
+func (m *LibvirtNetworkManager) liveTemplate(nw libvirt.Network) (*templates.Network, error) {
+	desc, err := m.conn.NetworkGetXMLDesc(nw, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get XML for network %q: %w", nw.Name, err)
+	}
+	var live templates.Network
+	if err := xml.Unmarshal([]byte(desc), &live); err != nil {
+		return nil, fmt.Errorf("parse XML of network %q: %w", nw.Name, err)
+	}
+	return &live, nil
+}
+
+// redefine replaces the definition of the network with want, keeping its
+// UUID, its bridge and the DHCP and DNS entries of its VMs, and restarts
+// it when restart is set.
+func (m *LibvirtNetworkManager) redefine(
+	nw libvirt.Network,
+	live, want *templates.Network,
+	declared, held map[string]bool,
+	restart bool,
+) error {
+	carryOver(live, want, declared, held)
+	def, err := renderNetwork(want)
+	if err != nil {
+		return err
+	}
+	if _, err := m.conn.NetworkDefineXML(def); err != nil {
+		return fmt.Errorf("redefine network %q: %w", nw.Name, err)
+	}
+	if !restart {
+		return nil
+	}
+	if err := m.conn.NetworkDestroy(nw); err != nil {
+		return fmt.Errorf("stop network %q: %w", nw.Name, err)
+	}
+	if err := m.conn.NetworkCreate(nw); err != nil {
+		return fmt.Errorf("start network %q: %w", nw.Name, err)
+	}
+	return nil
+}
+
+// carryOver copies what libvirt or kvmcli added to the live definition
+// into want: the UUID, a generated bridge name, and the DHCP and DNS host
+// entries of VMs.
+func carryOver(live, want *templates.Network, declared, held map[string]bool) {
+	want.UUID = live.UUID
+	if want.Bridge == nil && live.Bridge != nil {
+		want.Bridge = &templates.Bridge{Name: live.Bridge.Name}
+	}
+
+	wanted := make(map[string]bool)
+	for _, ip := range want.IPs {
+		if ip.DHCP != nil {
+			for _, h := range ip.DHCP.Hosts {
+				wanted[canonicalIP(h.IP)] = true
+			}
+		}
+	}
+	for _, ip := range live.IPs {
+		if ip.DHCP == nil {
+			continue
+		}
+		for _, h := range ip.DHCP.Hosts {
+			addr := canonicalIP(h.IP)
+			if declared[addr] || wanted[addr] {
+				continue
+			}
+			// The entry goes to the address of its family, if any is left.
+			for i := range want.IPs {
+				if ipFamily(want.IPs[i]) == family(isIPv6(addr)) && contains(want.IPs[i], addr) {
+					if want.IPs[i].DHCP == nil {
+						want.IPs[i].DHCP = &templates.DHCP{}
+					}
+					want.IPs[i].DHCP.Hosts = append(want.IPs[i].DHCP.Hosts, h)
+					break
+				}
+			}
+		}
+	}
+
+	if live.DNS == nil {
+		return
+	}
+	for _, h := range live.DNS.Hosts {
+		addr := canonicalIP(h.IP)
+		if !held[addr] || want.DNS != nil && dnsHostAt(want.DNS.Hosts, addr) != nil {
+			continue
+		}
+		if want.DNS == nil {
+			want.DNS = &templates.DNS{}
+		}
+		want.DNS.Hosts = append(want.DNS.Hosts, h)
+	}
+}
+
+// restartReasons describes the differences between the live definition
+// and want that NetworkUpdate cannot apply.
+func restartReasons(live, want *templates.Network) []string {
+	var reasons []string
+	changed := func(what, from, to string) {
+		reasons = append(reasons, fmt.Sprintf("~ %s %s → %s", what, orDash(from), orDash(to)))
+	}
+
+	liveFwd, wantFwd := forwardOf(live), forwardOf(want)
+	if liveFwd.Mode != wantFwd.Mode {
+		changed("mode", liveFwd.Mode, wantFwd.Mode)
+	}
+	// libvirt reports the first interface of a pool as the forward dev.
+	if len(wantFwd.Interfaces) == 0 && liveFwd.Dev != wantFwd.Dev {
+		changed("dev", liveFwd.Dev, wantFwd.Dev)
+	}
+	if a, b := natOf(liveFwd), natOf(wantFwd); a != b {
+		changed("nat", a, b)
+	}
+	if a, b := interfacesOf(liveFwd), interfacesOf(wantFwd); a != b {
+		changed("interfaces", a, b)
+	}
+	if a, b := pfOf(liveFwd), pfOf(wantFwd); a != b {
+		changed("dev", a, b)
+	}
+	// Without a bridge in the config, libvirt picks one.
+	if want.Bridge != nil && (live.Bridge == nil || live.Bridge.Name != want.Bridge.Name) {
+		changed("bridge", bridgeOf(live), want.Bridge.Name)
+	}
+	if a, b := domainOf(live), domainOf(want); a != b {
+		changed("dns domain", a, b)
+	}
+	if a, b := forwardersOf(live), forwardersOf(want); a != b {
+		changed("dns forwarders", a, b)
+	}
+	if a, b := addressesOf(live), addressesOf(want); a != b {
+		changed("addresses", a, b)
+	}
+	return reasons
+}
+
+// liveChanges returns the NetworkUpdate calls that turn the live definition
+// into want, deletions first. Host entries are only removed when the
+// config declared them before (declared) or, for DNS records, when no VM
+// holds their address (held): VMs add their own.
+func liveChanges(live, want *templates.Network, declared, held map[string]bool) []networkChange {
+	var deletes, adds []networkChange
+	del := func(desc string, section uint32, parent int32, xml string) {
+		deletes = append(deletes, networkChange{
+			desc: desc, command: uint32(libvirt.NetworkUpdateCommandDelete), section: section, parent: parent, xml: xml,
+		})
+	}
+	add := func(desc string, section uint32, parent int32, xml string) {
+		adds = append(adds, networkChange{
+			desc: desc, command: uint32(libvirt.NetworkUpdateCommandAddLast), section: section, parent: parent, xml: xml,
+		})
+	}
+
+	for i := range want.IPs {
+		parent := int32(i)
+		if len(want.IPs) < 2 {
+			parent = -1
+		}
+		liveDHCP, wantDHCP := dhcpOf(live.IPs[i]), dhcpOf(want.IPs[i])
+
+		from, to := rangeOf(liveDHCP.Range), rangeOf(wantDHCP.Range)
+		if from != to {
+			if liveDHCP.Range != nil {
+				del("- dhcp range "+from, rangeSection, parent, elementXML(liveDHCP.Range, "range"))
+			}
+			if wantDHCP.Range != nil {
+				add("+ dhcp range "+to, rangeSection, parent, elementXML(wantDHCP.Range, "range"))
+			}
+		}
+
+		for _, h := range liveDHCP.Hosts {
+			addr := canonicalIP(h.IP)
+			if declared[addr] && !slices.ContainsFunc(wantDHCP.Hosts, sameDHCPHost(h)) {
+				del("- dhcp host "+describeDHCPHost(h), networkUpdateSectionIPDhcpHost, parent, elementXML(h, "host"))
+			}
+		}
+		for _, h := range wantDHCP.Hosts {
+			if slices.ContainsFunc(liveDHCP.Hosts, sameDHCPHost(h)) {
+				continue
+			}
+			// Another entry on the address would make libvirt refuse the new one.
+			for _, old := range liveDHCP.Hosts {
+				if canonicalIP(old.IP) == canonicalIP(h.IP) && !declared[canonicalIP(old.IP)] {
+					del("- dhcp host "+describeDHCPHost(old), networkUpdateSectionIPDhcpHost, parent, elementXML(old, "host"))
+				}
+			}
+			add("+ dhcp host "+describeDHCPHost(h), networkUpdateSectionIPDhcpHost, parent, elementXML(h, "host"))
+		}
+	}
+
+	liveDNS, wantDNS := dnsOf(live), dnsOf(want)
+	for _, h := range liveDNS.Hosts {
+		if !held[canonicalIP(h.IP)] && !slices.ContainsFunc(wantDNS.Hosts, sameDNSHost(h)) {
+			del("- dns host "+describeDNSHost(h), dnsHostSection, -1, elementXML(h, "host"))
+		}
+	}
+	for _, h := range wantDNS.Hosts {
+		if slices.ContainsFunc(liveDNS.Hosts, sameDNSHost(h)) {
+			continue
+		}
+		if old := dnsHostAt(liveDNS.Hosts, canonicalIP(h.IP)); old != nil && held[canonicalIP(h.IP)] {
+			del("- dns host "+describeDNSHost(*old), dnsHostSection, -1, elementXML(*old, "host"))
+		}
+		add("+ dns host "+describeDNSHost(h), dnsHostSection, -1, elementXML(h, "host"))
+	}
+	for _, txt := range liveDNS.TXT {
+		if !slices.Contains(wantDNS.TXT, txt) {
+			del("- dns txt "+txt.Name, dnsTXTSection, -1, elementXML(txt, "txt"))
+		}
+	}
+	for _, txt := range wantDNS.TXT {
+		if !slices.Contains(liveDNS.TXT, txt) {
+			add("+ dns txt "+txt.Name, dnsTXTSection, -1, elementXML(txt, "txt"))
+		}
+	}
+	for _, srv := range liveDNS.SRV {
+		if !slices.Contains(wantDNS.SRV, srv) {
+			del("- dns srv "+describeSRV(srv), dnsSRVSection, -1, elementXML(srv, "srv"))
+		}
+	}
+	for _, srv := range wantDNS.SRV {
+		if !slices.Contains(liveDNS.SRV, srv) {
+			add("+ dns srv "+describeSRV(srv), dnsSRVSection, -1, elementXML(srv, "srv"))
+		}
+	}
+
+	return append(deletes, adds...)
+}
+
+// Sections of a network definition NetworkUpdate changes.
+const (
+	rangeSection   = uint32(libvirt.NetworkSectionIPDhcpRange)
+	dnsHostSection = uint32(libvirt.NetworkSectionDNSHost)
+	dnsTXTSection  = uint32(libvirt.NetworkSectionDNSTxt)
+	dnsSRVSection  = uint32(libvirt.NetworkSectionDNSSrv)
+)
+
+// recordDiff describes the changes kvmcli keeps in its own record, or
+// applies without touching the definition of the network.
+func recordDiff(old, updated db.VirtualNetwork) []string {
+	var diff []string
+	if old.Autostart != updated.Autostart {
+		diff = append(diff, fmt.Sprintf("~ autostart %t → %t", old.Autostart, updated.Autostart))
+	}
+	if old.MACPrefix != updated.MACPrefix {
+		diff = append(diff, fmt.Sprintf("~ mac_prefix %s → %s", orDash(old.MACPrefix), updated.MACPrefix))
+	}
+	if !maps.Equal(old.Labels, updated.Labels) {
+		diff = append(diff, "~ labels")
+	}
+	return diff
+}
+
+// heldAddresses returns the addresses of the VM records, in canonical form.
+func heldAddresses(records []db.VirtualMachine) map[string]bool {
+	held := make(map[string]bool)
+	for _, r := range records {
+		for _, ip := range []string{r.IP, r.IPv6} {
+			if ip != "" {
+				held[canonicalIP(ip)] = true
+			}
+		}
+	}
+	return held
+}
+
+// declaredHosts returns the addresses of the host entries the config of the
+// network declared when it was last applied.
+func declaredHosts(record db.VirtualNetwork) map[string]bool {
+	declared := make(map[string]bool)
+	for _, ip := range record.IPs {
+		for _, h := range ip.Hosts {
+			declared[canonicalIP(h)] = true
+		}
+	}
+	return declared
+}
+
+func canonicalIP(ip string) string {
+	if addr := net.ParseIP(ip); addr != nil {
+		return addr.String()
+	}
+	return ip
+}
+
+// elementXML returns v as an XML element with the given name. The values
+// given are plain structs, which always encode.
+func elementXML(v any, element string) string {
+	var b strings.Builder
+	if err := xml.NewEncoder(&b).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: element}}); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+func forwardOf(n *templates.Network) templates.Forward {
+	if n.Forward == nil {
+		return templates.Forward{}
+	}
+	return *n.Forward
+}
+
+// natOf describes the nat of a forward; libvirt shows its default port
+// range even when none was given.
+func natOf(f templates.Forward) string {
+	if f.NAT == nil {
+		return ""
+	}
+	var parts []string
+	if f.NAT.Address != nil {
+		parts = append(parts, "address "+rangeOf(f.NAT.Address))
+	}
+	if p := f.NAT.Port; p != nil && !(p.Start == "1024" && p.End == "65535") {
+		parts = append(parts, "port "+rangeOf(p))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func interfacesOf(f templates.Forward) string {
+	var devs []string
+	for _, i := range f.Interfaces {
+		devs = append(devs, i.Dev)
+	}
+	return strings.Join(devs, ",")
+}
+
+func pfOf(f templates.Forward) string {
+	if f.PF == nil {
+		return ""
+	}
+	return f.PF.Dev
+}
+
+func bridgeOf(n *templates.Network) string {
+	if n.Bridge == nil {
+		return ""
+	}
+	return n.Bridge.Name
+}
+
+func domainOf(n *templates.Network) string {
+	if n.Domain == nil {
+		return ""
+	}
+	return n.Domain.Name
+}
+
+func dnsOf(n *templates.Network) templates.DNS {
+	if n.DNS == nil {
+		return templates.DNS{}
+	}
+	return *n.DNS
+}
+
+func forwardersOf(n *templates.Network) string {
+	var addrs []string
+	for _, f := range dnsOf(n).Forwarders {
+		addrs = append(addrs, canonicalIP(f.Addr))
+	}
+	return strings.Join(addrs, ",")
+}
+
+// addressesOf describes the <ip> elements of a network, in order.
+func addressesOf(n *templates.Network) string {
+	var addrs []string
+	for _, ip := range n.IPs {
+		addrs = append(addrs, fmt.Sprintf("%s/%d", canonicalIP(ip.Address), prefixOf(ip)))
+	}
+	return strings.Join(addrs, ",")
+}
+
+func prefixOf(ip templates.IP) int {
+	if ip.Prefix > 0 || ip.Netmask == "" {
+		return ip.Prefix
+	}
+	mask := net.ParseIP(ip.Netmask).To4()
+	if mask == nil {
+		return 0
+	}
+	ones, _ := net.IPMask(mask).Size()
+	return ones
+}
+
+func ipFamily(ip templates.IP) string {
+	return family(ip.Family == "ipv6" || isIPv6(ip.Address))
+}
+
+// contains reports whether addr is within the subnet of the <ip> element.
+func contains(ip templates.IP, addr string) bool {
+	bits := 32
+	if ipFamily(ip) == family(true) {
+		bits = 128
+	}
+	subnet := net.IPNet{IP: net.ParseIP(ip.Address), Mask: net.CIDRMask(prefixOf(ip), bits)}
+	return subnet.Contains(net.ParseIP(addr))
+}
+
+func dhcpOf(ip templates.IP) templates.DHCP {
+	if ip.DHCP == nil {
+		return templates.DHCP{}
+	}
+	return *ip.DHCP
+}
+
+func rangeOf(r *templates.Range) string {
+	if r == nil {
+		return ""
+	}
+	return canonicalIP(r.Start) + "-" + canonicalIP(r.End)
+}
+
+func sameDHCPHost(h templates.DHCPHost) func(templates.DHCPHost) bool {
+	return func(o templates.DHCPHost) bool {
+		return canonicalIP(o.IP) == canonicalIP(h.IP) &&
+			strings.EqualFold(o.MAC, h.MAC) && o.ID == h.ID && o.Name == h.Name
+	}
+}
+
+func describeDHCPHost(h templates.DHCPHost) string {
+	for _, id := range []string{h.MAC, h.ID, h.Name} {
+		if id != "" {
+			return fmt.Sprintf("%s (%s)", canonicalIP(h.IP), id)
+		}
+	}
+	return canonicalIP(h.IP)
+}
+
+func sameDNSHost(h templates.DNSHost) func(templates.DNSHost) bool {
+	return func(o templates.DNSHost) bool {
+		return canonicalIP(o.IP) == canonicalIP(h.IP) && slices.Equal(o.Hostnames, h.Hostnames)
+	}
+}
+
+// dnsHostAt returns the DNS host record of addr, in canonical form, or nil.
+func dnsHostAt(hosts []templates.DNSHost, addr string) *templates.DNSHost {
+	for i, h := range hosts {
+		if canonicalIP(h.IP) == addr {
+			return &hosts[i]
+		}
+	}
+	return nil
+}
+
+func describeDNSHost(h templates.DNSHost) string {
+	return strings.Join(h.Hostnames, ",") + " " + canonicalIP(h.IP)
+}
+
+func describeSRV(srv templates.DNSSRV) string {
+	return fmt.Sprintf("_%s._%s %s:%d", srv.Service, srv.Protocol, orDash(srv.Target), srv.Port)
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"encoding/xml"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	templates "github.com/kebairia/kvmcli/internal/templates"
+)
+
+// liveServices is the network below as libvirt reports it, with the
+// entries web-01 got and the defaults libvirt adds.
+const liveServices = `<network>
+  <name>services</name>
+  <uuid>3e3fce45-4f53-4ef3-9b8b-6c1c7c9b2a51</uuid>
+  <forward mode='nat'>
+    <nat><port start='1024' end='65535'/></nat>
+  </forward>
+  <bridge name='virbr1' stp='on' delay='0'/>
+  <mac address='52:54:00:0a:0b:0c'/>
+  <domain name='lab.local' localOnly='yes'/>
+  <dns>
+    <txt name='owner' value='ops'/>
+    <host ip='192.168.100.10'><hostname>web-01.lab.local</hostname></host>
+    <host ip='192.168.100.5'><hostname>old.lab.local</hostname></host>
+  </dns>
+  <ip address='192.168.100.1' netmask='255.255.255.0'>
+    <dhcp>
+      <range start='192.168.100.100' end='192.168.100.200'/>
+      <host mac='02:aa:bb:a8:64:0a' ip='192.168.100.10'/>
+    </dhcp>
+  </ip>
+  <ip family='ipv6' address='fd00:100::1' prefix='64'>
+    <dhcp>
+      <range start='fd00:100::1:0' end='fd00:100::1:ffff'/>
+      <host name='printer' ip='fd00:100::20'/>
+    </dhcp>
+  </ip>
+</network>`
+
+func servicesConfig() Config {
+	return Config{
+		Name:      "services",
+		Namespace: "homelab",
+		CIDR:      "192.168.100.0/24",
+		DHCP:      &DHCP{Start: "192.168.100.50", End: "192.168.100.200"},
+		IPs: []IPBlock{{
+			CIDR:  "fd00:100::/64",
+			Hosts: []Host{{Name: "scanner", IP: "fd00:100::21"}},
+		}},
+		DNS: &DNS{
+			Domain: "lab.local",
+			TXT:    []TXT{{Name: "owner", Value: "ops"}},
+		},
+	}
+}
+
+func parseLive(t *testing.T) *templates.Network {
+	t.Helper()
+	var live templates.Network
+	if err := xml.Unmarshal([]byte(liveServices), &live); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return &live
+}
+
+func TestLiveChanges(t *testing.T) {
+	spec := servicesConfig()
+	if err := spec.ResolveAddress(); err != nil {
+		t.Fatalf("ResolveAddress failed: %v", err)
+	}
+	if err := spec.ValidateMode(); err != nil {
+		t.Fatalf("ValidateMode failed: %v", err)
+	}
+	live, want := parseLive(t), spec.template()
+
+	if reasons := restartReasons(live, want); len(reasons) != 0 {
+		t.Fatalf("restartReasons = %v, want none", reasons)
+	}
+
+	declared := map[string]bool{"fd00:100::20": true}
+	held := map[string]bool{"192.168.100.10": true}
+	var got []string
+	for _, c := range liveChanges(live, want, declared, held) {
+		got = append(got, c.desc)
+	}
+	wantDescs := []string{
+		"- dhcp range 192.168.100.100-192.168.100.200",
+		"- dhcp host fd00:100::20 (printer)",
+		"- dns host old.lab.local 192.168.100.5",
+		"+ dhcp range 192.168.100.50-192.168.100.200",
+		"+ dhcp host fd00:100::21 (scanner)",
+	}
+	if !slices.Equal(got, wantDescs) {
+		t.Errorf("liveChanges =\n%q\nwant\n%q", got, wantDescs)
+	}
+}
+
+func TestRestartReasons(t *testing.T) {
+	spec := servicesConfig()
+	spec.Mode = ModeRoute
+	spec.DNS.Forwarders = []string{"9.9.9.9"}
+	if err := spec.ResolveAddress(); err != nil {
+		t.Fatalf("ResolveAddress failed: %v", err)
+	}
+	live, want := parseLive(t), spec.template()
+
+	reasons := restartReasons(live, want)
+	if len(reasons) != 2 {
+		t.Fatalf("restartReasons = %v, want the mode and the forwarders", reasons)
+	}
+
+	// Redefining keeps the UUID, the bridge and the entries of web-01.
+	carryOver(live, want, map[string]bool{"fd00:100::20": true}, map[string]bool{"192.168.100.10": true})
+	if want.UUID != live.UUID || want.Bridge == nil || want.Bridge.Name != "virbr1" {
+		t.Errorf("carryOver kept uuid %q and bridge %v", want.UUID, want.Bridge)
+	}
+	if hosts := want.IPs[0].DHCP.Hosts; len(hosts) != 1 || hosts[0].MAC != "02:aa:bb:a8:64:0a" {
+		t.Errorf("carried DHCP hosts = %v, want web-01's", hosts)
+	}
+	if hosts := want.IPs[1].DHCP.Hosts; len(hosts) != 1 || hosts[0].Name != "scanner" {
+		t.Errorf("IPv6 DHCP hosts = %v, want scanner only", hosts)
+	}
+	if want.DNS == nil || dnsHostAt(want.DNS.Hosts, "192.168.100.10") == nil || dnsHostAt(want.DNS.Hosts, "192.168.100.5") != nil {
+		t.Errorf("carried DNS hosts = %v, want web-01's only", want.DNS)
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	add, del := uint32(libvirt.NetworkUpdateCommandAddLast), uint32(libvirt.NetworkUpdateCommandDelete)
+	changes := []networkChange{
+		{desc: "- dhcp range 192.168.100.100-192.168.100.200", command: del, xml: "old-range"},
+		{desc: "+ dhcp range 192.168.100.50-192.168.100.200", command: add, xml: "new-range"},
+		{desc: "+ dns host gw.lab 192.168.100.1", command: add, xml: "gw"},
+	}
+
+	var calls []string
+	update := func(c networkChange) error {
+		verb := "add"
+		if c.command == del {
+			verb = "delete"
+		}
+		calls = append(calls, verb+" "+c.xml)
+		if c.xml == "gw" {
+			return errors.New("operation failed")
+		}
+		return nil
+	}
+	err := applyChanges(changes, update)
+	if err == nil || !strings.Contains(err.Error(), "gw.lab") {
+		t.Errorf("applyChanges error = %v, want the failed change", err)
+	}
+	// The applied changes are reverted, latest first.
+	want := []string{"delete old-range", "add new-range", "add gw", "delete new-range", "add old-range"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
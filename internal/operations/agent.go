@@ -0,0 +1,63 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// ExecVM runs a command inside a VM through the guest agent, copies its
+// output to stdout and stderr, and returns its exit code.
+func ExecVM(name string, command []string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	agent, err := vms.NewGuestAgent(operator.conn, name)
+	if err != nil {
+		return 0, err
+	}
+	if err := agent.Ping(); err != nil {
+		return 0, err
+	}
+	result, err := agent.Exec(operator.ctx, command)
+	if err != nil {
+		return 0, err
+	}
+	os.Stdout.Write(result.Stdout)
+	os.Stderr.Write(result.Stderr)
+	return result.ExitCode, nil
+}
+
+// SetVMPassword sets the password of a user inside a VM through the guest agent.
+func SetVMPassword(name, user, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	agent, err := vms.NewGuestAgent(operator.conn, name)
+	if err != nil {
+		return err
+	}
+	if err := agent.Ping(); err != nil {
+		return err
+	}
+	if err := agent.SetPassword(user, password); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s password of %s updated\n", name, user)
+	return nil
+}
@@ -0,0 +1,57 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/common"
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/network"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// SetVMAutostart sets whether a VM starts along with the host.
+func SetVMAutostart(name string, on bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	record, err := db.GetVMByName(operator.ctx, operator.db, name, "")
+	if err != nil {
+		return err
+	}
+	domains := vms.NewLibvirtDomainManager(operator.conn)
+	if err := domains.SetAutostart(operator.ctx, name, on); err != nil {
+		return err
+	}
+	if err := db.SetVMAutostart(operator.ctx, operator.db, name, record.Namespace, on); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s autostart %s\n", name, common.OnOff(on))
+	return nil
+}
+
+// SetNetworkAutostart sets whether a network starts along with the host.
+func SetNetworkAutostart(name string, on bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	manager := network.NewLibvirtNetworkManager(operator.conn, operator.db)
+	if err := manager.SetAutostart(operator.ctx, name, on); err != nil {
+		return err
+	}
+	fmt.Printf("network/%s autostart %s\n", name, common.OnOff(on))
+	return nil
+}
@@ -0,0 +1,52 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/backup"
+)
+
+// backupTimeout bounds a backup or restore; disks can be tens of gigabytes.
+const backupTimeout = 2 * time.Hour
+
+// BackupVM writes a portable archive of a VM.
+func BackupVM(vmName string, opts backup.Options) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	if opts.Output == "" {
+		opts.Output = vmName + ".tar.zst"
+	}
+	if err := backup.Backup(operator.ctx, operator.conn, operator.db, vmName, opts); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s backed up to %s\n", vmName, opts.Output)
+	return nil
+}
+
+// RestoreVM re-registers a VM from a backup archive.
+func RestoreVM(opts backup.RestoreOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	name, err := backup.Restore(operator.ctx, operator.conn, operator.db, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s restored from %s\n", name, opts.Archive)
+	return nil
+}
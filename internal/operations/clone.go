@@ -0,0 +1,55 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// CloneVM duplicates a VM under a new name. When hclPath is set, an HCL
+// manifest for the clone is written there ("-" for stdout).
+func CloneVM(src, dst string, opts vms.CloneOptions, hclPath string) error {
+	// Full clones copy the whole disk.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	clone, err := vms.Clone(operator.ctx, operator.conn, operator.db, src, dst, opts)
+	if clone == nil {
+		return err
+	}
+	fmt.Printf("vm/%s cloned from vm/%s\n", dst, src)
+	if err != nil {
+		return err
+	}
+
+	if hclPath == "" {
+		return nil
+	}
+	networkName, err := db.GetNetworkNameByID(operator.ctx, operator.db, clone.NetworkID)
+	if err != nil {
+		return err
+	}
+	storeName, err := db.GetStoreNameByID(operator.ctx, operator.db, clone.StoreID)
+	if err != nil {
+		return err
+	}
+	hcl := vms.NewHCLFile(*clone, networkName, storeName).Bytes()
+	if hclPath == "-" {
+		_, err = os.Stdout.Write(hcl)
+		return err
+	}
+	if err := os.WriteFile(hclPath, hcl, 0o644); err != nil {
+		return fmt.Errorf("write %q: %w", hclPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/cluster"
+	"github.com/kebairia/kvmcli/internal/config"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+const (
+	// clusterTimeout bounds a whole cluster start, stop or restart.
+	clusterTimeout = time.Hour
+	// rolloutTimeout bounds a whole rollout, which handles a few VMs at a time.
+	rolloutTimeout = 12 * time.Hour
+)
+
+// StartCluster boots the VMs of a cluster defined in the manifest, group by group.
+func StartCluster(manifestPath, name string, opts cluster.StartOptions) error {
+	return withCluster(manifestPath, name, func(o *Operator, c *cluster.Config) error {
+		if err := cluster.NewManager(o.conn, o.db).Start(o.ctx, c, opts); err != nil {
+			return err
+		}
+		fmt.Printf("cluster/%s started\n", c.Name)
+		return nil
+	})
+}
+
+// StopCluster shuts the VMs of a cluster defined in the manifest down, group by group.
+func StopCluster(manifestPath, name string, opts vms.StopOptions) error {
+	return withCluster(manifestPath, name, func(o *Operator, c *cluster.Config) error {
+		if err := cluster.NewManager(o.conn, o.db).Stop(o.ctx, c, opts); err != nil {
+			return err
+		}
+		fmt.Printf("cluster/%s stopped\n", c.Name)
+		return nil
+	})
+}
+
+// RestartCluster stops a cluster in stop order, then starts it in start order.
+func RestartCluster(
+	manifestPath, name string,
+	stopOpts vms.StopOptions,
+	startOpts cluster.StartOptions,
+) error {
+	return withCluster(manifestPath, name, func(o *Operator, c *cluster.Config) error {
+		if err := cluster.NewManager(o.conn, o.db).Restart(o.ctx, c, stopOpts, startOpts); err != nil {
+			return err
+		}
+		fmt.Printf("cluster/%s restarted\n", c.Name)
+		return nil
+	})
+}
+
+// RolloutCluster rolls the action over the VMs of a cluster defined in the
+// manifest, a batch at a time.
+func RolloutCluster(manifestPath, name string, opts cluster.RolloutOptions) error {
+	return withClusterTimeout(manifestPath, name, rolloutTimeout, func(o *Operator, c *cluster.Config) error {
+		if err := cluster.NewManager(o.conn, o.db).Rollout(o.ctx, c, opts); err != nil {
+			return err
+		}
+		fmt.Printf("cluster/%s rolled out\n", c.Name)
+		return nil
+	})
+}
+
+// ResumeRollout continues the paused rollout of a cluster defined in the manifest.
+func ResumeRollout(manifestPath, name string) error {
+	return withClusterTimeout(manifestPath, name, rolloutTimeout, func(o *Operator, c *cluster.Config) error {
+		if err := cluster.NewManager(o.conn, o.db).ResumeRollout(o.ctx, c); err != nil {
+			return err
+		}
+		fmt.Printf("cluster/%s rolled out\n", c.Name)
+		return nil
+	})
+}
+
+// AbortRollout drops the paused rollout of a cluster. The namespace is only
+// needed when clusters of several namespaces share the name.
+func AbortRollout(name, namespace string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	if err := cluster.NewManager(operator.conn, operator.db).AbortRollout(operator.ctx, name, namespace); err != nil {
+		return err
+	}
+	fmt.Printf("cluster/%s rollout aborted\n", name)
+	return nil
+}
+
+// withCluster loads the named cluster from the manifest and runs fn on it.
+func withCluster(manifestPath, name string, fn func(*Operator, *cluster.Config) error) error {
+	return withClusterTimeout(manifestPath, name, clusterTimeout, fn)
+}
+
+// withClusterTimeout is withCluster with a custom bound on the whole operation.
+func withClusterTimeout(
+	manifestPath, name string,
+	timeout time.Duration,
+	fn func(*Operator, *cluster.Config) error,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	c, err := config.LoadCluster(manifestPath, name, operator.ctx, operator.db)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest %q: %w", manifestPath, err)
+	}
+	return fn(operator, c)
+}
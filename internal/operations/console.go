@@ -0,0 +1,31 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// ConsoleVM attaches the current terminal to the serial console of a VM.
+// When recordPath is set, the guest output is also appended to that file.
+func ConsoleVM(name, recordPath string, opts vms.ConsoleOptions) error {
+	// No timeout here: the session lasts as long as the user stays attached.
+	operator, err := NewOperator(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	if recordPath != "" {
+		f, err := os.OpenFile(recordPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("open record file %q: %w", recordPath, err)
+		}
+		defer f.Close()
+		opts.Record = f
+	}
+
+	return vms.OpenConsole(operator.ctx, operator.conn, name, os.Stdin, os.Stdout, opts)
+}
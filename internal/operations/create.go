@@ -8,6 +8,7 @@ import (
 	"github.com/kebairia/kvmcli/internal/config"
 	// "github.com/kebairia/kvmcli/internal/manifest"
 	"github.com/kebairia/kvmcli/internal/resources"
+	"github.com/kebairia/kvmcli/internal/vms"
 )
 
 // TODO: Create a context with a timeout for the operations.
@@ -23,8 +24,14 @@ import (
 // NOTICE: using go routines has an issue, because sometimes I need to create network resources
 //       before creating the VMs.
 
-func CreateFromManifest(manifestPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// CreateFromManifest creates the resources of a manifest. With wait set, it
+// then waits for every VM of the manifest to be ready, up to waitTimeout each.
+func CreateFromManifest(manifestPath string, wait bool, waitTimeout time.Duration) error {
+	timeout := 30 * time.Second
+	if wait {
+		timeout += waitTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	operator, err := NewOperator(ctx)
 	if err != nil {
@@ -38,13 +45,20 @@ func CreateFromManifest(manifestPath string) error {
 		return fmt.Errorf("failed to load manifest %q: %w", manifestPath, err)
 	}
 
+	var names []string
 	for _, resource := range resources {
 		if err := operator.Create(resource); err != nil {
 			return err
 		}
+		if vm, ok := resource.(*vms.VirtualMachine); ok {
+			names = append(names, vm.Spec.Name)
+		}
 	}
 
-	return nil
+	if !wait {
+		return nil
+	}
+	return operator.waitReady(names, waitTimeout)
 }
 
 // Create provisions the given Resource.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/kebairia/kvmcli/internal/cluster"
 	"github.com/kebairia/kvmcli/internal/network"
 	"github.com/kebairia/kvmcli/internal/store"
 	"github.com/kebairia/kvmcli/internal/vms"
@@ -99,3 +100,82 @@ func ListAllStores() error {
 	w.Flush()
 	return nil
 }
+
+// ListAllSnapshots lists the snapshots of every VM, optionally filtered by namespace.
+func ListAllSnapshots(namespace string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	snapshots, err := vms.GetSnapshots(operator.ctx, operator.db, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve snapshots: %w", err)
+	}
+
+	info := &vms.SnapshotInfo{}
+	w := info.Header()
+
+	for _, snap := range snapshots {
+		snap.PrintInfo(w)
+	}
+	w.Flush()
+	return nil
+}
+
+// ListAllClusters lists every cluster, optionally filtered by namespace.
+func ListAllClusters(namespace string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	clusters, err := cluster.GetClusters(operator.ctx, operator.db, operator.conn, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve clusters: %w", err)
+	}
+
+	info := &cluster.ClusterInfo{}
+	w := info.Header()
+
+	for _, c := range clusters {
+		c.PrintInfo(w)
+	}
+	w.Flush()
+	return nil
+}
+
+// ListAllLeases lists the DHCP leases and static reservations of the
+// networks of a namespace, or of a single network.
+func ListAllLeases(namespace, networkName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	leases, err := network.GetLeases(operator.ctx, operator.db, operator.conn, namespace, networkName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve leases: %w", err)
+	}
+
+	info := &network.LeaseInfo{}
+	w := info.Header()
+
+	for _, lease := range leases {
+		lease.PrintInfo(w)
+	}
+	w.Flush()
+	return nil
+}
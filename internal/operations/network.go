@@ -0,0 +1,56 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/network"
+)
+
+// PruneReservations removes the DHCP reservations of a network that no VM
+// record holds; every recorded network when name is empty.
+func PruneReservations(name string, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	names := []string{name}
+	if name == "" {
+		networks, err := db.GetNetworks(operator.ctx, operator.db, "")
+		if err != nil {
+			return fmt.Errorf("failed to retrieve networks: %w", err)
+		}
+		names = names[:0]
+		for _, n := range networks {
+			names = append(names, n.Name)
+		}
+	}
+
+	verb := "removed"
+	if dryRun {
+		verb = "would be removed"
+	}
+	manager := network.NewLibvirtNetworkManager(operator.conn, operator.db)
+	for _, networkName := range names {
+		pruned, err := manager.PruneReservations(operator.ctx, networkName, dryRun)
+		for _, host := range pruned {
+			fmt.Printf("network/%s reservation %s %s\n", networkName, host.IP, verb)
+		}
+		if err != nil {
+			if name != "" {
+				return err
+			}
+			// Keep going with the other networks.
+			log.Errorf("%v", err)
+		}
+	}
+	return nil
+}
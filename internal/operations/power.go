@@ -0,0 +1,85 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// RestartVM stops a VM gracefully and starts it again. The fresh boot picks
+// up the persistent definition, so any "restart required" flag is cleared.
+func RestartVM(name string, opts vms.StopOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout(opts)+stopGrace)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	domains := vms.NewLibvirtDomainManager(operator.conn)
+	if err := domains.Restart(operator.ctx, name, opts); err != nil {
+		return err
+	}
+	if err := clearRestartRequired(operator, name); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s restarted\n", name)
+	return nil
+}
+
+// clearRestartRequired clears the restart flag of a VM that booted afresh
+// from its persistent definition.
+func clearRestartRequired(o *Operator, name string) error {
+	record, err := db.GetVMByName(o.ctx, o.db, name, "")
+	if err != nil {
+		return err
+	}
+	return db.SetRestartRequired(o.ctx, o.db, name, record.Namespace, false)
+}
+
+// PauseVM freezes a running VM.
+func PauseVM(name string) error {
+	return powerAction(name, "paused", (*vms.LibvirtDomainManager).Pause)
+}
+
+// ResumeVM unfreezes a paused VM.
+func ResumeVM(name string) error {
+	return powerAction(name, "resumed", (*vms.LibvirtDomainManager).Resume)
+}
+
+// SuspendVM saves a VM's state to disk and stops it; starting it restores the state.
+func SuspendVM(name string) error {
+	return powerAction(name, "suspended", (*vms.LibvirtDomainManager).Suspend)
+}
+
+// ResetVM hard-resets a VM.
+func ResetVM(name string) error {
+	return powerAction(name, "reset", (*vms.LibvirtDomainManager).Reset)
+}
+
+// powerAction runs a single DomainManager call against a VM and reports it.
+func powerAction(
+	name, done string,
+	action func(*vms.LibvirtDomainManager, context.Context, string) error,
+) error {
+	// Managed save writes the whole guest memory to disk.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	if err := action(vms.NewLibvirtDomainManager(operator.conn), operator.ctx, name); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s %s\n", name, done)
+	return nil
+}
@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// reimageGrace bounds the disk work of a re-image, on top of the stop timeout.
+const reimageGrace = 2 * time.Minute
+
+// ReimageVM stops a VM, boots it from a fresh overlay on image and keeps
+// the replaced overlay until the re-image is confirmed or rolled back.
+func ReimageVM(name, image string, opts vms.StopOptions) error {
+	return withReimage(opts, func(o *Operator, domains *vms.LibvirtDomainManager) error {
+		record, err := db.GetVMByName(o.ctx, o.db, name, "")
+		if err != nil {
+			return err
+		}
+		err = vms.WhileStopped(o.ctx, domains, name, opts, func() error {
+			return vms.Reimage(o.ctx, o.conn, o.db, &vms.QemuDiskManager{}, name, image)
+		})
+		if err != nil {
+			return err
+		}
+		if err := db.SetRestartRequired(o.ctx, o.db, name, record.Namespace, false); err != nil {
+			return err
+		}
+		fmt.Printf("vm/%s reimaged from %s to %s\n", name, record.Image, image)
+		return nil
+	})
+}
+
+// ConfirmReimage keeps the last re-image of a VM and deletes the overlay it replaced.
+func ConfirmReimage(name string) error {
+	return withReimage(vms.StopOptions{}, func(o *Operator, _ *vms.LibvirtDomainManager) error {
+		if err := vms.ConfirmReimage(o.ctx, o.db, &vms.QemuDiskManager{}, name); err != nil {
+			return err
+		}
+		fmt.Printf("vm/%s reimage confirmed\n", name)
+		return nil
+	})
+}
+
+// RollbackReimage stops a VM and boots it again from the overlay its last
+// re-image replaced.
+func RollbackReimage(name string, opts vms.StopOptions) error {
+	return withReimage(opts, func(o *Operator, domains *vms.LibvirtDomainManager) error {
+		err := vms.WhileStopped(o.ctx, domains, name, opts, func() error {
+			return vms.RollbackReimage(o.ctx, o.conn, o.db, &vms.QemuDiskManager{}, name)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("vm/%s reimage rolled back\n", name)
+		return nil
+	})
+}
+
+// withReimage runs fn with an operator bounded by the stop timeout plus reimageGrace.
+func withReimage(opts vms.StopOptions, fn func(*Operator, *vms.LibvirtDomainManager) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout(opts)+reimageGrace)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	return fn(operator, vms.NewLibvirtDomainManager(operator.conn))
+}
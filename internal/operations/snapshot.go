@@ -0,0 +1,94 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// CreateSnapshot takes a snapshot of a VM.
+func CreateSnapshot(vmName string, opts vms.SnapshotOptions) error {
+	// Internal snapshots of running VMs save memory too, which can take a while.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	manager := vms.NewLibvirtSnapshotManager(operator.conn, operator.db)
+	snap, err := manager.Create(operator.ctx, vmName, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("snapshot/%s created for vm/%s\n", snap.Name, vmName)
+	return nil
+}
+
+// RevertSnapshot rolls a VM back to one of its snapshots.
+func RevertSnapshot(vmName, snapshot string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	manager := vms.NewLibvirtSnapshotManager(operator.conn, operator.db)
+	if err := manager.Revert(operator.ctx, vmName, snapshot); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s reverted to snapshot/%s\n", vmName, snapshot)
+	return nil
+}
+
+// DeleteSnapshot removes one snapshot of a VM.
+func DeleteSnapshot(vmName, snapshot string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	manager := vms.NewLibvirtSnapshotManager(operator.conn, operator.db)
+	if err := manager.Delete(operator.ctx, vmName, snapshot); err != nil {
+		return err
+	}
+	fmt.Printf("snapshot/%s deleted\n", snapshot)
+	return nil
+}
+
+// ListSnapshots prints the snapshots of a single VM.
+func ListSnapshots(vmName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	manager := vms.NewLibvirtSnapshotManager(operator.conn, operator.db)
+	records, err := manager.List(operator.ctx, vmName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve snapshots: %w", err)
+	}
+
+	info := &vms.SnapshotInfo{}
+	w := info.Header()
+	for _, rec := range records {
+		vms.NewSnapshotInfo(rec).PrintInfo(w)
+	}
+	w.Flush()
+	return nil
+}
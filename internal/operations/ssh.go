@@ -0,0 +1,63 @@
+package operations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/ssh"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// SSHVM opens an SSH session to a VM, or runs command on it when one is given.
+// An empty user falls back to the image default, then to the namespace settings.
+func SSHVM(name, user string, command []string) error {
+	// No timeout here: the session lasts as long as the user needs it.
+	operator, err := NewOperator(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	record, err := db.GetVMByName(operator.ctx, operator.db, name, "")
+	if err != nil {
+		return err
+	}
+
+	target, err := operator.sshTarget(record, user)
+	if err != nil {
+		return err
+	}
+
+	return target.Command(operator.ctx, command).Run()
+}
+
+// sshTarget resolves the address, user and key used to reach a VM.
+func (o *Operator) sshTarget(record db.VirtualMachine, user string) (ssh.Target, error) {
+	host, err := vms.Address(o.ctx, o.db, o.conn, record)
+	if err != nil {
+		return ssh.Target{}, err
+	}
+	target := ssh.Target{Host: host}
+
+	settings, err := db.GetSSHSettings(o.ctx, o.db, record.Namespace)
+	switch {
+	case err == nil:
+		target.User = settings.User
+		target.Port = settings.Port
+		target.IdentityFile = settings.IdentityFile
+		target.Options = settings.Options
+	case !errors.Is(err, sql.ErrNoRows):
+		return ssh.Target{}, err
+	}
+
+	if img, err := db.GetImage(o.ctx, o.db, record.Image); err == nil && img.DefaultUser != "" {
+		target.User = img.DefaultUser
+	}
+	if user != "" {
+		target.User = user
+	}
+	return target, nil
+}
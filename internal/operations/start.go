@@ -1,7 +1,45 @@
 package operations
 
-import "github.com/kebairia/kvmcli/internal/resources"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/resources"
+	"github.com/kebairia/kvmcli/internal/vms"
+)
 
 func (o *Operator) Start(r resources.Resource) error {
 	return r.Start()
 }
+
+// StartVM powers on a VM. A fresh boot picks up the persistent definition,
+// so any pending "restart required" flag is cleared.
+func StartVM(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	domains := vms.NewLibvirtDomainManager(operator.conn)
+	status, err := domains.State(operator.ctx, name)
+	if err != nil {
+		return err
+	}
+	if status.State == vms.StateRunning {
+		fmt.Printf("vm/%s already running\n", name)
+		return nil
+	}
+	if err := domains.Start(operator.ctx, name); err != nil {
+		return err
+	}
+	if err := clearRestartRequired(operator, name); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s started\n", name)
+	return nil
+}
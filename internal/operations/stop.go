@@ -1 +1,38 @@
 package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// stopGrace is added to the stop timeout to leave room for the final destroy.
+const stopGrace = 15 * time.Second
+
+// StopVM gracefully shuts a VM down, waiting for it to be shut off.
+func StopVM(name string, opts vms.StopOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout(opts)+stopGrace)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	domains := vms.NewLibvirtDomainManager(operator.conn)
+	if err := domains.Stop(operator.ctx, name, opts); err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s stopped\n", name)
+	return nil
+}
+
+func stopTimeout(opts vms.StopOptions) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return vms.DefaultStopTimeout
+}
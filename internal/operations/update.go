@@ -1 +1,44 @@
 package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/config"
+	"github.com/kebairia/kvmcli/internal/network"
+	"github.com/kebairia/kvmcli/internal/resources"
+)
+
+// UpdateFromManifest applies the changes of a manifest to resources that
+// already exist. confirm is asked before a network is redefined or restarted.
+func UpdateFromManifest(manifestPath string, confirm network.ConfirmFunc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create operator: %w", err)
+	}
+	defer operator.Close()
+
+	resources, err := config.Load(manifestPath, operator.ctx, operator.db, operator.conn)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest %q: %w", manifestPath, err)
+	}
+
+	for _, resource := range resources {
+		if n, ok := resource.(*network.Network); ok {
+			n.Confirm = confirm
+		}
+		if err := operator.Update(resource); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update applies configuration changes to the given Resource.
+func (o *Operator) Update(r resources.Resource) error {
+	return r.Update()
+}
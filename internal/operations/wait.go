@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kebairia/kvmcli/internal/vms"
+)
+
+// Conditions accepted by WaitVM.
+const (
+	WaitForReady   = "ready"
+	WaitForRunning = "running"
+	WaitForStopped = "stopped"
+)
+
+// WaitVM blocks until a VM meets the condition: ready (running and passing
+// its health check), running, or stopped.
+func WaitVM(name, condition string, timeout time.Duration) error {
+	switch condition {
+	case WaitForReady, WaitForRunning, WaitForStopped:
+	default:
+		return fmt.Errorf("unknown condition %q (supported: ready, running, stopped)", condition)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+stopGrace)
+	defer cancel()
+
+	operator, err := NewOperator(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize operator: %w", err)
+	}
+	defer operator.Close()
+
+	switch condition {
+	case WaitForReady:
+		err = vms.WaitReady(operator.ctx, operator.conn, operator.db, name, timeout)
+	case WaitForRunning:
+		err = vms.WaitState(operator.ctx, operator.conn, name, vms.StateRunning, timeout)
+	case WaitForStopped:
+		err = vms.WaitState(operator.ctx, operator.conn, name, vms.StateShutOff, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("vm/%s %s\n", name, condition)
+	return nil
+}
+
+// waitReady waits for the named VMs to be ready, all at once.
+func (o *Operator) waitReady(names []string, timeout time.Duration) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := vms.WaitReady(o.ctx, o.conn, o.db, name, timeout); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			fmt.Printf("vm/%s ready\n", name)
+		}(name)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -16,6 +16,8 @@ type Resource interface {
 	// Delete removes the resource from libvirt.
 	Delete() error
 	Start() error
+	// Update applies configuration changes to an existing resource.
+	Update() error
 }
 
 // Record encapsulates database persistence operations for a resource.
@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Target is everything needed to open an SSH session to a VM.
+type Target struct {
+	Host         string
+	User         string
+	Port         int
+	IdentityFile string
+	Options      []string
+}
+
+// Args returns the ssh client arguments for the target followed by the remote command.
+func (t Target) Args(command []string) []string {
+	var args []string
+	if t.Port != 0 && t.Port != 22 {
+		args = append(args, "-p", strconv.Itoa(t.Port))
+	}
+	if t.IdentityFile != "" {
+		args = append(args, "-i", expandHome(t.IdentityFile))
+	}
+	for _, opt := range t.Options {
+		args = append(args, "-o", opt)
+	}
+
+	host := t.Host
+	if t.User != "" {
+		host = t.User + "@" + t.Host
+	}
+	args = append(args, host)
+	return append(args, command...)
+}
+
+// Command builds an ssh client process attached to the current terminal.
+func (t Target) Command(ctx context.Context, command []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "ssh", t.Args(command)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
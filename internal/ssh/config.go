@@ -0,0 +1,15 @@
+package ssh
+
+// Config describes the SSH defaults of a namespace:
+//
+//	ssh "homelab" {
+//	  user          = "admin"
+//	  identity_file = "~/.ssh/homelab_ed25519"
+//	}
+type Config struct {
+	Namespace    string   `hcl:"namespace,label"`
+	User         string   `hcl:"user,optional"`
+	IdentityFile string   `hcl:"identity_file,optional"`
+	Port         int      `hcl:"port,optional"`
+	Options      []string `hcl:"options,optional"` // extra -o options, e.g. "StrictHostKeyChecking=accept-new"
+}
@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// SettingsManager defines the interface for managing per-namespace SSH settings.
+type SettingsManager interface {
+	Create(ctx context.Context, spec Config) error
+	Update(ctx context.Context, spec Config) error
+	Delete(ctx context.Context, namespace string) error
+	Get(ctx context.Context, namespace string) (*db.SSHSettings, error)
+}
+
+// DBSettingsManager implements SettingsManager using a SQL database.
+type DBSettingsManager struct {
+	db *sql.DB
+}
+
+// NewDBSettingsManager creates a new DBSettingsManager.
+func NewDBSettingsManager(db *sql.DB) *DBSettingsManager {
+	return &DBSettingsManager{db: db}
+}
+
+// Create persists the SSH settings of a namespace.
+func (m *DBSettingsManager) Create(ctx context.Context, spec Config) error {
+	record := NewSettingsRecord(spec)
+	if err := record.Insert(ctx, m.db); err != nil {
+		return fmt.Errorf("failed to insert ssh settings: %w", err)
+	}
+	fmt.Printf("ssh/%s created\n", spec.Namespace)
+	return nil
+}
+
+// Update replaces the SSH settings of a namespace.
+func (m *DBSettingsManager) Update(ctx context.Context, spec Config) error {
+	record := NewSettingsRecord(spec)
+	if err := record.Insert(ctx, m.db); err != nil {
+		return fmt.Errorf("failed to insert ssh settings: %w", err)
+	}
+	fmt.Printf("ssh/%s updated\n", spec.Namespace)
+	return nil
+}
+
+// Delete removes the SSH settings of a namespace.
+func (m *DBSettingsManager) Delete(ctx context.Context, namespace string) error {
+	record := &db.SSHSettings{Namespace: namespace}
+	if err := record.Delete(ctx, m.db); err != nil {
+		return err
+	}
+	fmt.Printf("ssh/%s deleted\n", namespace)
+	return nil
+}
+
+// Get retrieves the SSH settings of a namespace.
+func (m *DBSettingsManager) Get(ctx context.Context, namespace string) (*db.SSHSettings, error) {
+	s, err := db.GetSSHSettings(ctx, m.db, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// NewSettingsRecord creates a database record from the SSH configuration.
+func NewSettingsRecord(spec Config) *db.SSHSettings {
+	return &db.SSHSettings{
+		Namespace:    spec.Namespace,
+		User:         spec.User,
+		IdentityFile: spec.IdentityFile,
+		Port:         spec.Port,
+		Options:      spec.Options,
+		CreatedAt:    time.Now(),
+	}
+}
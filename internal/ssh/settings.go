@@ -0,0 +1,44 @@
+// Package ssh resolves how to reach a VM over SSH and runs the ssh client.
+package ssh
+
+import "context"
+
+// Settings represents a bound SSH settings resource (configuration + manager).
+// It implements resources.Resource.
+type Settings struct {
+	Spec    Config
+	ctx     context.Context
+	manager SettingsManager
+}
+
+// NewSettings creates a new Settings resource.
+func NewSettings(spec Config, manager SettingsManager, ctx context.Context) *Settings {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Settings{
+		Spec:    spec,
+		manager: manager,
+		ctx:     ctx,
+	}
+}
+
+// Create delegates to the manager.
+func (s *Settings) Create() error {
+	return s.manager.Create(s.ctx, s.Spec)
+}
+
+// Delete delegates to the manager.
+func (s *Settings) Delete() error {
+	return s.manager.Delete(s.ctx, s.Spec.Namespace)
+}
+
+// Update delegates to the manager.
+func (s *Settings) Update() error {
+	return s.manager.Update(s.ctx, s.Spec)
+}
+
+// Start does nothing, settings have no runtime state.
+func (s *Settings) Start() error {
+	return nil
+}
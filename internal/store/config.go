@@ -47,4 +47,5 @@ type Image struct {
 	File      string `hcl:"file,optional"`
 	Size      string `hcl:"size,optional"`
 	Checksum  string `hcl:"checksum,optional"`
+	User      string `hcl:"user,optional"` // default SSH user for VMs using this image
 }
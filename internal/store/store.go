@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	db "github.com/kebairia/kvmcli/internal/database"
@@ -60,6 +61,12 @@ func (s *Store) Start() error {
 	return nil
 }
 
+// Update leaves the store as is; stores cannot be updated yet.
+func (s *Store) Update() error {
+	fmt.Printf("store/%s unchanged\n", s.Spec.Name)
+	return nil
+}
+
 // func (st *Store) Header() *tabwriter.Writer {
 // 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 // 	// Columns: store name, namespace, backend, artifacts path,
@@ -98,6 +105,7 @@ func NewStoreRecord(spec Config) *db.Store {
 			File:      img.File,
 			Checksum:  img.Checksum,
 			Size:      img.Size,
+			User:      img.User,
 		}
 	}
 
@@ -0,0 +1,54 @@
+package templates
+
+import (
+	"encoding/xml"
+)
+
+// DomainBackup represents the <domainbackup> element passed to DomainBackupBegin.
+type DomainBackup struct {
+	XMLName xml.Name    `xml:"domainbackup"`
+	Mode    string      `xml:"mode,attr"`
+	Disks   BackupDisks `xml:"disks"`
+}
+
+// BackupDisks lists the disks included in a backup job.
+type BackupDisks struct {
+	Disks []BackupDisk `xml:"disk"`
+}
+
+// BackupDisk describes where a single disk is copied to.
+type BackupDisk struct {
+	Name   string       `xml:"name,attr"`
+	Backup string       `xml:"backup,attr"`
+	Type   string       `xml:"type,attr"`
+	Driver DiskDriver   `xml:"driver"`
+	Target BackupTarget `xml:"target"`
+}
+
+// BackupTarget is the file the disk contents are pushed into.
+type BackupTarget struct {
+	File string `xml:"file,attr"`
+}
+
+// NewPushBackup builds a full push-mode backup of one disk into a standalone qcow2 file.
+func NewPushBackup(disk, target string) *DomainBackup {
+	return &DomainBackup{
+		Mode: "push",
+		Disks: BackupDisks{
+			Disks: []BackupDisk{
+				{
+					Name:   disk,
+					Backup: "yes",
+					Type:   DiskTypeFile,
+					Driver: DiskDriver{Type: DiskFormatQCOW2},
+					Target: BackupTarget{File: target},
+				},
+			},
+		},
+	}
+}
+
+// GenerateXML returns the XML representation of the DomainBackup.
+func (b *DomainBackup) GenerateXML() ([]byte, error) {
+	return xml.MarshalIndent(b, "", "  ")
+}
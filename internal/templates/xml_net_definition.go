@@ -5,31 +5,108 @@ import (
 )
 
 // Network represents the overall XML structure
-// The Bridge/Forward field is a pointer, so if it's nil, it won't be included in the XML
+// The Bridge/Forward fields are pointers, so if they're nil, they won't be included in the XML
 type Network struct {
-	XMLName xml.Name `xml:"network"`
-	Name    string   `xml:"name"`
-	Bridge  *Bridge  `xml:"bridge,omitempty"`
-	Forward *Forward `xml:"forward,omitempty"`
-	IP      IP       `xml:"ip"`
+	XMLName xml.Name   `xml:"network"`
+	Name    string     `xml:"name"`
+	UUID    string     `xml:"uuid,omitempty"` // kept when an existing network is redefined
+	Bridge  *Bridge    `xml:"bridge,omitempty"`
+	Forward *Forward   `xml:"forward,omitempty"`
+	Domain  *DNSDomain `xml:"domain,omitempty"`
+	DNS     *DNS       `xml:"dns,omitempty"`
+	// IPs holds one <ip> element per address, the primary one first.
+	IPs []IP `xml:"ip"`
 }
 
 // Bridge represents the <bridge> element.
 type Bridge struct {
 	Name string `xml:"name,attr"`
 }
+
+// Forward represents the <forward> element. A network without one is isolated.
 type Forward struct {
-	Mode string `xml:"mode,attr"`
+	Mode       string             `xml:"mode,attr"`
+	Dev        string             `xml:"dev,attr,omitempty"`
+	Managed    string             `xml:"managed,attr,omitempty"`
+	NAT        *NAT               `xml:"nat,omitempty"`
+	Interfaces []ForwardInterface `xml:"interface,omitempty"`
+	PF         *ForwardInterface  `xml:"pf,omitempty"`
+}
+
+// ForwardInterface is a host interface of a forward pool (<interface>) or
+// the SR-IOV physical function of a hostdev network (<pf>).
+type ForwardInterface struct {
+	Dev string `xml:"dev,attr"`
+}
+
+// NAT represents the <nat> element of a nat forward.
+type NAT struct {
+	Address *Range `xml:"address,omitempty"`
+	Port    *Range `xml:"port,omitempty"`
+}
+
+// DNSDomain represents the <domain> element: the DNS domain of the network.
+type DNSDomain struct {
+	Name      string `xml:"name,attr"`
+	LocalOnly string `xml:"localOnly,attr,omitempty"`
+}
+
+// DNS represents the <dns> element.
+type DNS struct {
+	Forwarders []DNSForwarder `xml:"forwarder"`
+	TXT        []DNSTXT       `xml:"txt"`
+	Hosts      []DNSHost      `xml:"host"`
+	SRV        []DNSSRV       `xml:"srv"`
+}
+
+type DNSForwarder struct {
+	Addr string `xml:"addr,attr"`
+}
+
+type DNSTXT struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// DNSHost is a DNS host record, also the XML NetworkUpdate takes to add or
+// delete one.
+type DNSHost struct {
+	XMLName   xml.Name `xml:"host"`
+	IP        string   `xml:"ip,attr"`
+	Hostnames []string `xml:"hostname"`
 }
+
+type DNSSRV struct {
+	Service  string `xml:"service,attr"`
+	Protocol string `xml:"protocol,attr"`
+	Domain   string `xml:"domain,attr,omitempty"`
+	Target   string `xml:"target,attr,omitempty"`
+	Port     int    `xml:"port,attr,omitempty"`
+	Priority int    `xml:"priority,attr,omitempty"`
+	Weight   int    `xml:"weight,attr,omitempty"`
+}
+
 type IP struct {
+	Family  string `xml:"family,attr,omitempty"`
 	Address string `xml:"address,attr"`
-	Netmask string `xml:"netmask,attr"`
+	Netmask string `xml:"netmask,attr,omitempty"`
+	Prefix  int    `xml:"prefix,attr,omitempty"`
 	// DHCP is omitted if nil.
 	DHCP *DHCP `xml:"dhcp,omitempty"`
 }
 
 type DHCP struct {
-	Range Range `xml:"range"`
+	Range *Range     `xml:"range,omitempty"`
+	Hosts []DHCPHost `xml:"host"`
+}
+
+// DHCPHost is a static host entry. IPv4 entries match on mac, IPv6 entries
+// on id (the client DUID) or name.
+type DHCPHost struct {
+	MAC  string `xml:"mac,attr,omitempty"`
+	ID   string `xml:"id,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty"`
+	IP   string `xml:"ip,attr"`
 }
 
 type Range struct {
@@ -53,8 +130,11 @@ func WithBridge(bridgeName string) NetworkOption {
 
 func WithDHCP(start, end string) NetworkOption {
 	return func(n *Network) {
-		n.IP.DHCP = &DHCP{
-			Range: Range{
+		if len(n.IPs) == 0 {
+			return
+		}
+		n.IPs[0].DHCP = &DHCP{
+			Range: &Range{
 				Start: start,
 				End:   end,
 			},
@@ -62,9 +142,96 @@ func WithDHCP(start, end string) NetworkOption {
 	}
 }
 
+// WithIPv6 is an option that addresses the network over IPv6 with the given
+// prefix length instead of an IPv4 netmask.
+func WithIPv6(prefix int) NetworkOption {
+	return func(n *Network) {
+		if len(n.IPs) == 0 {
+			return
+		}
+		n.IPs[0].Family = "ipv6"
+		n.IPs[0].Netmask = ""
+		n.IPs[0].Prefix = prefix
+	}
+}
+
+// WithIP is an option that adds an address to the network, such as the
+// IPv6 side of a dual-stack network.
+func WithIP(ip IP) NetworkOption {
+	return func(n *Network) {
+		n.IPs = append(n.IPs, ip)
+	}
+}
+
+// WithForwardDev is an option that forwards the traffic of the network
+// through the given host interface.
+func WithForwardDev(dev string) NetworkOption {
+	return func(n *Network) {
+		if n.Forward != nil {
+			n.Forward.Dev = dev
+		}
+	}
+}
+
+// WithNAT is an option that limits the public addresses and ports a nat
+// forward translates to. Empty bounds leave libvirt's defaults.
+func WithNAT(addressStart, addressEnd, portStart, portEnd string) NetworkOption {
+	return func(n *Network) {
+		if n.Forward == nil {
+			return
+		}
+		nat := &NAT{}
+		if addressStart != "" {
+			nat.Address = &Range{Start: addressStart, End: addressEnd}
+		}
+		if portStart != "" {
+			nat.Port = &Range{Start: portStart, End: portEnd}
+		}
+		n.Forward.NAT = nat
+	}
+}
+
+// WithForwardInterfaces is an option that sets the pool of host interfaces
+// guests are attached to with macvtap or passthrough.
+func WithForwardInterfaces(devs ...string) NetworkOption {
+	return func(n *Network) {
+		if n.Forward == nil {
+			return
+		}
+		for _, dev := range devs {
+			n.Forward.Interfaces = append(n.Forward.Interfaces, ForwardInterface{Dev: dev})
+		}
+	}
+}
+
+// WithHostdevPF is an option that hands guests the virtual functions of the
+// given SR-IOV physical function.
+func WithHostdevPF(dev string) NetworkOption {
+	return func(n *Network) {
+		if n.Forward == nil {
+			return
+		}
+		n.Forward.Managed = "yes"
+		n.Forward.PF = &ForwardInterface{Dev: dev}
+	}
+}
+
+// WithDNS is an option that sets the DNS domain of the network, answered
+// locally, and its DNS records. An empty domain leaves <domain> out.
+func WithDNS(domain string, dns DNS) NetworkOption {
+	return func(n *Network) {
+		if domain != "" {
+			n.Domain = &DNSDomain{Name: domain, LocalOnly: "yes"}
+		}
+		n.DNS = &dns
+	}
+}
+
 // NewNetwork is the constructor that creates a new Network instance.
 // it takes required parameters: name, forwardMode, ipAddress, and netmask.
 // Addtional optional configurations can be provided using variadic options.
+// An empty forwardMode makes an isolated network and an empty ipAddress a
+// network without a primary <ip> element.
 
 func NewNetwork(
 	name, forwardMode, ipAddress, netmask string,
@@ -72,16 +239,16 @@ func NewNetwork(
 	opts ...NetworkOption,
 ) *Network {
 	// Create the network with required fields.
-	network := &Network{
-		Name: name,
-		Forward: &Forward{
-			Mode: forwardMode,
-		},
+	network := &Network{Name: name}
+	if forwardMode != "" {
+		network.Forward = &Forward{Mode: forwardMode}
+	}
+	if ipAddress != "" {
 		// DHCP is nil by default, meaning it will be omitted unless enabled.
-		IP: IP{
+		network.IPs = []IP{{
 			Address: ipAddress,
 			Netmask: netmask,
-		},
+		}}
 	}
 	for _, opt := range opts {
 		opt(network)
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"encoding/xml"
+)
+
+// Snapshot modes accepted by libvirt for a <disk> element.
+const (
+	SnapshotInternal = "internal"
+	SnapshotExternal = "external"
+)
+
+// DomainSnapshot represents the <domainsnapshot> element passed to DomainSnapshotCreateXML.
+type DomainSnapshot struct {
+	XMLName     xml.Name        `xml:"domainsnapshot"`
+	Name        string          `xml:"name"`
+	Description string          `xml:"description,omitempty"`
+	Disks       *SnapshotDisks  `xml:"disks,omitempty"`
+	Memory      *SnapshotMemory `xml:"memory,omitempty"`
+}
+
+// SnapshotDisks lists per-disk snapshot settings.
+type SnapshotDisks struct {
+	Disks []SnapshotDisk `xml:"disk"`
+}
+
+// SnapshotDisk describes how a single disk is captured.
+type SnapshotDisk struct {
+	Name     string              `xml:"name,attr"`
+	Snapshot string              `xml:"snapshot,attr"`
+	Driver   *SnapshotDiskDriver `xml:"driver,omitempty"`
+	Source   *DiskSource         `xml:"source,omitempty"`
+}
+
+// SnapshotDiskDriver sets the format of an external overlay.
+type SnapshotDiskDriver struct {
+	Type string `xml:"type,attr"`
+}
+
+// SnapshotMemory controls whether guest memory is saved.
+type SnapshotMemory struct {
+	Snapshot string `xml:"snapshot,attr"`
+}
+
+// NewInternalSnapshot builds a snapshot stored inside the qcow2 image of the domain.
+func NewInternalSnapshot(name, description string) *DomainSnapshot {
+	return &DomainSnapshot{
+		Name:        name,
+		Description: description,
+	}
+}
+
+// NewExternalSnapshot builds a disk-only snapshot: the current disk becomes
+// read-only and new writes go to a qcow2 overlay at overlayPath.
+func NewExternalSnapshot(name, description, disk, overlayPath string) *DomainSnapshot {
+	return &DomainSnapshot{
+		Name:        name,
+		Description: description,
+		Memory:      &SnapshotMemory{Snapshot: "no"},
+		Disks: &SnapshotDisks{
+			Disks: []SnapshotDisk{
+				{
+					Name:     disk,
+					Snapshot: SnapshotExternal,
+					Driver:   &SnapshotDiskDriver{Type: DiskFormatQCOW2},
+					Source:   &DiskSource{File: overlayPath},
+				},
+			},
+		},
+	}
+}
+
+// GenerateXML returns the XML representation of the DomainSnapshot.
+func (s *DomainSnapshot) GenerateXML() ([]byte, error) {
+	return xml.MarshalIndent(s, "", "  ")
+}
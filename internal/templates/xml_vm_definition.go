@@ -18,20 +18,24 @@ const (
 	VirtIO          = "virtio"
 	NetTypeNetwork  = "network"
 	GraphicsTypeVNC = "vnc"
+	// GuestAgentChannel is the virtio-serial port qemu-guest-agent listens on.
+	GuestAgentChannel = "org.qemu.guest_agent.0"
 )
 
 // Domain represents the root domain element
 type Domain struct {
-	XMLName  xml.Name `xml:"domain"`
-	Type     string   `xml:"type,attr"`
-	Name     string   `xml:"name"`
-	Metadata Metadata `xml:"metadata"`
-	Memory   Memory   `xml:"memory"`
-	VCPU     VCPU     `xml:"vcpu"`
-	OS       OS       `xml:"os"`
-	Features Features `xml:"features"`
-	CPU      CPU      `xml:"cpu"`
-	Devices  Devices  `xml:"devices"`
+	XMLName       xml.Name `xml:"domain"`
+	Type          string   `xml:"type,attr"`
+	Name          string   `xml:"name"`
+	UUID          string   `xml:"uuid,omitempty"` // libvirt generates one when empty
+	Metadata      Metadata `xml:"metadata"`
+	Memory        Memory   `xml:"memory"`                  // maximum memory
+	CurrentMemory *Memory  `xml:"currentMemory,omitempty"` // set when below the maximum
+	VCPU          VCPU     `xml:"vcpu"`
+	OS            OS       `xml:"os"`
+	Features      Features `xml:"features"`
+	CPU           CPU      `xml:"cpu"`
+	Devices       Devices  `xml:"devices"`
 }
 
 // Metadata holds guest metadata information
@@ -61,9 +65,23 @@ type Memory struct {
 // VCPU defines the vCPU configuration
 type VCPU struct {
 	Placement string `xml:"placement,attr"`
+	Current   int    `xml:"current,attr,omitempty"` // online vCPUs when below the maximum
 	Value     int    `xml:",chardata"`
 }
 
+// SetMaximums raises the memory (MiB) and vCPU maximums above the current
+// allocation so both can later be grown on the running domain.
+func (d *Domain) SetMaximums(maxCPU, maxMemory int) {
+	if maxCPU > d.VCPU.Value {
+		d.VCPU.Current = d.VCPU.Value
+		d.VCPU.Value = maxCPU
+	}
+	if maxMemory > d.Memory.Value {
+		d.CurrentMemory = &Memory{Unit: d.Memory.Unit, Value: d.Memory.Value}
+		d.Memory.Value = maxMemory
+	}
+}
+
 // OS represents the OS configuration
 type OS struct {
 	Type OSType `xml:"type"`
@@ -107,7 +125,7 @@ type Devices struct {
 	Controllers []Controller `xml:"controller"`
 	Disk        Disk         `xml:"disk"`
 	Interface   Interface    `xml:"interface"`
-	Channel     Channel      `xml:"channel"`
+	Channels    []Channel    `xml:"channel"`
 	Serial      Serial       `xml:"serial"`
 	Console     Console      `xml:"console"`
 	Graphics    Graphics     `xml:"graphics"`
@@ -133,7 +151,7 @@ type Disk struct {
 
 // DiskDriver represents the disk driver configuration
 type DiskDriver struct {
-	Name string `xml:"name,attr"`
+	Name string `xml:"name,attr,omitempty"`
 	Type string `xml:"type,attr"`
 }
 
@@ -171,14 +189,14 @@ type NetModel struct {
 	Type string `xml:"type,attr"`
 }
 
-// Channel represents the SPICE channel
+// Channel represents a virtio-serial channel (SPICE, guest agent)
 type Channel struct {
-	Type    string        `xml:"type,attr"`
-	Target  ChannelTarget `xml:"target"`
-	Address VirtioAddress `xml:"address"`
+	Type    string         `xml:"type,attr"`
+	Target  ChannelTarget  `xml:"target"`
+	Address *VirtioAddress `xml:"address,omitempty"` // libvirt assigns one when nil
 }
 
-// ChannelTarget represents the target for a SPICE channel
+// ChannelTarget represents the target for a channel
 type ChannelTarget struct {
 	Type string `xml:"type,attr"`
 	Name string `xml:"name,attr"`
@@ -317,17 +335,27 @@ func NewDomain(
 					Type: VirtIO,
 				},
 			},
-			Channel: Channel{
-				Type: "spicevmc",
-				Target: ChannelTarget{
-					Type: "virtio",
-					Name: "com.redhat.spice.0",
+			Channels: []Channel{
+				{
+					Type: "spicevmc",
+					Target: ChannelTarget{
+						Type: "virtio",
+						Name: "com.redhat.spice.0",
+					},
+					Address: &VirtioAddress{
+						Type:       "virtio-serial",
+						Controller: "0",
+						Bus:        "0",
+						Port:       "2",
+					},
 				},
-				Address: VirtioAddress{
-					Type:       "virtio-serial",
-					Controller: "0",
-					Bus:        "0",
-					Port:       "2",
+				{
+					// libvirt creates the host side socket for the QEMU guest agent.
+					Type: "unix",
+					Target: ChannelTarget{
+						Type: "virtio",
+						Name: GuestAgentChannel,
+					},
 				},
 			},
 			Serial: Serial{
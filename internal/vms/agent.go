@@ -0,0 +1,233 @@
+package vms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+const (
+	// defaultAgentTimeout bounds a single guest agent command, in seconds.
+	defaultAgentTimeout = 5
+	execPollInterval    = 200 * time.Millisecond
+)
+
+// ErrNoGuestAgent is returned when the guest agent of a domain does not answer.
+var ErrNoGuestAgent = errors.New("qemu guest agent is not available")
+
+// GuestAgent talks to qemu-guest-agent inside a running domain through
+// libvirt's QEMUDomainAgentCommand.
+type GuestAgent struct {
+	conn    *libvirt.Libvirt
+	dom     libvirt.Domain
+	Timeout int32 // per-command timeout in seconds
+}
+
+// GuestInterface is a network interface as seen by the guest.
+type GuestInterface struct {
+	Name string
+	MAC  string
+	IPs  []string // addresses with their prefix, e.g. 10.10.10.2/24
+}
+
+// GuestOSInfo describes the guest operating system.
+type GuestOSInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty-name"`
+	Version       string `json:"version"`
+	VersionID     string `json:"version-id"`
+	KernelRelease string `json:"kernel-release"`
+	Machine       string `json:"machine"`
+}
+
+// ExecResult holds the outcome of a command run by the guest agent.
+type ExecResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// NewGuestAgent returns a GuestAgent for the named domain.
+func NewGuestAgent(conn *libvirt.Libvirt, name string) (*GuestAgent, error) {
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	return &GuestAgent{conn: conn, dom: dom, Timeout: defaultAgentTimeout}, nil
+}
+
+// Ping checks that the guest agent is up and answering.
+func (a *GuestAgent) Ping() error {
+	if err := a.run("guest-ping", nil, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrNoGuestAgent, err)
+	}
+	return nil
+}
+
+// Interfaces returns the network interfaces of the guest and their addresses.
+func (a *GuestAgent) Interfaces() ([]GuestInterface, error) {
+	var raw []struct {
+		Name        string `json:"name"`
+		MAC         string `json:"hardware-address"`
+		IPAddresses []struct {
+			Type    string `json:"ip-address-type"`
+			Address string `json:"ip-address"`
+			Prefix  int    `json:"prefix"`
+		} `json:"ip-addresses"`
+	}
+	if err := a.run("guest-network-get-interfaces", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	ifaces := make([]GuestInterface, 0, len(raw))
+	for _, r := range raw {
+		iface := GuestInterface{Name: r.Name, MAC: r.MAC}
+		for _, ip := range r.IPAddresses {
+			iface.IPs = append(iface.IPs, fmt.Sprintf("%s/%d", ip.Address, ip.Prefix))
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, nil
+}
+
+// IPv4Addresses returns the IPv4 addresses of the guest, loopback excluded.
+func (a *GuestAgent) IPv4Addresses() ([]string, error) {
+	ifaces, err := a.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, iface := range ifaces {
+		for _, cidr := range iface.IPs {
+			ip, _, err := net.ParseCIDR(cidr)
+			if err != nil || ip.To4() == nil || ip.IsLoopback() {
+				continue
+			}
+			ips = append(ips, ip.String())
+		}
+	}
+	return ips, nil
+}
+
+// OSInfo returns the operating system reported by the guest.
+func (a *GuestAgent) OSInfo() (GuestOSInfo, error) {
+	var info GuestOSInfo
+	err := a.run("guest-get-osinfo", nil, &info)
+	return info, err
+}
+
+// FSFreeze flushes and freezes the guest file systems, returning how many were frozen.
+// Every successful call must be followed by FSThaw.
+func (a *GuestAgent) FSFreeze() (int, error) {
+	var frozen int
+	err := a.run("guest-fsfreeze-freeze", nil, &frozen)
+	return frozen, err
+}
+
+// FSThaw thaws the guest file systems, returning how many were thawed.
+func (a *GuestAgent) FSThaw() (int, error) {
+	var thawed int
+	err := a.run("guest-fsfreeze-thaw", nil, &thawed)
+	return thawed, err
+}
+
+// SetPassword sets the password of a guest user.
+func (a *GuestAgent) SetPassword(user, password string) error {
+	args := map[string]any{
+		"username": user,
+		"password": base64.StdEncoding.EncodeToString([]byte(password)),
+		"crypted":  false,
+	}
+	return a.run("guest-set-user-password", args, nil)
+}
+
+// Exec runs a command in the guest and waits for it, capturing its output.
+func (a *GuestAgent) Exec(ctx context.Context, command []string) (ExecResult, error) {
+	if len(command) == 0 {
+		return ExecResult{}, fmt.Errorf("no command given")
+	}
+	args := map[string]any{
+		"path":           command[0],
+		"arg":            command[1:],
+		"capture-output": true,
+	}
+	var started struct {
+		PID int `json:"pid"`
+	}
+	if err := a.run("guest-exec", args, &started); err != nil {
+		return ExecResult{}, err
+	}
+
+	ticker := time.NewTicker(execPollInterval)
+	defer ticker.Stop()
+	for {
+		var status struct {
+			Exited   bool   `json:"exited"`
+			ExitCode int    `json:"exitcode"`
+			Signal   int    `json:"signal"`
+			OutData  string `json:"out-data"`
+			ErrData  string `json:"err-data"`
+		}
+		if err := a.run("guest-exec-status", map[string]any{"pid": started.PID}, &status); err != nil {
+			return ExecResult{}, err
+		}
+		if status.Exited {
+			result := ExecResult{ExitCode: status.ExitCode}
+			if status.Signal != 0 {
+				result.ExitCode = 128 + status.Signal
+			}
+			var err error
+			if result.Stdout, err = base64.StdEncoding.DecodeString(status.OutData); err != nil {
+				return result, fmt.Errorf("decode stdout: %w", err)
+			}
+			if result.Stderr, err = base64.StdEncoding.DecodeString(status.ErrData); err != nil {
+				return result, fmt.Errorf("decode stderr: %w", err)
+			}
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ExecResult{}, fmt.Errorf("exec in %q: %w", a.dom.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// run sends a guest agent command and decodes its "return" value into result.
+func (a *GuestAgent) run(command string, args any, result any) error {
+	request := map[string]any{"execute": command}
+	if args != nil {
+		request["arguments"] = args
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", command, err)
+	}
+
+	out, err := a.conn.QEMUDomainAgentCommand(a.dom, string(payload), a.Timeout, 0)
+	if err != nil {
+		return fmt.Errorf("%s on %q: %w", command, a.dom.Name, err)
+	}
+	if result == nil || len(out) == 0 {
+		return nil
+	}
+
+	var response struct {
+		Return json.RawMessage `json:"return"`
+	}
+	if err := json.Unmarshal([]byte(out[0]), &response); err != nil {
+		return fmt.Errorf("decode %s reply: %w", command, err)
+	}
+	if err := json.Unmarshal(response.Return, result); err != nil {
+		return fmt.Errorf("decode %s reply: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package vms
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/network"
+	"github.com/kebairia/kvmcli/internal/templates"
+)
+
+// CloneOptions describes the identity given to a cloned VM.
+type CloneOptions struct {
+	IP     string // address for the clone; allocated by IPAM when empty
+	Linked bool   // fresh overlay on the source's base image instead of copying its disk
+	Start  bool   // power on the clone once it is defined
+}
+
+// Clone duplicates the VM src as dst with a new name, UUID, MAC and IP.
+// A full clone copies the source disk and requires the source to be shut off;
+// a linked clone only creates a new overlay on the same base image.
+func Clone(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	src, dst string,
+	opts CloneOptions,
+) (*db.VirtualMachine, error) {
+	var source db.VirtualMachine
+	if err := source.GetRecord(ctx, database, src); err != nil {
+		return nil, err
+	}
+	var existing db.VirtualMachine
+	if err := existing.GetRecord(ctx, database, dst); err == nil {
+		return nil, fmt.Errorf("vm %q already exists", dst)
+	}
+	if _, err := conn.DomainLookupByName(dst); err == nil {
+		return nil, fmt.Errorf("domain %q already exists in libvirt", dst)
+	}
+
+	dom, err := conn.DomainLookupByName(src)
+	if err != nil {
+		return nil, fmt.Errorf("lookup domain %q: %w", src, err)
+	}
+	if !opts.Linked {
+		active, err := conn.DomainIsActive(dom)
+		if err != nil {
+			return nil, fmt.Errorf("get state for %q: %w", src, err)
+		}
+		if active == 1 {
+			return nil, fmt.Errorf("vm %q is running; stop it first or use a linked clone", src)
+		}
+	}
+
+	img, err := db.GetImage(ctx, database, source.Image)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image %q: %w", source.Image, err)
+	}
+	networkName, err := db.GetNetworkNameByID(ctx, database, source.NetworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cleanups []func() error
+	rollback := func(step string, origin error) (*db.VirtualMachine, error) {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if err := cleanups[i](); err != nil {
+				log.Warnf("rollback failed, step %s, err %s", step, err)
+			}
+		}
+		return nil, fmt.Errorf("failed at %s: %w", step, origin)
+	}
+
+	// Networks without a subnet leave the clone on a dynamic lease.
+	ipam := network.NewIPAM(conn, database)
+	ip := opts.IP
+	if ip == "" {
+		var inserted bool
+		ip, inserted, err = ipam.Allocate(ctx, networkName, dst)
+		if errors.Is(err, network.ErrNoSubnet) {
+			ip, err = "", nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("allocate ip for %q: %w", dst, err)
+		}
+		if inserted {
+			cleanups = append(cleanups, func() error { return ipam.Release(ctx, networkName, dst) })
+		}
+	}
+	mac, err := network.NewMACAllocator(conn, database).Allocate(ctx, networkName, ip)
+	if err != nil {
+		return rollback("allocate mac", err)
+	}
+	if opts.IP != "" {
+		inserted, err := ipam.Reserve(ctx, networkName, dst, ip, mac)
+		if err != nil {
+			return nil, fmt.Errorf("reserve ip for %q: %w", dst, err)
+		}
+		if inserted {
+			cleanups = append(cleanups, func() error { return ipam.Release(ctx, networkName, dst) })
+		}
+	}
+
+	disk := &QemuDiskManager{}
+	diskPath := filepath.Join(filepath.Dir(source.DiskPath), dst+".qcow2")
+	if opts.Linked {
+		err = disk.CreateOverlay(ctx, filepath.Join(img.ArtifactsPath, img.ImageFile), diskPath)
+	} else {
+		err = disk.Copy(ctx, source.DiskPath, diskPath)
+	}
+	if err != nil {
+		return rollback("create disk", err)
+	}
+	cleanups = append(cleanups, func() error { return disk.DeleteOverlay(ctx, diskPath) })
+
+	domain := templates.NewDomain(dst, source.RAM, source.CPU, diskPath, networkName, mac, img.OsProfile)
+	domain.UUID = newUUID()
+	xmlConfig, err := domain.GenerateXML()
+	if err != nil {
+		return rollback("build XML", err)
+	}
+
+	domains := NewLibvirtDomainManager(conn)
+	if err := domains.Define(ctx, xml.Header+string(xmlConfig)); err != nil {
+		return rollback("define domain", err)
+	}
+	cleanups = append(cleanups, func() error { return domains.Undefine(ctx, dst) })
+	if source.Autostart {
+		if err := domains.SetAutostart(ctx, dst, true); err != nil {
+			return rollback("enable autostart", err)
+		}
+	}
+
+	if ip != "" {
+		nm := network.NewLibvirtNetworkManager(conn, database)
+		if err := nm.SetStaticMapping(ctx, networkName, ip, mac, dst); err != nil {
+			return rollback("add static ip mapping", err)
+		}
+		cleanups = append(cleanups, func() error { return nm.RemoveStaticMapping(ctx, networkName, ip, dst) })
+	}
+
+	clone := source
+	clone.ID = 0
+	clone.Name = dst
+	clone.IP = ip
+	clone.IPv6 = ""
+	clone.MacAddress = mac
+	clone.DiskPath = diskPath
+	clone.CreatedAt = time.Now()
+	if err := clone.Insert(ctx, database); err != nil {
+		return rollback("insert record", err)
+	}
+
+	if opts.Start {
+		if err := domains.Start(ctx, dst); err != nil {
+			return &clone, fmt.Errorf("vm %q cloned but failed to start: %w", dst, err)
+		}
+	}
+	return &clone, nil
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -11,12 +11,17 @@ type Config struct {
 	Image     string            `hcl:"image"`
 	CPU       int               `hcl:"cpu"`
 	Memory    int               `hcl:"memory"`
+	MaxCPU    int               `hcl:"max_cpu,optional"`    // hot-plug ceiling, defaults to cpu
+	MaxMemory int               `hcl:"max_memory,optional"` // balloon ceiling in MiB, defaults to memory
 	Disk      string            `hcl:"disk,optional"`
 	NetExpr   hcl.Expression    `hcl:"network,attr"` // raw HCL expression, e.g. network.homelab
 	NetName   string            // resolved network name; filled by ResolveReferences
 	StoreExpr hcl.Expression    `hcl:"store,attr"`
 	Store     string            // resolved store name; filled by ResolveReferences
 	MAC       string            `hcl:"mac,optional"`
-	IP        string            `hcl:"ip,optional"`
+	IP        string            `hcl:"ip,optional"`        // static address, or "auto" to let IPAM pick one
+	IPv6      string            `hcl:"ipv6,optional"`      // static IPv6 address on a dual-stack or IPv6 network
+	Autostart bool              `hcl:"autostart,optional"` // start along with the host
 	Labels    map[string]string `hcl:"labels,optional"`
+	Health    *HealthCheck      `hcl:"healthcheck,block"` // readiness check, see HealthCheck
 }
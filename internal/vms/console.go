@@ -0,0 +1,218 @@
+package vms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"golang.org/x/term"
+)
+
+// DefaultEscapeChar is Ctrl+], the same detach key used by virsh console.
+const DefaultEscapeChar byte = 0x1d
+
+// ErrConsoleDetached is returned when the user leaves the console with the escape sequence.
+var ErrConsoleDetached = errors.New("console detached")
+
+// ConsoleOptions controls an interactive serial console session.
+type ConsoleOptions struct {
+	Device     string    // console alias (e.g. "serial0"); empty selects the primary console
+	EscapeChar byte      // byte that detaches the session; defaults to DefaultEscapeChar
+	Record     io.Writer // optional sink receiving a copy of everything the guest prints
+	Force      bool      // take over a console already opened by another client
+}
+
+// ParseEscapeSequence converts caret notation ("^]", "^A") or a single
+// character into the byte that detaches a console session.
+func ParseEscapeSequence(seq string) (byte, error) {
+	switch {
+	case seq == "":
+		return DefaultEscapeChar, nil
+	case seq == "^?":
+		return 0x7f, nil
+	case len(seq) == 2 && seq[0] == '^':
+		return seq[1] & 0x1f, nil
+	case len(seq) == 1:
+		return seq[0], nil
+	default:
+		return 0, fmt.Errorf("invalid escape sequence %q (use caret notation, e.g. ^])", seq)
+	}
+}
+
+// OpenConsole attaches the terminal behind in/out to the serial console of the named domain.
+//
+// Guest output and keystrokes both go through the stream opened by
+// libvirt's DomainOpenConsole, on a connection of its own. The call blocks
+// until the escape character is typed, the stream ends or ctx is cancelled,
+// and returns once the session is torn down.
+func OpenConsole(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	name string,
+	in *os.File,
+	out io.Writer,
+	opts ConsoleOptions,
+) error {
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+
+	status, err := GetDomainStatus(conn, dom)
+	if err != nil {
+		return err
+	}
+	if status.State != StateRunning {
+		return fmt.Errorf("domain %q is not running (%s)", name, status)
+	}
+
+	escape := opts.EscapeChar
+	if escape == 0 {
+		escape = DefaultEscapeChar
+	}
+
+	session, guest, err := connectConsole()
+	if err != nil {
+		return err
+	}
+
+	if term.IsTerminal(int(in.Fd())) {
+		oldState, err := term.MakeRaw(int(in.Fd()))
+		if err != nil {
+			session.Disconnect()
+			return fmt.Errorf("set terminal raw mode: %w", err)
+		}
+		defer term.Restore(int(in.Fd()), oldState)
+	}
+
+	// in.Fd makes the terminal blocking again, so the copy comes last.
+	input, err := interruptibleInput(in)
+	if err != nil {
+		session.Disconnect()
+		return err
+	}
+	defer closeInput(input)
+
+	fmt.Fprintf(out, "Connected to domain '%s'\r\n", name)
+	fmt.Fprintf(out, "Escape character is %s\r\n", caretNotation(escape))
+
+	sink := out
+	if opts.Record != nil {
+		sink = io.MultiWriter(out, opts.Record)
+	}
+
+	var flags uint32
+	if opts.Force {
+		flags |= uint32(libvirt.DomainConsoleForce)
+	}
+	var dev libvirt.OptString
+	if opts.Device != "" {
+		dev = libvirt.OptString{opts.Device}
+	}
+
+	var wg sync.WaitGroup
+	streamErr := make(chan error, 1)
+	inputErr := make(chan error, 1)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamErr <- session.DomainOpenConsole(dom, dev, sink, flags)
+	}()
+	go func() {
+		defer wg.Done()
+		inputErr <- forwardInput(input, guest, escape)
+	}()
+	// Both goroutines are done before returning: disconnecting ends the
+	// stream and the deadline interrupts a pending read of the terminal.
+	defer func() {
+		input.SetReadDeadline(time.Now())
+		session.Disconnect()
+		wg.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-streamErr:
+		if err != nil {
+			return fmt.Errorf("console stream for %q: %w", name, err)
+		}
+		return nil
+	case err := <-inputErr:
+		if errors.Is(err, ErrConsoleDetached) {
+			guest.finish()
+			fmt.Fprint(out, "\r\n")
+			return nil
+		}
+		return err
+	}
+}
+
+// interruptibleInput returns a non-blocking copy of in, so that a read of
+// the terminal can be interrupted with a deadline.
+func interruptibleInput(in *os.File) (*os.File, error) {
+	fd, err := syscall.Dup(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("duplicate terminal input: %w", err)
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("set terminal input non-blocking: %w", err)
+	}
+	return os.NewFile(uintptr(fd), in.Name()), nil
+}
+
+// closeInput closes a copy made by interruptibleInput. The terminal is
+// shared with the shell, so it is made blocking again first.
+func closeInput(input *os.File) {
+	if raw, err := input.SyscallConn(); err == nil {
+		raw.Control(func(fd uintptr) { syscall.SetNonblock(int(fd), false) })
+	}
+	input.Close()
+}
+
+// forwardInput copies keystrokes to the console until the escape byte is read.
+func forwardInput(in io.Reader, guest io.Writer, escape byte) error {
+	buf := make([]byte, 1024)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			for i, b := range chunk {
+				if b == escape {
+					if i > 0 {
+						if _, err := guest.Write(chunk[:i]); err != nil {
+							return fmt.Errorf("write to console: %w", err)
+						}
+					}
+					return ErrConsoleDetached
+				}
+			}
+			if _, err := guest.Write(chunk); err != nil {
+				return fmt.Errorf("write to console: %w", err)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return ErrConsoleDetached
+			}
+			return fmt.Errorf("read from terminal: %w", err)
+		}
+	}
+}
+
+func caretNotation(b byte) string {
+	if b < 0x20 {
+		return "^" + string(rune(b+0x40))
+	}
+	if b == 0x7f {
+		return "^?"
+	}
+	return string(rune(b))
+}
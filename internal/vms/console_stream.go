@@ -0,0 +1,139 @@
+package vms
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/digitalocean/go-libvirt/socket"
+	"github.com/digitalocean/go-libvirt/socket/dialers"
+)
+
+// procDomainOpenConsole is REMOTE_PROC_DOMAIN_OPEN_CONSOLE.
+const procDomainOpenConsole = 201
+
+// packetHeaderSize is the size of the length prefix and header of a libvirt
+// RPC packet.
+const packetHeaderSize = 28
+
+// consoleConn is a libvirt connection that can send data on the stream
+// opened by DomainOpenConsole. go-libvirt only hands back the receiving half
+// of that stream, so the packets it writes are framed here: the call opening
+// the console is remembered, and stream packets for it are written between
+// whole packets of go-libvirt.
+type consoleConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	pending []byte
+	call    []byte // header of the DomainOpenConsole call
+	opened  chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newConsoleConn(conn net.Conn) *consoleConn {
+	return &consoleConn{Conn: conn, opened: make(chan struct{}), closed: make(chan struct{})}
+}
+
+// Close closes the connection and fails pending stream writes.
+func (c *consoleConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// Write forwards the packets of go-libvirt once they are complete.
+func (c *consoleConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(c.pending, p...)
+	for len(c.pending) >= packetHeaderSize {
+		size := int(binary.BigEndian.Uint32(c.pending[0:4]))
+		if size < packetHeaderSize || len(c.pending) < size {
+			break
+		}
+		packet := c.pending[:size]
+		if c.call == nil && isConsoleCall(packet) {
+			c.call = append([]byte(nil), packet[:packetHeaderSize]...)
+			close(c.opened)
+		}
+		if _, err := c.Conn.Write(packet); err != nil {
+			return 0, err
+		}
+		c.pending = append(c.pending[:0], c.pending[size:]...)
+	}
+	return len(p), nil
+}
+
+// isConsoleCall reports whether packet is the call opening a console.
+func isConsoleCall(packet []byte) bool {
+	proc := binary.BigEndian.Uint32(packet[12:16])
+	typ := binary.BigEndian.Uint32(packet[16:20])
+	return proc == procDomainOpenConsole && typ == socket.Call
+}
+
+// sendStream writes a stream packet carrying payload for the open console.
+func (c *consoleConn) sendStream(status uint32, payload []byte) error {
+	select {
+	case <-c.opened:
+	case <-c.closed:
+		return net.ErrClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	packet := make([]byte, packetHeaderSize+len(payload))
+	copy(packet, c.call)
+	binary.BigEndian.PutUint32(packet[0:4], uint32(len(packet)))
+	binary.BigEndian.PutUint32(packet[16:20], socket.Stream)
+	binary.BigEndian.PutUint32(packet[24:28], status)
+	copy(packet[packetHeaderSize:], payload)
+	_, err := c.Conn.Write(packet)
+	return err
+}
+
+// consoleWriter sends keystrokes to the guest on the console stream.
+type consoleWriter struct {
+	conn *consoleConn
+}
+
+func (w consoleWriter) Write(p []byte) (int, error) {
+	if err := w.conn.sendStream(socket.StatusContinue, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// finish ends the console stream, which makes DomainOpenConsole return.
+func (w consoleWriter) finish() error {
+	return w.conn.sendStream(socket.StatusOK, nil)
+}
+
+// consoleDialer dials libvirt like InitConnection does and keeps the
+// connection so that console input can be sent on it.
+type consoleDialer struct {
+	conn *consoleConn
+}
+
+func (d *consoleDialer) Dial() (net.Conn, error) {
+	conn, err := dialers.NewLocal().Dial()
+	if err != nil {
+		return nil, err
+	}
+	d.conn = newConsoleConn(conn)
+	return d.conn, nil
+}
+
+// connectConsole opens a libvirt connection dedicated to one console session.
+func connectConsole() (*libvirt.Libvirt, consoleWriter, error) {
+	dialer := &consoleDialer{}
+	conn := libvirt.NewWithDialer(dialer)
+	if err := conn.ConnectToURI(libvirt.QEMUSystem); err != nil {
+		return nil, consoleWriter{}, fmt.Errorf("connect console session: %w", err)
+	}
+	return conn, consoleWriter{conn: dialer.conn}, nil
+}
@@ -0,0 +1,122 @@
+package vms
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseEscapeSequence(t *testing.T) {
+	tests := []struct {
+		seq     string
+		want    byte
+		wantErr bool
+	}{
+		{"", DefaultEscapeChar, false},
+		{"^]", 0x1d, false},
+		{"^A", 0x01, false},
+		{"^a", 0x01, false},
+		{"^?", 0x7f, false},
+		{"^@", 0x00, false},
+		{"q", 'q', false},
+		{"ab", 0, true},
+		{"^]]", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEscapeSequence(tt.seq)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEscapeSequence(%q) error = %v, wantErr %v", tt.seq, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseEscapeSequence(%q) = %#x, want %#x", tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestForwardInput(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"escape ends the session", "ls\r\x1d", "ls\r"},
+		{"nothing after the escape is sent", "ab\x1dcd", "ab"},
+		{"escape first", "\x1dab", ""},
+		{"end of input detaches", "uptime\r", "uptime\r"},
+	}
+
+	for _, tt := range tests {
+		var guest bytes.Buffer
+		err := forwardInput(strings.NewReader(tt.input), &guest, DefaultEscapeChar)
+		if !errors.Is(err, ErrConsoleDetached) {
+			t.Errorf("%s: forwardInput() error = %v, want ErrConsoleDetached", tt.name, err)
+		}
+		if got := guest.String(); got != tt.want {
+			t.Errorf("%s: guest got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleConnStream(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newConsoleConn(client)
+	defer conn.Close()
+
+	call := make([]byte, packetHeaderSize+4)
+	binary.BigEndian.PutUint32(call[0:4], uint32(len(call)))
+	binary.BigEndian.PutUint32(call[4:8], 0x20008086)
+	binary.BigEndian.PutUint32(call[12:16], procDomainOpenConsole)
+	binary.BigEndian.PutUint32(call[20:24], 7)
+
+	received := make(chan []byte, 2)
+	go func() {
+		for range 2 {
+			header := make([]byte, 4)
+			if _, err := server.Read(header); err != nil {
+				return
+			}
+			packet := make([]byte, binary.BigEndian.Uint32(header))
+			copy(packet, header)
+			for n := 4; n < len(packet); {
+				m, err := server.Read(packet[n:])
+				if err != nil {
+					return
+				}
+				n += m
+			}
+			received <- packet
+		}
+	}()
+
+	// go-libvirt may write a packet in pieces: it goes out once complete.
+	if _, err := conn.Write(call[:10]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := conn.Write(call[10:]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := <-received; !bytes.Equal(got, call) {
+		t.Fatalf("forwarded call = %x, want %x", got, call)
+	}
+
+	if _, err := (consoleWriter{conn: conn}).Write([]byte("ls\r")); err != nil {
+		t.Fatalf("console Write failed: %v", err)
+	}
+	got := <-received
+	if len(got) != packetHeaderSize+3 || string(got[packetHeaderSize:]) != "ls\r" {
+		t.Fatalf("stream packet = %x, want the keystrokes", got)
+	}
+	if typ := binary.BigEndian.Uint32(got[16:20]); typ != 3 {
+		t.Errorf("stream packet type = %d, want 3 (stream)", typ)
+	}
+	if serial := binary.BigEndian.Uint32(got[20:24]); serial != 7 {
+		t.Errorf("stream packet serial = %d, want the serial of the call", serial)
+	}
+	if status := binary.BigEndian.Uint32(got[24:28]); status != 2 {
+		t.Errorf("stream packet status = %d, want 2 (continue)", status)
+	}
+}
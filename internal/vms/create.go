@@ -10,15 +10,66 @@ import (
 
 // Create Virtual Machine
 func (vm *VirtualMachine) Create() error {
-	// Resolve MAC address (explicit in config, otherwise derived from IP).
-	macAddress, err := network.ResolveMAC("02:aa:bb", vm.Spec.IP, vm.Spec.MAC)
-	if err != nil {
-		return fmt.Errorf("resolve mac for %q: %w", vm.Spec.Name, err)
+	// this is a slice that collection all the cleanup functions
+	// so that when an error happens in each step, a proper rollback
+	// will be executed
+	var cleanups []func() error
+
+	// Reserve the static address of the VM, letting IPAM pick it for ip = "auto".
+	ipam := network.NewIPAM(vm.conn, vm.db)
+	allocated := vm.Spec.IP == network.AutoIP
+	if allocated {
+		ip, inserted, err := ipam.Allocate(vm.ctx, vm.Spec.NetName, vm.Spec.Name)
+		if err != nil {
+			return fmt.Errorf("allocate ip for %q: %w", vm.Spec.Name, err)
+		}
+		vm.Spec.IP = ip
+		// An address the VM already held belongs to a live VM of that name.
+		if inserted {
+			cleanups = append(cleanups, func() error {
+				return ipam.Release(vm.ctx, vm.Spec.NetName, vm.Spec.Name)
+			})
+		}
+	}
+
+	// Check the configured MAC address, or allocate a unique one under the
+	// prefix of the network, derived from the IP when it is free.
+	macs := network.NewMACAllocator(vm.conn, vm.db)
+	if vm.Spec.MAC != "" {
+		if err := macs.Check(vm.ctx, vm.Spec.Name, vm.Spec.MAC); err != nil {
+			return vm.rollback(cleanups, "check mac", err)
+		}
+	} else {
+		mac, err := macs.Allocate(vm.ctx, vm.Spec.NetName, vm.Spec.IP)
+		if err != nil {
+			return vm.rollback(cleanups, "allocate mac", err)
+		}
+		vm.Spec.MAC = mac
+	}
+	macAddress := vm.Spec.MAC
+
+	if vm.Spec.IP != "" && !allocated {
+		inserted, err := ipam.Reserve(vm.ctx, vm.Spec.NetName, vm.Spec.Name, vm.Spec.IP, macAddress)
+		if err != nil {
+			return fmt.Errorf("reserve ip for %q: %w", vm.Spec.Name, err)
+		}
+		if inserted {
+			cleanups = append(cleanups, func() error {
+				return ipam.Release(vm.ctx, vm.Spec.NetName, vm.Spec.Name)
+			})
+		}
 	}
+
+	if vm.Spec.IPv6 != "" {
+		if err := ipam.CheckIPv6(vm.ctx, vm.Spec.NetName, vm.Spec.Name, vm.Spec.IPv6); err != nil {
+			return vm.rollback(cleanups, "check ipv6", err)
+		}
+	}
+
 	// Initiliaze a new vm record
 	record, err := NewVirtualMachineRecord(vm)
 	if err != nil {
-		return fmt.Errorf("can't Initiliaze a new vm: %w", err)
+		return vm.rollback(cleanups, "init record", err)
 	}
 
 	// store, img, err := vm.fetchStoreAndImage(vm.Config.Spec.Image)
@@ -27,22 +78,17 @@ func (vm *VirtualMachine) Create() error {
 	// }
 	img, err := database.GetImage(vm.ctx, vm.db, vm.Spec.Image)
 	if err != nil {
-		return fmt.Errorf("fetch store and image: %w", err)
+		return vm.rollback(cleanups, "fetch store and image", err)
 	}
 
 	src := filepath.Join(img.ArtifactsPath, img.ImageFile)
 	dest := filepath.Join(img.ImagesPath, vm.Spec.Name+".qcow2")
 
-	// this is a slice that collection all the cleanup functions
-	// so that when an error happens in each step, a proper rollback
-	// will be executed
-	var cleanups []func() error
-
 	// artifactsPath, imagesPath := vm.disk.Paths()
 	// src := fmt.Sprintf("%s/%s", artifactsPath, vm.Config.Spec.Image)
 	// dest := fmt.Sprintf("%s/%s.qcow2", imagesPath, vm.Config.Metadata.Name)
 	if err := vm.disk.CreateOverlay(vm.ctx, src, dest); err != nil {
-		return fmt.Errorf("create disk overlay: %w", err)
+		return vm.rollback(cleanups, "create disk overlay", err)
 	}
 	//
 	cleanups = append(cleanups, func() error {
@@ -64,16 +110,35 @@ func (vm *VirtualMachine) Create() error {
 		return vm.domain.Undefine(vm.ctx, vm.Spec.Name)
 	})
 
+	if vm.Spec.Autostart {
+		if err := vm.domain.SetAutostart(vm.ctx, vm.Spec.Name, true); err != nil {
+			return vm.rollback(cleanups, "enable autostart", err)
+		}
+	}
+
 	// Step 4: Add static IP mapping if configured
 	if vm.Spec.IP != "" {
 		nm := network.NewLibvirtNetworkManager(vm.conn, vm.db)
 		// We're using NetName which connects to the network name in config
 		// if err := nm.SetStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IP, vm.Spec.MAC); err != nil {
-		if err := nm.SetStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IP, macAddress); err != nil {
+		if err := nm.SetStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IP, macAddress, vm.Spec.Name); err != nil {
 			// We might want to warn instead of fail, or fail.
 			// If we fail, we should rollback (undefine domain).
 			return vm.rollback(cleanups, "add static ip mapping", err)
 		}
+		cleanups = append(cleanups, func() error {
+			return nm.RemoveStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IP, vm.Spec.Name)
+		})
+	}
+	// DHCPv6 hands the IPv6 address out by host name.
+	if vm.Spec.IPv6 != "" {
+		nm := network.NewLibvirtNetworkManager(vm.conn, vm.db)
+		if err := nm.SetStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IPv6, macAddress, vm.Spec.Name); err != nil {
+			return vm.rollback(cleanups, "add static ipv6 mapping", err)
+		}
+		cleanups = append(cleanups, func() error {
+			return nm.RemoveStaticMapping(vm.ctx, vm.Spec.NetName, vm.Spec.IPv6, vm.Spec.Name)
+		})
 	}
 
 	if err := vm.domain.Start(vm.ctx, vm.Spec.Name); err != nil {
@@ -82,7 +147,7 @@ func (vm *VirtualMachine) Create() error {
 
 	// cleanups = append(cleanups, vm.domain.)
 	cleanups = append(cleanups, func() error {
-		return vm.domain.Stop(vm.ctx, vm.Spec.Name)
+		return vm.domain.Destroy(vm.ctx, vm.Spec.Name)
 	})
 
 	// Insert the vm record
@@ -1,10 +1,14 @@
 package vms
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/network"
 )
 
 // OPTIMIZE:
@@ -29,7 +33,7 @@ func (vm *VirtualMachine) Delete() error {
 
 	dest := filepath.Join(img.ImagesPath, vm.Spec.Name+".qcow2")
 
-	// Attempt to destroy the domain.
+	// Destroy the domain; Destroy skips domains that are already shut off.
 	if err := vm.domain.Destroy(vm.ctx, vm.Spec.Name); err != nil {
 		return err
 	}
@@ -39,11 +43,31 @@ func (vm *VirtualMachine) Delete() error {
 		return fmt.Errorf("failed to undefine VM %q: %w", vmName, err)
 	}
 
+	// Remove the overlays left by external snapshots, then their records.
+	if err := vm.deleteSnapshots(); err != nil {
+		return err
+	}
+
 	// Remove the disk associated with the VM.
-	if err := vm.disk.DeleteOverlay(vm.ctx, dest); err != nil {
+	if err := DeleteDisk(vm.ctx, vm.disk, dest); err != nil {
 		return err
 	}
 
+	// Remove the DHCP reservations and DNS records of the VM, so that its
+	// addresses can be handed out again; the stored record has the
+	// addresses IPAM picked.
+	if stored, err := database.GetVMByName(vm.ctx, vm.db, vmName, vm.Spec.Namespace); err == nil {
+		nm := network.NewLibvirtNetworkManager(vm.conn, vm.db)
+		for _, ip := range []string{stored.IP, stored.IPv6} {
+			if ip == "" {
+				continue
+			}
+			if err := nm.RemoveStaticMapping(vm.ctx, vm.Spec.NetName, ip, vmName); err != nil {
+				log.Warnf("%v", err)
+			}
+		}
+	}
+
 	record, err := NewVirtualMachineRecord(vm)
 	if err != nil {
 		return err
@@ -52,7 +76,61 @@ func (vm *VirtualMachine) Delete() error {
 	if err != nil {
 		return err
 	}
+	// Free the static address the VM held.
+	if err := network.NewIPAM(vm.conn, vm.db).Release(vm.ctx, vm.Spec.NetName, vmName); err != nil {
+		return err
+	}
 	fmt.Printf("vm/%s deleted\n", vmName)
 
 	return nil
 }
+
+// DeleteDisk removes the disk of a VM along with the files kept next to
+// it: the overlay replaced by a re-image that was never confirmed, and the
+// backing image a restore extracted from its archive.
+func DeleteDisk(ctx context.Context, disk DiskManager, dest string) error {
+	if err := disk.DeleteOverlay(ctx, dest); err != nil {
+		return err
+	}
+	for _, path := range []string{PreviousOverlayPath(dest), BaseImagePath(dest)} {
+		if !exists(path) {
+			continue
+		}
+		if err := disk.DeleteOverlay(ctx, path); err != nil {
+			log.Warnf("%v", err)
+		}
+	}
+	return nil
+}
+
+// BaseImagePath returns where a restore puts the backing image shipped in
+// the archive of the VM whose disk is at diskPath.
+func BaseImagePath(diskPath string) string {
+	base := strings.TrimSuffix(diskPath, filepath.Ext(diskPath))
+	return base + ".base" + filepath.Ext(diskPath)
+}
+
+// deleteSnapshots removes the snapshot records of the VM and the overlay
+// files created by its external snapshots.
+func (vm *VirtualMachine) deleteSnapshots() error {
+	record, err := database.GetVMByName(vm.ctx, vm.db, vm.Spec.Name, vm.Spec.Namespace)
+	if err != nil {
+		// No record means no snapshot was ever taken through kvmcli.
+		return nil
+	}
+
+	snapshots, err := database.GetSnapshotsByVM(vm.ctx, vm.db, record.ID)
+	if err != nil {
+		return err
+	}
+	for _, snap := range snapshots {
+		if snap.Kind != database.SnapshotExternal || snap.DiskPath == "" {
+			continue
+		}
+		if err := vm.disk.DeleteOverlay(vm.ctx, snap.DiskPath); err != nil {
+			log.Warnf("snapshot %q of %q: %v", snap.Name, vm.Spec.Name, err)
+		}
+	}
+
+	return database.DeleteSnapshotsByVM(vm.ctx, vm.db, record.ID)
+}
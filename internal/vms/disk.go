@@ -2,7 +2,10 @@ package vms
 
 import (
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"time"
@@ -13,6 +16,12 @@ import (
 type DiskManager interface {
 	CreateOverlay(ctx context.Context, src, dest string) error
 	DeleteOverlay(ctx context.Context, dest string) error
+	// Copy duplicates a disk, keeping its backing file reference.
+	Copy(ctx context.Context, src, dest string) error
+	// Flatten writes a standalone copy of src, with its backing chain merged in.
+	Flatten(ctx context.Context, src, dest string) error
+	// Rebase points an existing overlay at a new backing file without copying data.
+	Rebase(ctx context.Context, path, backing string) error
 	Paths() (baseImagesPath, destImagesPath string)
 	// Size()
 }
@@ -67,6 +76,134 @@ func (d *QemuDiskManager) DeleteOverlay(ctx context.Context, dest string) error
 	return nil
 }
 
+func (d *QemuDiskManager) Copy(ctx context.Context, src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open disk %q: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("create disk %q: %w", dest, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return fmt.Errorf("copy %q to %q failed: %w", src, dest, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return fmt.Errorf("copy %q to %q failed: %w", src, dest, err)
+	}
+	log.Debugf("copied %s to %s", src, dest)
+	return nil
+}
+
+func (d *QemuDiskManager) Flatten(ctx context.Context, src, dest string) error {
+	args := []string{
+		"convert",
+		"-O", "qcow2",
+		src,
+		dest,
+	}
+	if err := d.run(ctx, args...); err != nil {
+		return fmt.Errorf("flatten %q failed: %w", src, err)
+	}
+	log.Debugf("flattened %s into %s", src, dest)
+	return nil
+}
+
+func (d *QemuDiskManager) Rebase(ctx context.Context, path, backing string) error {
+	format, err := d.Format(ctx, backing)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"rebase",
+		"-u",
+		"-b", backing,
+		"-F", format,
+		path,
+	}
+	if err := d.run(ctx, args...); err != nil {
+		return fmt.Errorf("rebase %q failed: %w", path, err)
+	}
+	log.Debugf("rebased %s onto %s", path, backing)
+	return nil
+}
+
+// Format returns the image format of a disk (qcow2, raw, ...), as read by
+// qemu-img info.
+func (d *QemuDiskManager) Format(ctx context.Context, path string) (string, error) {
+	output, err := exec.CommandContext(ctx, d.cmdPath(), "info", "--output=json", path).Output()
+	if err != nil {
+		return "", fmt.Errorf("inspect %q failed: %w", path, err)
+	}
+	return parseFormat(output)
+}
+
+// parseFormat extracts the format from the JSON printed by qemu-img info.
+func parseFormat(info []byte) (string, error) {
+	var image struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(info, &image); err != nil {
+		return "", fmt.Errorf("parse qemu-img info: %w", err)
+	}
+	if image.Format == "" {
+		return "", fmt.Errorf("qemu-img info reported no format")
+	}
+	return image.Format, nil
+}
+
+// disksXML is the subset of the domain XML describing disks.
+type disksXML struct {
+	Devices struct {
+		Disks []struct {
+			Source struct {
+				File string `xml:"file,attr"`
+			} `xml:"source"`
+			Target struct {
+				Dev string `xml:"dev,attr"`
+			} `xml:"target"`
+		} `xml:"disk"`
+	} `xml:"devices"`
+}
+
+// ParseDiskSource returns the file behind the disk dev of a domain XML
+// document. After an external snapshot it is the snapshot overlay, not the
+// disk the VM was created with.
+func ParseDiskSource(domainXML, dev string) (string, error) {
+	var desc disksXML
+	if err := xml.Unmarshal([]byte(domainXML), &desc); err != nil {
+		return "", fmt.Errorf("parse domain XML: %w", err)
+	}
+	for _, disk := range desc.Devices.Disks {
+		if disk.Target.Dev == dev && disk.Source.File != "" {
+			return disk.Source.File, nil
+		}
+	}
+	return "", fmt.Errorf("domain XML has no file-backed disk %s", dev)
+}
+
+// run executes qemu-img with the given arguments.
+func (d *QemuDiskManager) run(ctx context.Context, args ...string) error {
+	output, err := exec.CommandContext(ctx, d.cmdPath(), args...).CombinedOutput()
+	if err != nil {
+		log.Errorf("qemu-img error: %s", output)
+		return err
+	}
+	return nil
+}
+
+func (d *QemuDiskManager) cmdPath() string {
+	if d.QemuImgPath == "" {
+		return qemuImgCmd
+	}
+	return d.QemuImgPath
+}
+
 func (d *QemuDiskManager) GetPath() error {
 	return nil
 }
@@ -0,0 +1,56 @@
+package vms
+
+import "testing"
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		info    string
+		want    string
+		wantErr bool
+	}{
+		{`{"filename": "rocky-9.qcow2", "format": "qcow2", "virtual-size": 10737418240}`, "qcow2", false},
+		{`{"filename": "debian.img", "format": "raw"}`, "raw", false},
+		{`{"filename": "debian.img"}`, "", true},
+		{`qemu-img: Could not open 'x'`, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFormat([]byte(tt.info))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFormat(%s) error = %v, wantErr %v", tt.info, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseFormat(%s) = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}
+
+func TestParseDiskSource(t *testing.T) {
+	// The domain after a disk-only snapshot: vda writes to the snapshot overlay.
+	const domainXML = `<domain type='kvm'>
+  <name>web-01</name>
+  <devices>
+    <disk type='file' device='cdrom'>
+      <source file='/var/lib/kvmcli/images/web-01-cidata.iso'/>
+      <target dev='sda' bus='sata'/>
+    </disk>
+    <disk type='file' device='disk'>
+      <driver name='qemu' type='qcow2'/>
+      <source file='/var/lib/kvmcli/images/web-01.pre-upgrade.qcow2'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+  </devices>
+</domain>`
+
+	got, err := ParseDiskSource(domainXML, "vda")
+	if err != nil {
+		t.Fatalf("ParseDiskSource failed: %v", err)
+	}
+	if want := "/var/lib/kvmcli/images/web-01.pre-upgrade.qcow2"; got != want {
+		t.Errorf("ParseDiskSource = %q, want %q", got, want)
+	}
+	if _, err := ParseDiskSource(domainXML, "vdb"); err == nil {
+		t.Error("ParseDiskSource of a missing disk succeeded")
+	}
+}
@@ -9,16 +9,9 @@ import (
 
 	"github.com/digitalocean/go-libvirt"
 	"github.com/kebairia/kvmcli/internal/database"
-	"github.com/kebairia/kvmcli/internal/network"
 	"github.com/kebairia/kvmcli/internal/templates"
 )
 
-const (
-	domainStateRunning = 1
-	domainStatePaused  = 3
-	domainStateStopped = 5
-)
-
 type DomainManager interface {
 	// BuildXML returns the full libvirt XML for this Config.
 	BuildXML(ctx context.Context, db *sql.DB, cfg Config) (string, error)
@@ -29,17 +22,44 @@ type DomainManager interface {
 	// Start actually powers on the domain.
 	Start(ctx context.Context, name string) error
 
-	// Stop gracefully shuts down the domain.
-	Stop(ctx context.Context, name string) error
+	// Stop gracefully shuts down the domain and waits until it is shut off.
+	Stop(ctx context.Context, name string, opts StopOptions) error
 
 	// Stop (kills) shuts down the domain immediately.
 	Destroy(ctx context.Context, name string) error
 
+	// Restart stops the domain like Stop, then starts it again.
+	Restart(ctx context.Context, name string, opts StopOptions) error
+
+	// Pause freezes the vCPUs of the domain, keeping it in memory.
+	Pause(ctx context.Context, name string) error
+
+	// Resume unfreezes a paused domain.
+	Resume(ctx context.Context, name string) error
+
+	// Suspend saves the domain state to disk (managed save) and stops it;
+	// the next Start restores it.
+	Suspend(ctx context.Context, name string) error
+
+	// Reset hard-resets the domain, like pressing the reset button.
+	Reset(ctx context.Context, name string) error
+
 	// Undefine removes the domain metadata.
 	Undefine(ctx context.Context, name string) error
 
-	// State returns one of “Running”/“Stopped”/etc.
-	State(ctx context.Context, name string) (string, error)
+	// State returns the lifecycle state of the domain and its reason.
+	State(ctx context.Context, name string) (DomainStatus, error)
+
+	// SetVCPUs changes the vCPU count in the persistent definition,
+	// and on the running domain too when live is set.
+	SetVCPUs(ctx context.Context, name string, count int, live bool) error
+
+	// SetMemory changes the memory (MiB) in the persistent definition,
+	// and on the running domain too when live is set.
+	SetMemory(ctx context.Context, name string, memory int, live bool) error
+
+	// SetAutostart sets whether the domain starts along with the host.
+	SetAutostart(ctx context.Context, name string, on bool) error
 }
 type LibvirtDomainManager struct {
 	conn *libvirt.Libvirt
@@ -59,11 +79,12 @@ func (d *LibvirtDomainManager) BuildXML(
 	if err != nil {
 		return "", nil
 	}
-	// Resolve MAC address (explicit in config, otherwise derived from IP).
-	macAddress, err := network.ResolveMAC("02:aa:bb", spec.IP, spec.MAC)
-	if err != nil {
-		return "", fmt.Errorf("resolve mac for %q: %w", spec.Name, err)
+	// The MAC address comes from the MACAllocator of the network, which
+	// honours its mac_prefix and keeps addresses unique.
+	if spec.MAC == "" {
+		return "", fmt.Errorf("vm %q has no MAC address allocated", spec.Name)
 	}
+	macAddress := spec.MAC
 
 	// Build the disk image path for the domain configuration.
 	diskImagePath := fmt.Sprintf(
@@ -79,6 +100,10 @@ func (d *LibvirtDomainManager) BuildXML(
 		macAddress,
 		img.OsProfile,
 	)
+	if err := validateMaximums(spec); err != nil {
+		return "", err
+	}
+	domain.SetMaximums(spec.MaxCPU, spec.MaxMemory)
 	xmlConfig, err := domain.GenerateXML()
 	if err != nil {
 		return "", fmt.Errorf("failed to generate XML for Config %s: %v", spec.Name, err)
@@ -96,36 +121,55 @@ func (m *LibvirtDomainManager) Define(ctx context.Context, xmlConfig string) err
 	return nil
 }
 
-// Start powers on the given domain by name.
+// Start powers on the given domain by name. A domain that is already
+// running is left alone, a paused or PM-suspended one is woken up and a
+// crashed one is torn down and booted again.
 func (m *LibvirtDomainManager) Start(ctx context.Context, name string) error {
 	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
 		return fmt.Errorf("lookup domain %q: %w", name, err)
 	}
-	if err := m.conn.DomainCreate(dom); err != nil {
-		return fmt.Errorf("start domain %q: %w", name, err)
-	}
-	return nil
-}
-
-// Stop attempts a graceful shutdown of the domain.
-func (m *LibvirtDomainManager) Stop(ctx context.Context, name string) error {
-	dom, err := m.conn.DomainLookupByName(name)
+	status, err := GetDomainStatus(m.conn, dom)
 	if err != nil {
-		return fmt.Errorf("lookup domain %q: %w", name, err)
+		return err
+	}
+	switch status.State {
+	case StateRunning, StateBlocked:
+		return nil
+	case StateShuttingDown:
+		return fmt.Errorf("domain %q is shutting down", name)
+	case StatePaused:
+		return m.Resume(ctx, name)
+	case StatePMSuspended:
+		if err := m.conn.DomainPmWakeup(dom, 0); err != nil {
+			return fmt.Errorf("wake up domain %q: %w", name, err)
+		}
+		return nil
+	case StateCrashed:
+		if err := m.conn.DomainDestroy(dom); err != nil {
+			return fmt.Errorf("destroy crashed domain %q: %w", name, err)
+		}
 	}
-	if err := m.conn.DomainShutdown(dom); err != nil {
-		return fmt.Errorf("shutdown domain %q: %w", name, err)
+	if err := m.conn.DomainCreate(dom); err != nil {
+		return fmt.Errorf("start domain %q: %w", name, err)
 	}
 	return nil
 }
 
 // Destroy force-stops (kills) the domain immediately.
+// It does nothing when the domain is already shut off.
 func (m *LibvirtDomainManager) Destroy(ctx context.Context, name string) error {
 	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
 		return fmt.Errorf("lookup domain %q: %w", name, err)
 	}
+	status, err := GetDomainStatus(m.conn, dom)
+	if err != nil {
+		return err
+	}
+	if !status.State.IsActive() && status.State != StateCrashed {
+		return nil
+	}
 	if err := m.conn.DomainDestroy(dom); err != nil {
 		return fmt.Errorf("destroy domain %q: %w", name, err)
 	}
@@ -138,51 +182,69 @@ func (m *LibvirtDomainManager) Undefine(ctx context.Context, name string) error
 	if err != nil {
 		return fmt.Errorf("lookup domain %q: %w", name, err)
 	}
-	if err := m.conn.DomainUndefine(dom); err != nil {
+	// Snapshot metadata and managed-save images would otherwise block the undefine.
+	flags := libvirt.DomainUndefineSnapshotsMetadata | libvirt.DomainUndefineManagedSave
+	if err := m.conn.DomainUndefineFlags(dom, flags); err != nil {
 		return fmt.Errorf("undefine domain %q: %w", name, err)
 	}
 	return nil
 }
 
-// State returns a human-readable state (“Running”, “Paused”, “Shut off”, etc.).
-func (m *LibvirtDomainManager) State(ctx context.Context, name string) (string, error) {
+// SetVCPUs changes the number of vCPUs, within the configured maximum.
+func (m *LibvirtDomainManager) SetVCPUs(ctx context.Context, name string, count int, live bool) error {
 	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
-		return "", fmt.Errorf("lookup domain %q: %w", name, err)
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	flags := libvirt.DomainVCPUConfig
+	if live {
+		flags |= libvirt.DomainVCPULive
+	}
+	if err := m.conn.DomainSetVcpusFlags(dom, uint32(count), uint32(flags)); err != nil {
+		return fmt.Errorf("set vcpus of %q to %d: %w", name, count, err)
 	}
-	state, _, _, _, _, err := m.conn.DomainGetInfo(dom)
+	return nil
+}
+
+// SetMemory changes the memory (MiB) through the balloon, within the configured maximum.
+func (m *LibvirtDomainManager) SetMemory(ctx context.Context, name string, memory int, live bool) error {
+	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
-		return "", fmt.Errorf("get state for %q: %w", name, err)
-	}
-	switch int(state) {
-	case 1:
-		return "Running", nil
-	case 3:
-		return "Paused", nil
-	case 5:
-		return "Shut off", nil
-	default:
-		return "Unknown", nil
+		return fmt.Errorf("lookup domain %q: %w", name, err)
 	}
+	flags := libvirt.DomainMemConfig
+	if live {
+		flags |= libvirt.DomainMemLive
+	}
+	if err := m.conn.DomainSetMemoryFlags(dom, uint64(memory)*1024, uint32(flags)); err != nil {
+		return fmt.Errorf("set memory of %q to %d MiB: %w", name, memory, err)
+	}
+	return nil
 }
 
-// GetDomainState returns a string representation of the VM state based on its domain info.
-func GetDomainState(conn *libvirt.Libvirt, domain libvirt.Domain) (string, error) {
-	state, _, _, _, _, err := conn.DomainGetInfo(domain)
+// SetAutostart sets whether libvirt starts the domain along with the host.
+func (m *LibvirtDomainManager) SetAutostart(ctx context.Context, name string, on bool) error {
+	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to get info for domain %s: %w", domain.Name, err)
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	var autostart int32
+	if on {
+		autostart = 1
 	}
+	if err := m.conn.DomainSetAutostart(dom, autostart); err != nil {
+		return fmt.Errorf("set autostart of %q: %w", name, err)
+	}
+	return nil
+}
 
-	switch int(state) {
-	case domainStateRunning:
-		return "Running", nil
-	case domainStatePaused:
-		return "Paused", nil
-	case domainStateStopped:
-		return "Stopped", nil
-	default:
-		return "Unknown", nil
+// State returns the lifecycle state of the domain and its reason.
+func (m *LibvirtDomainManager) State(ctx context.Context, name string) (DomainStatus, error) {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return DomainStatus{}, fmt.Errorf("lookup domain %q: %w", name, err)
 	}
+	return GetDomainStatus(m.conn, dom)
 }
 
 // GetDiskSize returns the disk size (in gigabytes) for the specified VM domain.
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/digitalocean/go-libvirt"
 	"github.com/kebairia/kvmcli/internal/common"
@@ -15,31 +17,37 @@ import (
 
 const allDomains = -1
 
+// guestDeadline bounds how long listing VMs waits for their guest agents.
+const guestDeadline = 3 * time.Second
+
 const (
 	vmCols = `id, name, namespace, cpu, ram, ip_address, mac_address, network_id, image, disk_size, disk_path, created_at, labels`
 )
 
 // VirtualMachineInfo holds everything we need to print one row.
 type VirtualMachineInfo struct {
-	Name     string
-	State    string
-	CPU      int
-	RAM      int     // in MB
-	DiskSize float64 // in GB
-	Network  string
-	IP       string
-	OS       string
-	Age      string
+	Name      string
+	State     string
+	CPU       int
+	RAM       int     // in MB
+	DiskSize  float64 // in GB
+	Network   string
+	IP        string
+	OS        string
+	Autostart string
+	Age       string
+
+	running bool // asked to the guest agent for its addresses and OS
 }
 
 func (info *VirtualMachineInfo) Header() *tabwriter.Writer {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tSTATE\tCPU\tMEMORY\tDISK\tNETWORK\tIP\tOS\tAGE")
+	fmt.Fprintln(w, "NAME\tSTATE\tCPU\tMEMORY\tDISK\tNETWORK\tIP\tOS\tAUTOSTART\tAGE")
 	return w
 }
 
 func (info *VirtualMachineInfo) PrintInfo(w *tabwriter.Writer) {
-	fmt.Fprintf(w, "%s\t%s\t%d\t%d MB\t%.2f GB\t%s\t%s\t%s\t%s\n",
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d MB\t%.2f GB\t%s\t%s\t%s\t%s\t%s\n",
 		info.Name,
 		info.State,
 		info.CPU,
@@ -48,6 +56,7 @@ func (info *VirtualMachineInfo) PrintInfo(w *tabwriter.Writer) {
 		info.Network,
 		info.IP,
 		info.OS,
+		info.Autostart,
 		info.Age,
 	)
 }
@@ -66,10 +75,16 @@ func NewVirtualMachineInfo(
 	}
 
 	// State
-	state, err := GetDomainState(conn, dom)
+	var state string
+	status, err := GetDomainStatus(conn, dom)
 	if err != nil {
 		log.Errorf("cannot get state for %q: %v", rec.Name, err)
 		state = "unknown"
+	} else {
+		state = status.String()
+	}
+	if rec.RestartRequired && status.State == StateRunning {
+		state += " (restart required)"
 	}
 
 	// Disk size
@@ -96,19 +111,89 @@ func NewVirtualMachineInfo(
 		osName = rec.Image
 	}
 
+	// Autostart, as libvirt has it
+	autostart := "unknown"
+	if on, err := conn.DomainGetAutostart(dom); err != nil {
+		log.Errorf("cannot get autostart for %q: %v", rec.Name, err)
+	} else {
+		autostart = common.OnOff(on == 1)
+	}
+
 	return &VirtualMachineInfo{
-		Name:     rec.Name,
-		State:    state,
-		CPU:      rec.CPU,
-		RAM:      rec.RAM,
-		DiskSize: disk,
-		Network:  network,
-		IP:       rec.IP,
-		OS:       osName,
-		Age:      common.FormatAge(rec.CreatedAt),
+		Name:      rec.Name,
+		State:     state,
+		CPU:       rec.CPU,
+		RAM:       rec.RAM,
+		DiskSize:  disk,
+		Network:   network,
+		IP:        rec.IP,
+		OS:        osName,
+		Autostart: autostart,
+		Age:       common.FormatAge(rec.CreatedAt),
+		running:   status.State == StateRunning,
 	}, nil
 }
 
+// addGuestDetails fills in what the guest agents of the running VMs report,
+// in place of the recorded IP and the store image. The agents are asked all
+// at once, and those that have not answered by guestDeadline are skipped.
+func addGuestDetails(conn *libvirt.Libvirt, infos []VirtualMachineInfo) {
+	type details struct {
+		index       int
+		ips, osName string
+	}
+	results := make(chan details, len(infos))
+	pending := 0
+	for i, info := range infos {
+		if !info.running {
+			continue
+		}
+		pending++
+		go func() {
+			ips, osName := guestDetails(conn, info.Name)
+			results <- details{index: i, ips: ips, osName: osName}
+		}()
+	}
+
+	deadline := time.After(guestDeadline)
+	for ; pending > 0; pending-- {
+		select {
+		case d := <-results:
+			if d.ips != "" {
+				infos[d.index].IP = d.ips
+			}
+			if d.osName != "" {
+				infos[d.index].OS = d.osName
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
+
+// guestDetails returns the IPv4 addresses and OS name reported by the guest
+// agent, or empty strings when the agent does not answer.
+func guestDetails(conn *libvirt.Libvirt, name string) (string, string) {
+	agent, err := NewGuestAgent(conn, name)
+	if err != nil {
+		return "", ""
+	}
+	// Keep `get vm` responsive when an agent is connected but stuck.
+	agent.Timeout = 2
+	if err := agent.Ping(); err != nil {
+		return "", ""
+	}
+
+	var ips, osName string
+	if addrs, err := agent.IPv4Addresses(); err == nil {
+		ips = strings.Join(addrs, ",")
+	}
+	if info, err := agent.OSInfo(); err == nil {
+		osName = info.PrettyName
+	}
+	return ips, osName
+}
+
 func GetVirtualMachines(
 	ctx context.Context,
 	database *sql.DB,
@@ -130,6 +215,7 @@ func GetVirtualMachines(
 		}
 		vms = append(vms, *vmInfo)
 	}
+	addGuestDetails(conn, vms)
 
 	return vms, nil
 }
@@ -0,0 +1,89 @@
+package vms
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// NewHCLFile renders a VM record as a standalone manifest: data blocks for the
+// network and store it uses, followed by a vm block referencing them.
+func NewHCLFile(record db.VirtualMachine, networkName, storeName string) *hclwrite.File {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	body.AppendNewBlock("data", []string{"network", networkName})
+	body.AppendNewBlock("data", []string{"store", storeName})
+	body.AppendNewline()
+
+	vm := body.AppendNewBlock("vm", []string{record.Name}).Body()
+	vm.SetAttributeValue("namespace", cty.StringVal(record.Namespace))
+	vm.SetAttributeValue("image", cty.StringVal(record.Image))
+	vm.SetAttributeValue("cpu", cty.NumberIntVal(int64(record.CPU)))
+	vm.SetAttributeValue("memory", cty.NumberIntVal(int64(record.RAM)))
+	if record.DiskSize != "" {
+		vm.SetAttributeValue("disk", cty.StringVal(record.DiskSize))
+	}
+	vm.SetAttributeTraversal("network", dataTraversal("network", networkName))
+	vm.SetAttributeTraversal("store", dataTraversal("store", storeName))
+	if record.MacAddress != "" {
+		vm.SetAttributeValue("mac", cty.StringVal(record.MacAddress))
+	}
+	if record.IP != "" {
+		vm.SetAttributeValue("ip", cty.StringVal(record.IP))
+	}
+	if record.IPv6 != "" {
+		vm.SetAttributeValue("ipv6", cty.StringVal(record.IPv6))
+	}
+	if record.Autostart {
+		vm.SetAttributeValue("autostart", cty.True)
+	}
+	if len(record.Labels) > 0 {
+		vm.SetAttributeValue("labels", labelsValue(record.Labels))
+	}
+	if check, err := DecodeHealthCheck(record.HealthCheck); err == nil && check != nil {
+		appendHealthCheck(vm, check)
+	}
+
+	return file
+}
+
+// appendHealthCheck writes a healthcheck block, leaving out unset fields.
+func appendHealthCheck(body *hclwrite.Body, check *HealthCheck) {
+	hc := body.AppendNewBlock("healthcheck", nil).Body()
+	hc.SetAttributeValue("type", cty.StringVal(check.Type))
+	if check.Port != 0 {
+		hc.SetAttributeValue("port", cty.NumberIntVal(int64(check.Port)))
+	}
+	for _, attr := range []struct{ name, value string }{
+		{"path", check.Path},
+		{"interval", check.Interval},
+		{"timeout", check.Timeout},
+	} {
+		if attr.value != "" {
+			hc.SetAttributeValue(attr.name, cty.StringVal(attr.value))
+		}
+	}
+	if check.Retries != 0 {
+		hc.SetAttributeValue("retries", cty.NumberIntVal(int64(check.Retries)))
+	}
+}
+
+// dataTraversal builds a data.<kind>.<name> reference.
+func dataTraversal(kind, name string) hcl.Traversal {
+	return hcl.Traversal{
+		hcl.TraverseRoot{Name: "data"},
+		hcl.TraverseAttr{Name: kind},
+		hcl.TraverseAttr{Name: name},
+	}
+}
+
+// labelsValue converts labels into an HCL object (cty keeps attributes sorted).
+func labelsValue(labels map[string]string) cty.Value {
+	attrs := make(map[string]cty.Value, len(labels))
+	for k, v := range labels {
+		attrs[k] = cty.StringVal(v)
+	}
+	return cty.ObjectVal(attrs)
+}
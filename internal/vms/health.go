@@ -0,0 +1,220 @@
+package vms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// Health check types.
+const (
+	CheckTCP   = "tcp"
+	CheckICMP  = "icmp"
+	CheckSSH   = "ssh"
+	CheckAgent = "agent"
+	CheckHTTP  = "http"
+)
+
+const (
+	defaultCheckInterval = 5 * time.Second
+	defaultCheckTimeout  = 3 * time.Second
+	defaultSSHPort       = 22
+	defaultHTTPPort      = 80
+)
+
+// HealthCheck tells when the guest of a VM is actually serving, as opposed
+// to merely running. It is declared with a healthcheck block:
+//
+//	healthcheck {
+//	  type     = "http"
+//	  port     = 8080
+//	  path     = "/healthz"
+//	  interval = "5s"
+//	  timeout  = "2s"
+//	  retries  = 30
+//	}
+type HealthCheck struct {
+	Type     string `hcl:"type" json:"type"`
+	Port     int    `hcl:"port,optional" json:"port,omitempty"`
+	Path     string `hcl:"path,optional" json:"path,omitempty"`
+	Interval string `hcl:"interval,optional" json:"interval,omitempty"` // between probes, default 5s
+	Timeout  string `hcl:"timeout,optional" json:"timeout,omitempty"`   // of one probe, default 3s
+	Retries  int    `hcl:"retries,optional" json:"retries,omitempty"`   // failed probes before giving up, 0 for no limit
+}
+
+// Validate checks the fields make sense for the check type.
+func (h *HealthCheck) Validate() error {
+	if h == nil {
+		return nil
+	}
+	switch h.Type {
+	case CheckTCP:
+		if h.Port <= 0 {
+			return fmt.Errorf("healthcheck: %s requires a port", h.Type)
+		}
+	case CheckHTTP, CheckSSH:
+	case CheckICMP, CheckAgent:
+		if h.Port != 0 {
+			return fmt.Errorf("healthcheck: %s does not take a port", h.Type)
+		}
+	default:
+		return fmt.Errorf(
+			"healthcheck: unknown type %q (supported: tcp, icmp, ssh, agent, http)",
+			h.Type,
+		)
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		return fmt.Errorf("healthcheck: invalid port %d", h.Port)
+	}
+	if h.Path != "" && h.Type != CheckHTTP {
+		return fmt.Errorf("healthcheck: path only applies to http checks")
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("healthcheck: retries must not be negative")
+	}
+	for _, d := range []struct{ name, value string }{
+		{"interval", h.Interval},
+		{"timeout", h.Timeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d.value); err != nil || v <= 0 {
+			return fmt.Errorf("healthcheck: invalid %s %q", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// Encode returns the check as stored in the database; "" for no check.
+func (h *HealthCheck) Encode() (string, error) {
+	if h == nil {
+		return "", nil
+	}
+	raw, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("encode healthcheck: %w", err)
+	}
+	return string(raw), nil
+}
+
+// DecodeHealthCheck parses a check stored by Encode; "" yields nil.
+func DecodeHealthCheck(raw string) (*HealthCheck, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var h HealthCheck
+	if err := json.Unmarshal([]byte(raw), &h); err != nil {
+		return nil, fmt.Errorf("decode healthcheck: %w", err)
+	}
+	return &h, nil
+}
+
+// interval returns the time between two probes.
+func (h *HealthCheck) interval() time.Duration {
+	if d, err := time.ParseDuration(h.Interval); err == nil && d > 0 {
+		return d
+	}
+	return defaultCheckInterval
+}
+
+// timeout returns how long a single probe may take.
+func (h *HealthCheck) timeout() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultCheckTimeout
+}
+
+// port returns the port probed, falling back to the default of the type.
+func (h *HealthCheck) port() int {
+	if h.Port != 0 {
+		return h.Port
+	}
+	switch h.Type {
+	case CheckSSH:
+		return defaultSSHPort
+	case CheckHTTP:
+		return defaultHTTPPort
+	}
+	return 0
+}
+
+// Probe runs the check once. addr is the IP address of the guest; it is
+// not used by agent checks.
+func (h *HealthCheck) Probe(ctx context.Context, conn *libvirt.Libvirt, dom libvirt.Domain, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+
+	hostPort := net.JoinHostPort(addr, strconv.Itoa(h.port()))
+	switch h.Type {
+	case CheckAgent:
+		agent := &GuestAgent{conn: conn, dom: dom, Timeout: int32(max(h.timeout()/time.Second, 1))}
+		return agent.Ping()
+
+	case CheckTCP:
+		var d net.Dialer
+		c, err := d.DialContext(ctx, "tcp", hostPort)
+		if err != nil {
+			return err
+		}
+		return c.Close()
+
+	case CheckSSH:
+		var d net.Dialer
+		c, err := d.DialContext(ctx, "tcp", hostPort)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = c.SetReadDeadline(deadline)
+		}
+		// sshd greets first; a listening port alone may still be a proxy.
+		banner, err := bufio.NewReader(c).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read ssh banner from %s: %w", hostPort, err)
+		}
+		if !strings.HasPrefix(banner, "SSH-") {
+			return fmt.Errorf("%s is not an ssh server", hostPort)
+		}
+		return nil
+
+	case CheckHTTP:
+		path := h.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+hostPort+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+		}
+		return nil
+
+	case CheckICMP:
+		// Raw ICMP sockets need privileges, ping(8) is set up to have them.
+		secs := strconv.Itoa(int(max(h.timeout()/time.Second, 1)))
+		if out, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", secs, addr).CombinedOutput(); err != nil {
+			return fmt.Errorf("ping %s: %w: %s", addr, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return fmt.Errorf("healthcheck: unknown type %q", h.Type)
+}
@@ -0,0 +1,57 @@
+package vms
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckValidate(t *testing.T) {
+	tests := []struct {
+		check   *HealthCheck
+		wantErr bool
+	}{
+		{nil, false},
+		{&HealthCheck{Type: CheckTCP, Port: 6443}, false},
+		{&HealthCheck{Type: CheckTCP}, true},
+		{&HealthCheck{Type: CheckHTTP, Path: "/healthz", Interval: "2s", Timeout: "1s"}, false},
+		{&HealthCheck{Type: CheckSSH}, false},
+		{&HealthCheck{Type: CheckAgent, Retries: 10}, false},
+		{&HealthCheck{Type: CheckAgent, Port: 22}, true},
+		{&HealthCheck{Type: CheckICMP, Path: "/"}, true},
+		{&HealthCheck{Type: CheckTCP, Port: 70000}, true},
+		{&HealthCheck{Type: CheckSSH, Interval: "soon"}, true},
+		{&HealthCheck{Type: CheckSSH, Retries: -1}, true},
+		{&HealthCheck{Type: "udp", Port: 53}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.check.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, wantErr %v", tt.check, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHealthCheckEncoding(t *testing.T) {
+	check := &HealthCheck{Type: CheckHTTP, Port: 8080, Path: "/ready", Interval: "10s", Retries: 3}
+	raw, err := check.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeHealthCheck(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, check) {
+		t.Errorf("round trip = %+v, want %+v", decoded, check)
+	}
+	if decoded.interval() != 10*time.Second || decoded.timeout() != defaultCheckTimeout {
+		t.Errorf("interval %s, timeout %s", decoded.interval(), decoded.timeout())
+	}
+
+	if raw, _ := (*HealthCheck)(nil).Encode(); raw != "" {
+		t.Errorf("nil check encoded as %q", raw)
+	}
+	if none, err := DecodeHealthCheck(""); none != nil || err != nil {
+		t.Errorf("DecodeHealthCheck(\"\") = %v, %v", none, err)
+	}
+}
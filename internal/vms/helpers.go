@@ -48,6 +48,14 @@ func NewVirtualMachineRecord(
 	}
 	diskPath := filepath.Join(store.ImagesPath, vm.Spec.Name+".qcow2")
 
+	if err := vm.Spec.Health.Validate(); err != nil {
+		return nil, fmt.Errorf("vm %q: %w", vm.Spec.Name, err)
+	}
+	healthCheck, err := vm.Spec.Health.Encode()
+	if err != nil {
+		return nil, err
+	}
+
 	return &db.VirtualMachine{
 		Name:      vm.Spec.Name,
 		Namespace: vm.Spec.Namespace,
@@ -55,14 +63,17 @@ func NewVirtualMachineRecord(
 		CPU:       vm.Spec.CPU,
 		RAM:       vm.Spec.Memory,
 		// DiskSize:   vm.Spec.Disk.Size,
-		DiskSize:   vm.Spec.Disk,
-		DiskPath:   diskPath,
-		Image:      vm.Spec.Image,
-		MacAddress: vm.Spec.MAC,
-		IP:         vm.Spec.IP,
-		NetworkID:  networkID,
-		StoreID:    storeID,
-		CreatedAt:  time.Now(),
+		DiskSize:    vm.Spec.Disk,
+		DiskPath:    diskPath,
+		Image:       vm.Spec.Image,
+		MacAddress:  vm.Spec.MAC,
+		IP:          vm.Spec.IP,
+		IPv6:        vm.Spec.IPv6,
+		NetworkID:   networkID,
+		StoreID:     storeID,
+		HealthCheck: healthCheck,
+		Autostart:   vm.Spec.Autostart,
+		CreatedAt:   time.Now(),
 	}, nil
 }
 
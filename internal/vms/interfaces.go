@@ -0,0 +1,84 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	"github.com/kebairia/kvmcli/internal/network"
+)
+
+// interfacesXML is the subset of the domain XML describing network interfaces.
+type interfacesXML struct {
+	Devices struct {
+		Interfaces []struct {
+			MAC struct {
+				Address string `xml:"address,attr"`
+			} `xml:"mac"`
+		} `xml:"interface"`
+	} `xml:"devices"`
+}
+
+// InterfaceMACs returns the MAC addresses of every interface of the named domain,
+// including the ones libvirt generated because none was configured.
+func InterfaceMACs(conn *libvirt.Libvirt, name string) ([]string, error) {
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	raw, err := conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get XML for %q: %w", name, err)
+	}
+	return ParseInterfaceMACs(raw)
+}
+
+// ParseInterfaceMACs returns the MAC addresses found in a domain XML document.
+func ParseInterfaceMACs(domainXML string) ([]string, error) {
+	var desc interfacesXML
+	if err := xml.Unmarshal([]byte(domainXML), &desc); err != nil {
+		return nil, fmt.Errorf("parse domain XML: %w", err)
+	}
+
+	macs := make([]string, 0, len(desc.Devices.Interfaces))
+	for _, iface := range desc.Devices.Interfaces {
+		if iface.MAC.Address != "" {
+			macs = append(macs, iface.MAC.Address)
+		}
+	}
+	return macs, nil
+}
+
+// Address returns the IP recorded for the VM, or the one currently
+// leased to it by the network's DHCP server.
+func Address(
+	ctx context.Context,
+	database *sql.DB,
+	conn *libvirt.Libvirt,
+	record db.VirtualMachine,
+) (string, error) {
+	if record.IP != "" {
+		return record.IP, nil
+	}
+
+	netName, err := db.GetNetworkNameByID(ctx, database, record.NetworkID)
+	if err != nil {
+		return "", err
+	}
+
+	macs := []string{record.MacAddress}
+	if record.MacAddress == "" {
+		if macs, err = InterfaceMACs(conn, record.Name); err != nil {
+			return "", err
+		}
+	}
+
+	ip, err := network.LeaseIP(conn, netName, macs)
+	if err != nil {
+		return "", fmt.Errorf("vm %q has no recorded IP: %w", record.Name, err)
+	}
+	return ip, nil
+}
@@ -0,0 +1,242 @@
+package vms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	log "github.com/kebairia/kvmcli/internal/logger"
+)
+
+const (
+	// DefaultStopTimeout is how long Stop waits for the guest to power off.
+	DefaultStopTimeout = 60 * time.Second
+	statePollInterval  = 500 * time.Millisecond
+)
+
+// ErrStopTimeout is returned when the guest ignored the shutdown requests
+// and Stop was not allowed to force it off.
+var ErrStopTimeout = errors.New("domain did not shut down in time")
+
+// StopOptions controls how a domain is shut down.
+type StopOptions struct {
+	Timeout time.Duration // total time allowed for a graceful shutdown; defaults to DefaultStopTimeout
+	Force   bool          // destroy the domain when the graceful shutdown times out
+}
+
+// Stop asks the guest to power off, first through an ACPI power button press
+// and then through the guest agent, and waits for the domain to be shut off.
+// Each method gets half of the timeout. When the guest is still running
+// afterwards, the domain is destroyed if opts.Force is set.
+func (m *LibvirtDomainManager) Stop(ctx context.Context, name string, opts StopOptions) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	status, err := GetDomainStatus(m.conn, dom)
+	if err != nil {
+		return err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+
+	// Bring the guest to a state where it can react to a shutdown request.
+	switch status.State {
+	case StateShutOff:
+		return nil
+	case StateCrashed:
+		// Nothing left to shut down gracefully, only the QEMU process to clean up.
+		return m.Destroy(ctx, name)
+	case StateShuttingDown:
+		off, err := m.waitShutOff(ctx, dom, timeout)
+		if err != nil || off {
+			return err
+		}
+		return m.escalate(dom, timeout, opts.Force)
+	case StatePaused:
+		if err := m.conn.DomainResume(dom); err != nil {
+			return fmt.Errorf("resume domain %q before shutdown: %w", name, err)
+		}
+	case StatePMSuspended:
+		if err := m.conn.DomainPmWakeup(dom, 0); err != nil {
+			return fmt.Errorf("wake up domain %q before shutdown: %w", name, err)
+		}
+	}
+
+	methods := []struct {
+		name  string
+		flags libvirt.DomainShutdownFlagValues
+	}{
+		{"acpi", libvirt.DomainShutdownAcpiPowerBtn},
+		{"guest agent", libvirt.DomainShutdownGuestAgent},
+	}
+	for _, method := range methods {
+		if err := m.conn.DomainShutdownFlags(dom, method.flags); err != nil {
+			log.Debugf("%s shutdown of %s failed: %v", method.name, name, err)
+			continue
+		}
+		log.Debugf("%s shutdown of %s requested", method.name, name)
+
+		off, err := m.waitShutOff(ctx, dom, timeout/time.Duration(len(methods)))
+		if err != nil {
+			return err
+		}
+		if off {
+			return nil
+		}
+	}
+
+	return m.escalate(dom, timeout, opts.Force)
+}
+
+// escalate destroys a domain that ignored the shutdown requests when force
+// is set, and reports ErrStopTimeout otherwise.
+func (m *LibvirtDomainManager) escalate(dom libvirt.Domain, timeout time.Duration, force bool) error {
+	if !force {
+		return fmt.Errorf("stop domain %q after %s: %w", dom.Name, timeout, ErrStopTimeout)
+	}
+	log.Warnf("%s did not shut down within %s, destroying it", dom.Name, timeout)
+	if err := m.conn.DomainDestroy(dom); err != nil {
+		return fmt.Errorf("destroy domain %q: %w", dom.Name, err)
+	}
+	return nil
+}
+
+// Restart stops the domain like Stop, then starts it again.
+func (m *LibvirtDomainManager) Restart(ctx context.Context, name string, opts StopOptions) error {
+	if err := m.Stop(ctx, name, opts); err != nil {
+		return err
+	}
+	return m.Start(ctx, name)
+}
+
+// WhileStopped shuts the domain down like Stop, runs fn and brings the
+// domain back to the state it had: a domain that was running is started
+// again, even when fn fails, so that a failed change does not leave the VM
+// down; a domain that was shut off stays shut off.
+func WhileStopped(
+	ctx context.Context,
+	domains DomainManager,
+	name string,
+	opts StopOptions,
+	fn func() error,
+) error {
+	status, err := domains.State(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !status.State.IsActive() {
+		return fn()
+	}
+	if err := domains.Stop(ctx, name, opts); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return errors.Join(err, domains.Start(ctx, name))
+	}
+	return domains.Start(ctx, name)
+}
+
+// Pause freezes the vCPUs of the domain, keeping it in memory.
+func (m *LibvirtDomainManager) Pause(ctx context.Context, name string) error {
+	dom, err := m.lookupInState(name, "pause", StateRunning, StateBlocked)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainSuspend(dom); err != nil {
+		return fmt.Errorf("pause domain %q: %w", name, err)
+	}
+	return nil
+}
+
+// Resume unfreezes a paused domain.
+func (m *LibvirtDomainManager) Resume(ctx context.Context, name string) error {
+	dom, err := m.lookupInState(name, "resume", StatePaused)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainResume(dom); err != nil {
+		return fmt.Errorf("resume domain %q: %w", name, err)
+	}
+	return nil
+}
+
+// Suspend saves the domain state to disk (managed save) and stops it.
+func (m *LibvirtDomainManager) Suspend(ctx context.Context, name string) error {
+	dom, err := m.lookupInState(name, "suspend", StateRunning, StateBlocked, StatePaused)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainManagedSave(dom, 0); err != nil {
+		return fmt.Errorf("suspend domain %q: %w", name, err)
+	}
+	return nil
+}
+
+// Reset hard-resets the domain without a guest shutdown.
+func (m *LibvirtDomainManager) Reset(ctx context.Context, name string) error {
+	dom, err := m.lookupInState(name, "reset", StateRunning, StateBlocked, StatePaused)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainReset(dom, 0); err != nil {
+		return fmt.Errorf("reset domain %q: %w", name, err)
+	}
+	return nil
+}
+
+// lookupInState looks the domain up and refuses the action unless the
+// domain is in one of the allowed states.
+func (m *LibvirtDomainManager) lookupInState(
+	name, action string,
+	allowed ...DomainState,
+) (libvirt.Domain, error) {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return dom, fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	status, err := GetDomainStatus(m.conn, dom)
+	if err != nil {
+		return dom, err
+	}
+	if !slices.Contains(allowed, status.State) {
+		return dom, fmt.Errorf("cannot %s domain %q: it is %s", action, name, status)
+	}
+	return dom, nil
+}
+
+// waitShutOff polls the domain until it is shut off or timeout elapses.
+// It reports whether the domain reached shut off.
+func (m *LibvirtDomainManager) waitShutOff(
+	ctx context.Context,
+	dom libvirt.Domain,
+	timeout time.Duration,
+) (bool, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(statePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("stop domain %q: %w", dom.Name, ctx.Err())
+		case <-deadline.C:
+			return false, nil
+		case <-ticker.C:
+		}
+		status, err := GetDomainStatus(m.conn, dom)
+		if err != nil {
+			return false, err
+		}
+		if status.State == StateShutOff {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+const (
+	// DefaultReadyTimeout is how long WaitReady waits for a guest to come up.
+	DefaultReadyTimeout = 5 * time.Minute
+	readyPollInterval   = 2 * time.Second
+)
+
+// ErrNotReady is returned when a guest did not become ready in time.
+var ErrNotReady = errors.New("domain is not ready")
+
+// WaitReady waits until the domain is running and its guest is serving:
+// its health check passes or, for a VM without one, the guest agent
+// answers or the domain holds a DHCP lease.
+func WaitReady(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	name string,
+	timeout time.Duration,
+) error {
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+
+	// VMs kvmcli does not track get the default check.
+	var check *HealthCheck
+	record, err := db.GetVMByName(ctx, database, name, "")
+	switch {
+	case err == nil:
+		if check, err = DecodeHealthCheck(record.HealthCheck); err != nil {
+			return fmt.Errorf("vm %q: %w", name, err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	interval := readyPollInterval
+	if check != nil {
+		interval = check.interval()
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		failures int
+		lastErr  error
+	)
+	for {
+		status, err := GetDomainStatus(conn, dom)
+		if err != nil {
+			return err
+		}
+		switch status.State {
+		case StateRunning, StateBlocked:
+			lastErr = probeGuest(ctx, conn, database, dom, record, check)
+			if lastErr == nil {
+				return nil
+			}
+			failures++
+			if check != nil && check.Retries > 0 && failures >= check.Retries {
+				return fmt.Errorf("%q failed %d %s checks: %w: %v", name, failures, check.Type, ErrNotReady, lastErr)
+			}
+		case StateShutOff, StateCrashed:
+			// Waiting would not change that.
+			return fmt.Errorf("domain %q is %s", name, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %q: %w", name, ctx.Err())
+		case <-deadline.C:
+			if lastErr != nil {
+				return fmt.Errorf("%q after %s: %w: %v", name, timeout, ErrNotReady, lastErr)
+			}
+			return fmt.Errorf("%q after %s: %w", name, timeout, ErrNotReady)
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeGuest checks once whether the guest of a running domain is up.
+func probeGuest(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	dom libvirt.Domain,
+	record db.VirtualMachine,
+	check *HealthCheck,
+) error {
+	if check == nil {
+		agent := &GuestAgent{conn: conn, dom: dom, Timeout: 1}
+		if agent.Ping() == nil {
+			return nil
+		}
+		ifaces, err := conn.DomainInterfaceAddresses(dom, uint32(libvirt.DomainInterfaceAddressesSrcLease), 0)
+		if err == nil {
+			for _, iface := range ifaces {
+				if len(iface.Addrs) > 0 {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("no guest agent and no DHCP lease yet")
+	}
+
+	var addr string
+	if check.Type != CheckAgent {
+		var err error
+		if addr, err = Address(ctx, database, conn, record); err != nil {
+			return err
+		}
+	}
+	return check.Probe(ctx, conn, dom, addr)
+}
+
+// WaitState waits until the domain reaches the wanted state.
+func WaitState(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	name string,
+	want DomainState,
+	timeout time.Duration,
+) error {
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(statePollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := GetDomainStatus(conn, dom)
+		if err != nil {
+			return err
+		}
+		if status.State == want {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %q: %w", name, ctx.Err())
+		case <-deadline.C:
+			return fmt.Errorf("%q is still %s after %s", name, status, timeout)
+		case <-ticker.C:
+		}
+	}
+}
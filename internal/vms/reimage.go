@@ -0,0 +1,178 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+)
+
+// ErrReimagePending is returned when a VM still has a re-image that was
+// neither confirmed nor rolled back.
+var ErrReimagePending = errors.New("re-image not confirmed")
+
+// Reimage replaces the system disk of a shut off VM with a fresh overlay on
+// the base file of image. The overlay keeps its path, so the domain
+// definition, and with it the MAC address, the DHCP reservation and any
+// extra data disks, is left untouched. The previous overlay is kept next
+// to the new one until ConfirmReimage drops it or RollbackReimage puts it
+// back.
+func Reimage(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	disk DiskManager,
+	name, image string,
+) error {
+	record, err := stoppedRecord(ctx, conn, database, name, "re-imaging")
+	if err != nil {
+		return err
+	}
+	if record.PreviousImage != "" {
+		return fmt.Errorf("vm %q: %w from %s; confirm or roll it back first",
+			name, ErrReimagePending, record.PreviousImage)
+	}
+	// Internal snapshots live inside the overlay that is about to be replaced.
+	snapshots, err := db.GetSnapshotsByVM(ctx, database, record.ID)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		return fmt.Errorf("vm %q has %d snapshot(s); delete them before re-imaging", name, len(snapshots))
+	}
+
+	img, err := db.GetImage(ctx, database, image)
+	if err != nil {
+		return fmt.Errorf("fetch image %q: %w", image, err)
+	}
+	src := filepath.Join(img.ArtifactsPath, img.ImageFile)
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("image %q: %w", image, err)
+	}
+
+	previous := PreviousOverlayPath(record.DiskPath)
+	if exists(previous) {
+		return fmt.Errorf("vm %q: %s already exists", name, previous)
+	}
+	if err := os.Rename(record.DiskPath, previous); err != nil {
+		return fmt.Errorf("keep overlay of %q: %w", name, err)
+	}
+	restore := func() {
+		if err := os.Rename(previous, record.DiskPath); err != nil {
+			log.Errorf("restore overlay of %s: %v", name, err)
+		}
+	}
+	if err := disk.CreateOverlay(ctx, src, record.DiskPath); err != nil {
+		restore()
+		return fmt.Errorf("create disk overlay: %w", err)
+	}
+
+	if err := db.SetImage(ctx, database, name, record.Namespace, image, img.StoreID, record.Image); err != nil {
+		if err := disk.DeleteOverlay(ctx, record.DiskPath); err != nil {
+			log.Errorf("%v", err)
+		}
+		restore()
+		return err
+	}
+	return nil
+}
+
+// ConfirmReimage settles the last re-image of a VM and deletes the overlay
+// it replaced.
+func ConfirmReimage(ctx context.Context, database *sql.DB, disk DiskManager, name string) error {
+	record, err := db.GetVMByName(ctx, database, name, "")
+	if err != nil {
+		return err
+	}
+	if record.PreviousImage == "" {
+		return fmt.Errorf("vm %q has no re-image to confirm", name)
+	}
+	previous := PreviousOverlayPath(record.DiskPath)
+	if exists(previous) {
+		if err := disk.DeleteOverlay(ctx, previous); err != nil {
+			return err
+		}
+	}
+	return db.SetPreviousImage(ctx, database, name, record.Namespace, "")
+}
+
+// RollbackReimage puts the overlay replaced by the last re-image of a shut
+// off VM back in place, with the image it was based on.
+func RollbackReimage(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	disk DiskManager,
+	name string,
+) error {
+	record, err := stoppedRecord(ctx, conn, database, name, "rolling back")
+	if err != nil {
+		return err
+	}
+	if record.PreviousImage == "" {
+		return fmt.Errorf("vm %q has no re-image to roll back", name)
+	}
+	previous := PreviousOverlayPath(record.DiskPath)
+	if _, err := os.Stat(previous); err != nil {
+		return fmt.Errorf("vm %q: overlay to roll back to: %w", name, err)
+	}
+	img, err := db.GetImage(ctx, database, record.PreviousImage)
+	if err != nil {
+		return fmt.Errorf("fetch image %q: %w", record.PreviousImage, err)
+	}
+
+	if err := disk.DeleteOverlay(ctx, record.DiskPath); err != nil {
+		return err
+	}
+	if err := os.Rename(previous, record.DiskPath); err != nil {
+		return fmt.Errorf("restore overlay of %q: %w", name, err)
+	}
+	return db.SetImage(ctx, database, name, record.Namespace, record.PreviousImage, img.StoreID, "")
+}
+
+// PreviousOverlayPath returns where Reimage keeps the overlay it replaced.
+func PreviousOverlayPath(diskPath string) string {
+	base := strings.TrimSuffix(diskPath, filepath.Ext(diskPath))
+	return base + ".previous" + filepath.Ext(diskPath)
+}
+
+// stoppedRecord returns the record of a VM whose domain is shut off.
+func stoppedRecord(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	name, action string,
+) (db.VirtualMachine, error) {
+	record, err := db.GetVMByName(ctx, database, name, "")
+	if err != nil {
+		return record, err
+	}
+	if record.DiskPath == "" {
+		return record, fmt.Errorf("vm %q has no recorded disk path", name)
+	}
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return record, fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	active, err := conn.DomainIsActive(dom)
+	if err != nil {
+		return record, fmt.Errorf("get state for %q: %w", name, err)
+	}
+	if active == 1 {
+		return record, fmt.Errorf("vm %q is running; stop it before %s", name, action)
+	}
+	return record, nil
+}
+
+// exists reports whether a file is present at path.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
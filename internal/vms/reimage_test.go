@@ -0,0 +1,72 @@
+package vms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPreviousOverlayPath(t *testing.T) {
+	tests := map[string]string{
+		"/var/lib/kvmcli/images/k8s-worker-01.qcow2": "/var/lib/kvmcli/images/k8s-worker-01.previous.qcow2",
+		"/var/lib/kvmcli/images/disk":                "/var/lib/kvmcli/images/disk.previous",
+	}
+	for in, want := range tests {
+		if got := PreviousOverlayPath(in); got != want {
+			t.Errorf("PreviousOverlayPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// powerDomains records the power actions WhileStopped takes on a domain.
+type powerDomains struct {
+	DomainManager
+	state   DomainState
+	actions []string
+}
+
+func (d *powerDomains) State(ctx context.Context, name string) (DomainStatus, error) {
+	return DomainStatus{State: d.state}, nil
+}
+
+func (d *powerDomains) Stop(ctx context.Context, name string, opts StopOptions) error {
+	d.actions = append(d.actions, "stop")
+	d.state = StateShutOff
+	return nil
+}
+
+func (d *powerDomains) Start(ctx context.Context, name string) error {
+	d.actions = append(d.actions, "start")
+	d.state = StateRunning
+	return nil
+}
+
+func TestWhileStopped(t *testing.T) {
+	tests := []struct {
+		state DomainState
+		fnErr error
+		want  string
+	}{
+		{StateRunning, nil, "stop fn start"},
+		{StateRunning, errors.New("no space left"), "stop fn start"},
+		{StateShutOff, nil, "fn"},
+		{StateShutOff, errors.New("no space left"), "fn"},
+	}
+	for _, tt := range tests {
+		domains := &powerDomains{state: tt.state}
+		err := WhileStopped(context.Background(), domains, "web-01", StopOptions{}, func() error {
+			domains.actions = append(domains.actions, "fn")
+			return tt.fnErr
+		})
+		if !errors.Is(err, tt.fnErr) || (tt.fnErr == nil && err != nil) {
+			t.Errorf("%s, fn error %v: WhileStopped() error = %v", tt.state, tt.fnErr, err)
+		}
+		if got := strings.Join(domains.actions, " "); got != tt.want {
+			t.Errorf("%s, fn error %v: actions = %q, want %q", tt.state, tt.fnErr, got, tt.want)
+		}
+		if domains.state != tt.state {
+			t.Errorf("%s, fn error %v: left the domain %s", tt.state, tt.fnErr, domains.state)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+	"github.com/kebairia/kvmcli/internal/templates"
+)
+
+// SnapshotOptions describes a snapshot to take.
+type SnapshotOptions struct {
+	Name        string // defaults to a timestamp-based name
+	Description string
+	DiskOnly    bool // external disk-only snapshot instead of an internal qcow2 one
+}
+
+// SnapshotManager defines the interface for managing VM snapshots.
+type SnapshotManager interface {
+	// Create takes a snapshot of the VM and records it.
+	Create(ctx context.Context, vmName string, opts SnapshotOptions) (*db.Snapshot, error)
+
+	// Revert rolls the VM back to the named snapshot.
+	Revert(ctx context.Context, vmName, snapshot string) error
+
+	// Delete removes the named snapshot and its record.
+	Delete(ctx context.Context, vmName, snapshot string) error
+
+	// List returns the recorded snapshots of the VM, oldest first.
+	List(ctx context.Context, vmName string) ([]db.Snapshot, error)
+}
+
+// LibvirtSnapshotManager implements SnapshotManager using libvirt and a SQL database.
+type LibvirtSnapshotManager struct {
+	conn *libvirt.Libvirt
+	db   *sql.DB
+}
+
+// NewLibvirtSnapshotManager creates a new LibvirtSnapshotManager.
+func NewLibvirtSnapshotManager(conn *libvirt.Libvirt, db *sql.DB) *LibvirtSnapshotManager {
+	return &LibvirtSnapshotManager{conn: conn, db: db}
+}
+
+// Create takes an internal snapshot, or an external disk-only one when opts.DiskOnly is set.
+func (m *LibvirtSnapshotManager) Create(
+	ctx context.Context,
+	vmName string,
+	opts SnapshotOptions,
+) (*db.Snapshot, error) {
+	record, err := db.GetVMByName(ctx, m.db, vmName, "")
+	if err != nil {
+		return nil, err
+	}
+	dom, err := m.conn.DomainLookupByName(vmName)
+	if err != nil {
+		return nil, fmt.Errorf("lookup domain %q: %w", vmName, err)
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = "snap-" + time.Now().Format("20060102-150405")
+	}
+
+	status, err := GetDomainStatus(m.conn, dom)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &db.Snapshot{
+		VMID:        record.ID,
+		Name:        name,
+		Kind:        db.SnapshotInternal,
+		Description: opts.Description,
+		State:       status.State.String(),
+		CreatedAt:   time.Now(),
+	}
+
+	var (
+		spec  *templates.DomainSnapshot
+		flags uint32
+	)
+	if opts.DiskOnly {
+		snap.Kind = db.SnapshotExternal
+		snap.DiskPath = externalOverlayPath(record.DiskPath, vmName, name)
+		spec = templates.NewExternalSnapshot(name, opts.Description, deviceName, snap.DiskPath)
+		flags = uint32(libvirt.DomainSnapshotCreateDiskOnly | libvirt.DomainSnapshotCreateAtomic)
+	} else {
+		spec = templates.NewInternalSnapshot(name, opts.Description)
+	}
+
+	xmlConfig, err := spec.GenerateXML()
+	if err != nil {
+		return nil, fmt.Errorf("generate snapshot XML for %q: %w", vmName, err)
+	}
+	// A disk-only snapshot of a running guest is only crash-consistent unless
+	// the guest agent flushes and freezes the file systems around it.
+	// They are thawed as soon as the snapshot is taken, whatever the outcome.
+	var thaw func()
+	if opts.DiskOnly && status.State == StateRunning {
+		thaw = m.freeze(vmName)
+	}
+	_, err = m.conn.DomainSnapshotCreateXML(dom, string(xmlConfig), flags)
+	if thaw != nil {
+		thaw()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot %q of %q: %w", name, vmName, err)
+	}
+
+	if err := snap.Insert(ctx, m.db); err != nil {
+		// Keep libvirt and the database in sync.
+		if s, lerr := m.conn.DomainSnapshotLookupByName(dom, name, 0); lerr == nil {
+			_ = m.conn.DomainSnapshotDelete(s, libvirt.DomainSnapshotDeleteMetadataOnly)
+		}
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+// Revert rolls the VM back to the named snapshot.
+func (m *LibvirtSnapshotManager) Revert(ctx context.Context, vmName, snapshot string) error {
+	dom, err := m.conn.DomainLookupByName(vmName)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", vmName, err)
+	}
+	snap, err := m.conn.DomainSnapshotLookupByName(dom, snapshot, 0)
+	if err != nil {
+		return fmt.Errorf("lookup snapshot %q of %q: %w", snapshot, vmName, err)
+	}
+	if err := m.conn.DomainRevertToSnapshot(snap, 0); err != nil {
+		return fmt.Errorf("revert %q to snapshot %q: %w", vmName, snapshot, err)
+	}
+	return nil
+}
+
+// Delete removes the named snapshot from libvirt and from the database.
+func (m *LibvirtSnapshotManager) Delete(ctx context.Context, vmName, snapshot string) error {
+	record, err := db.GetVMByName(ctx, m.db, vmName, "")
+	if err != nil {
+		return err
+	}
+	dom, err := m.conn.DomainLookupByName(vmName)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", vmName, err)
+	}
+	snap, err := m.conn.DomainSnapshotLookupByName(dom, snapshot, 0)
+	if err != nil {
+		return fmt.Errorf("lookup snapshot %q of %q: %w", snapshot, vmName, err)
+	}
+	if err := m.conn.DomainSnapshotDelete(snap, 0); err != nil {
+		return fmt.Errorf("delete snapshot %q of %q: %w", snapshot, vmName, err)
+	}
+
+	rec := &db.Snapshot{VMID: record.ID, Name: snapshot}
+	return rec.Delete(ctx, m.db)
+}
+
+// List returns the recorded snapshots of the VM, oldest first.
+func (m *LibvirtSnapshotManager) List(ctx context.Context, vmName string) ([]db.Snapshot, error) {
+	record, err := db.GetVMByName(ctx, m.db, vmName, "")
+	if err != nil {
+		return nil, err
+	}
+	return db.GetSnapshotsByVM(ctx, m.db, record.ID)
+}
+
+// freeze freezes the guest file systems through the guest agent and returns
+// the function that thaws them, or nil when the agent is not available.
+func (m *LibvirtSnapshotManager) freeze(vmName string) func() {
+	agent, err := NewGuestAgent(m.conn, vmName)
+	if err != nil {
+		return nil
+	}
+	if err := agent.Ping(); err != nil {
+		log.Debugf("snapshot of %s without fsfreeze: %v", vmName, err)
+		return nil
+	}
+	if _, err := agent.FSFreeze(); err != nil {
+		log.Warnf("fsfreeze of %s failed, snapshot is crash-consistent only: %v", vmName, err)
+		return nil
+	}
+	return func() {
+		if _, err := agent.FSThaw(); err != nil {
+			log.Errorf("fsthaw of %s failed: %v", vmName, err)
+		}
+	}
+}
+
+// externalOverlayPath places the overlay next to the VM disk: <dir>/<vm>.<snapshot>.qcow2.
+func externalOverlayPath(diskPath, vmName, snapshot string) string {
+	dir := filepath.Dir(diskPath)
+	base := strings.TrimSuffix(filepath.Base(diskPath), filepath.Ext(diskPath))
+	if base == "" || base == "." {
+		base = vmName
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.qcow2", base, snapshot))
+}
@@ -0,0 +1,72 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kebairia/kvmcli/internal/common"
+	db "github.com/kebairia/kvmcli/internal/database"
+)
+
+// SnapshotInfo holds everything we need to print one snapshot row.
+type SnapshotInfo struct {
+	Name        string
+	VM          string
+	Namespace   string
+	Type        string
+	State       string
+	Description string
+	Age         string
+}
+
+func (info *SnapshotInfo) Header() *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVM\tNAMESPACE\tTYPE\tSTATE\tDESCRIPTION\tAGE")
+	return w
+}
+
+func (info *SnapshotInfo) PrintInfo(w *tabwriter.Writer) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		info.Name,
+		info.VM,
+		info.Namespace,
+		info.Type,
+		info.State,
+		info.Description,
+		info.Age,
+	)
+}
+
+// NewSnapshotInfo constructs a SnapshotInfo from a database record.
+func NewSnapshotInfo(record db.Snapshot) *SnapshotInfo {
+	return &SnapshotInfo{
+		Name:        record.Name,
+		VM:          record.VMName,
+		Namespace:   record.Namespace,
+		Type:        record.Kind,
+		State:       record.State,
+		Description: record.Description,
+		Age:         common.FormatAge(record.CreatedAt),
+	}
+}
+
+// GetSnapshots returns the snapshots of every VM, optionally filtered by namespace.
+func GetSnapshots(
+	ctx context.Context,
+	database *sql.DB,
+	namespace string,
+) ([]SnapshotInfo, error) {
+	records, err := db.GetSnapshots(ctx, database, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot records: %w", err)
+	}
+
+	snapshots := make([]SnapshotInfo, 0, len(records))
+	for _, rec := range records {
+		snapshots = append(snapshots, *NewSnapshotInfo(rec))
+	}
+	return snapshots, nil
+}
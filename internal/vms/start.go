@@ -5,7 +5,9 @@ import (
 )
 
 func (vm *VirtualMachine) Start() error {
-	vm.domain.Start(vm.ctx, vm.Spec.Name)
+	if err := vm.domain.Start(vm.ctx, vm.Spec.Name); err != nil {
+		return err
+	}
 	fmt.Printf("vm/%s started\n", vm.Spec.Name)
 	// cfg.NetworksByName, cfg.VMsByName, cfg.ClustersByName are now available
 	return nil
@@ -0,0 +1,111 @@
+package vms
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// DomainState is the lifecycle state of a libvirt domain (virDomainState).
+type DomainState int
+
+// Domain states, numbered as in libvirt.
+const (
+	StateNoState DomainState = iota
+	StateRunning
+	StateBlocked
+	StatePaused
+	StateShuttingDown
+	StateShutOff
+	StateCrashed
+	StatePMSuspended
+)
+
+var stateNames = map[DomainState]string{
+	StateNoState:      "No state",
+	StateRunning:      "Running",
+	StateBlocked:      "Blocked",
+	StatePaused:       "Paused",
+	StateShuttingDown: "Shutting down",
+	StateShutOff:      "Shut off",
+	StateCrashed:      "Crashed",
+	StatePMSuspended:  "Suspended (PM)",
+}
+
+// stateReasons names the reason codes libvirt reports for each state.
+// Index 0 is always "unknown" and is not displayed.
+var stateReasons = map[DomainState][]string{
+	StateRunning: {
+		"unknown", "booted", "migrated", "restored", "from snapshot", "unpaused",
+		"migration canceled", "save canceled", "woken up", "crashed", "post-copy",
+		"post-copy failed",
+	},
+	StatePaused: {
+		"unknown", "user", "migration", "save", "dump", "I/O error", "watchdog",
+		"from snapshot", "shutting down", "snapshot", "crashed", "starting up",
+		"post-copy", "post-copy failed", "API error",
+	},
+	StateShuttingDown: {"unknown", "user"},
+	StateShutOff: {
+		"unknown", "shutdown", "destroyed", "crashed", "migrated", "saved", "failed",
+		"from snapshot", "daemon",
+	},
+	StateCrashed: {"unknown", "panicked"},
+}
+
+// String returns the human-readable name of the state.
+func (s DomainState) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", int(s))
+}
+
+// IsActive reports whether the domain has a running QEMU process,
+// including paused and PM-suspended domains.
+func (s DomainState) IsActive() bool {
+	switch s {
+	case StateRunning, StateBlocked, StatePaused, StateShuttingDown, StatePMSuspended:
+		return true
+	}
+	return false
+}
+
+// DomainStatus is a domain state together with the reason libvirt gives for it.
+type DomainStatus struct {
+	State  DomainState
+	Reason int
+}
+
+// NewDomainStatus builds a DomainStatus from the raw values of DomainGetState.
+func NewDomainStatus(state, reason int32) DomainStatus {
+	return DomainStatus{State: DomainState(state), Reason: int(reason)}
+}
+
+// ReasonString returns the name of the reason, or "" when it is unknown.
+func (s DomainStatus) ReasonString() string {
+	reasons := stateReasons[s.State]
+	if s.Reason <= 0 || s.Reason >= len(reasons) {
+		return ""
+	}
+	return reasons[s.Reason]
+}
+
+// String renders the state with its reason, e.g. "Shut off (destroyed)".
+// The reason of a running domain is left out: it only says how it got there.
+func (s DomainStatus) String() string {
+	reason := s.ReasonString()
+	if reason == "" || s.State == StateRunning {
+		return s.State.String()
+	}
+	return fmt.Sprintf("%s (%s)", s.State, reason)
+}
+
+// GetDomainStatus returns the current state and reason of a domain.
+func GetDomainStatus(conn *libvirt.Libvirt, dom libvirt.Domain) (DomainStatus, error) {
+	state, reason, err := conn.DomainGetState(dom, 0)
+	if err != nil {
+		return DomainStatus{}, fmt.Errorf("get state for %q: %w", dom.Name, err)
+	}
+	return NewDomainStatus(state, reason), nil
+}
@@ -0,0 +1,35 @@
+package vms
+
+import "testing"
+
+func TestDomainStatusString(t *testing.T) {
+	tests := []struct {
+		state, reason int32
+		want          string
+		active        bool
+	}{
+		{1, 1, "Running", true},
+		{2, 0, "Blocked", true},
+		{3, 1, "Paused (user)", true},
+		{3, 5, "Paused (I/O error)", true},
+		{4, 1, "Shutting down (user)", true},
+		{5, 0, "Shut off", false},
+		{5, 2, "Shut off (destroyed)", false},
+		{5, 5, "Shut off (saved)", false},
+		{6, 1, "Crashed (panicked)", false},
+		{7, 0, "Suspended (PM)", true},
+		{0, 0, "No state", false},
+		{42, 0, "Unknown (42)", false},
+		{5, 99, "Shut off", false}, // reason from a newer libvirt
+	}
+
+	for _, tt := range tests {
+		status := NewDomainStatus(tt.state, tt.reason)
+		if got := status.String(); got != tt.want {
+			t.Errorf("NewDomainStatus(%d, %d).String() = %q, want %q", tt.state, tt.reason, got, tt.want)
+		}
+		if got := status.State.IsActive(); got != tt.active {
+			t.Errorf("state %d IsActive() = %v, want %v", tt.state, got, tt.active)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package vms
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+	db "github.com/kebairia/kvmcli/internal/database"
+	log "github.com/kebairia/kvmcli/internal/logger"
+)
+
+// Update reconciles the CPU and memory of an existing VM with its configuration.
+// Changes within the domain's maximums are applied live; anything else
+// rewrites the persistent definition and, for a running VM, flags it as
+// needing a restart.
+func (vm *VirtualMachine) Update() error {
+	record, err := db.GetVMByName(vm.ctx, vm.db, vm.Spec.Name, vm.Spec.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := validateMaximums(vm.Spec); err != nil {
+		return err
+	}
+	if err := vm.Spec.Health.Validate(); err != nil {
+		return fmt.Errorf("vm %q: %w", vm.Spec.Name, err)
+	}
+	healthCheck, err := vm.Spec.Health.Encode()
+	if err != nil {
+		return err
+	}
+
+	dom, err := vm.conn.DomainLookupByName(vm.Spec.Name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", vm.Spec.Name, err)
+	}
+	maxCPU, maxMemory, err := domainMaximums(vm.conn, dom)
+	if err != nil {
+		return err
+	}
+	wantMaxCPU, wantMaxMemory := max(vm.Spec.MaxCPU, vm.Spec.CPU), max(vm.Spec.MaxMemory, vm.Spec.Memory)
+
+	maximumsChanged := wantMaxCPU != maxCPU || wantMaxMemory != maxMemory
+	resized := vm.Spec.CPU != record.CPU || vm.Spec.Memory != record.RAM
+	healthChanged := healthCheck != record.HealthCheck
+	autostartChanged := vm.Spec.Autostart != record.Autostart
+	if !maximumsChanged && !resized && !healthChanged && !autostartChanged {
+		fmt.Printf("vm/%s unchanged\n", vm.Spec.Name)
+		return nil
+	}
+
+	// The health check is only used by kvmcli, the domain is not touched.
+	if healthChanged {
+		if err := db.SetHealthCheck(vm.ctx, vm.db, vm.Spec.Name, record.Namespace, healthCheck); err != nil {
+			return err
+		}
+	}
+	// Autostart applies to the domain right away, running or not.
+	if autostartChanged {
+		if err := vm.domain.SetAutostart(vm.ctx, vm.Spec.Name, vm.Spec.Autostart); err != nil {
+			return err
+		}
+		if err := db.SetVMAutostart(vm.ctx, vm.db, vm.Spec.Name, record.Namespace, vm.Spec.Autostart); err != nil {
+			return err
+		}
+	}
+	if !maximumsChanged && !resized {
+		fmt.Printf("vm/%s updated\n", vm.Spec.Name)
+		return nil
+	}
+
+	active, err := vm.conn.DomainIsActive(dom)
+	if err != nil {
+		return fmt.Errorf("get state for %q: %w", vm.Spec.Name, err)
+	}
+	running := active == 1
+
+	restart := false
+	if maximumsChanged || !running {
+		if err := vm.redefineResources(dom, wantMaxCPU, wantMaxMemory); err != nil {
+			return err
+		}
+		restart = running
+	} else {
+		restart = vm.resizeLive(record)
+	}
+
+	record.CPU = vm.Spec.CPU
+	record.RAM = vm.Spec.Memory
+	record.RestartRequired = running && (restart || record.RestartRequired)
+	if err := record.Update(vm.ctx, vm.db); err != nil {
+		return err
+	}
+
+	if record.RestartRequired {
+		fmt.Printf("vm/%s updated (restart required)\n", vm.Spec.Name)
+	} else {
+		fmt.Printf("vm/%s updated\n", vm.Spec.Name)
+	}
+	return nil
+}
+
+// resizeLive applies CPU and memory changes to the running domain. A change
+// the guest refuses, such as unplugging a vCPU, is kept in the persistent
+// definition only; the return value reports whether that happened.
+func (vm *VirtualMachine) resizeLive(record db.VirtualMachine) bool {
+	restart := false
+	if vm.Spec.CPU != record.CPU {
+		if err := vm.domain.SetVCPUs(vm.ctx, vm.Spec.Name, vm.Spec.CPU, true); err != nil {
+			log.Warnf("live vcpu change of %s failed, applying on next boot: %v", vm.Spec.Name, err)
+			if err := vm.domain.SetVCPUs(vm.ctx, vm.Spec.Name, vm.Spec.CPU, false); err != nil {
+				log.Errorf("%v", err)
+			}
+			restart = true
+		}
+	}
+	if vm.Spec.Memory != record.RAM {
+		if err := vm.domain.SetMemory(vm.ctx, vm.Spec.Name, vm.Spec.Memory, true); err != nil {
+			log.Warnf("live memory change of %s failed, applying on next boot: %v", vm.Spec.Name, err)
+			if err := vm.domain.SetMemory(vm.ctx, vm.Spec.Name, vm.Spec.Memory, false); err != nil {
+				log.Errorf("%v", err)
+			}
+			restart = true
+		}
+	}
+	return restart
+}
+
+// redefineResources sets the vCPU and memory allocation and maximums of the
+// persistent definition, keeping every other setting as is.
+func (vm *VirtualMachine) redefineResources(dom libvirt.Domain, maxCPU, maxMemory int) error {
+	return setResources(vm.conn, dom, vm.Spec.CPU, maxCPU, vm.Spec.Memory, maxMemory)
+}
+
+// ResizeStopped sets the vCPU count and memory (MiB) of a shut off VM,
+// raising the maximums of its definition when they are too low, and
+// records the new size. A zero cpu or memory keeps the current value.
+func ResizeStopped(
+	ctx context.Context,
+	conn *libvirt.Libvirt,
+	database *sql.DB,
+	name string,
+	cpu, memory int,
+) error {
+	record, err := db.GetVMByName(ctx, database, name, "")
+	if err != nil {
+		return err
+	}
+	dom, err := conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("lookup domain %q: %w", name, err)
+	}
+	active, err := conn.DomainIsActive(dom)
+	if err != nil {
+		return fmt.Errorf("get state for %q: %w", name, err)
+	}
+	if active == 1 {
+		return fmt.Errorf("vm %q is running; stop it before resizing", name)
+	}
+
+	if cpu == 0 {
+		cpu = record.CPU
+	}
+	if memory == 0 {
+		memory = record.RAM
+	}
+	maxCPU, maxMemory, err := domainMaximums(conn, dom)
+	if err != nil {
+		return err
+	}
+	if err := setResources(conn, dom, cpu, max(maxCPU, cpu), memory, max(maxMemory, memory)); err != nil {
+		return err
+	}
+
+	record.CPU = cpu
+	record.RAM = memory
+	record.RestartRequired = false
+	return record.Update(ctx, database)
+}
+
+// domainMaximums returns the vCPU and memory (MiB) maximums of the persistent definition.
+func domainMaximums(conn *libvirt.Libvirt, dom libvirt.Domain) (int, int, error) {
+	flags := libvirt.DomainVCPUMaximum | libvirt.DomainVCPUConfig
+	cpus, err := conn.DomainGetVcpusFlags(dom, uint32(flags))
+	if err != nil {
+		return 0, 0, fmt.Errorf("get maximum vcpus of %q: %w", dom.Name, err)
+	}
+	memory, err := conn.DomainGetMaxMemory(dom)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get maximum memory of %q: %w", dom.Name, err)
+	}
+	return int(cpus), int(memory / 1024), nil
+}
+
+// setResources sets the vCPU and memory (MiB) allocation and maximums of
+// the persistent definition of a domain. A maximum that goes up is raised
+// before the allocation and one that goes down is lowered after it, so the
+// allocation never exceeds its maximum along the way.
+func setResources(conn *libvirt.Libvirt, dom libvirt.Domain, cpu, maxCPU, memory, maxMemory int) error {
+	currentMaxCPU, currentMaxMemory, err := domainMaximums(conn, dom)
+	if err != nil {
+		return err
+	}
+
+	setMaxCPU := func() error {
+		flags := libvirt.DomainVCPUConfig | libvirt.DomainVCPUMaximum
+		if err := conn.DomainSetVcpusFlags(dom, uint32(maxCPU), uint32(flags)); err != nil {
+			return fmt.Errorf("set maximum vcpus of %q to %d: %w", dom.Name, maxCPU, err)
+		}
+		return nil
+	}
+	setCPU := func() error {
+		if err := conn.DomainSetVcpusFlags(dom, uint32(cpu), uint32(libvirt.DomainVCPUConfig)); err != nil {
+			return fmt.Errorf("set vcpus of %q to %d: %w", dom.Name, cpu, err)
+		}
+		return nil
+	}
+	setMaxMemory := func() error {
+		flags := libvirt.DomainMemConfig | libvirt.DomainMemMaximum
+		if err := conn.DomainSetMemoryFlags(dom, uint64(maxMemory)*1024, uint32(flags)); err != nil {
+			return fmt.Errorf("set maximum memory of %q to %d MiB: %w", dom.Name, maxMemory, err)
+		}
+		return nil
+	}
+	setMemory := func() error {
+		if err := conn.DomainSetMemoryFlags(dom, uint64(memory)*1024, uint32(libvirt.DomainMemConfig)); err != nil {
+			return fmt.Errorf("set memory of %q to %d MiB: %w", dom.Name, memory, err)
+		}
+		return nil
+	}
+
+	steps := []func() error{setCPU, setMaxCPU}
+	if maxCPU >= currentMaxCPU {
+		steps = []func() error{setMaxCPU, setCPU}
+	}
+	if maxMemory >= currentMaxMemory {
+		steps = append(steps, setMaxMemory, setMemory)
+	} else {
+		steps = append(steps, setMemory, setMaxMemory)
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMaximums rejects maximums below the requested allocation.
+func validateMaximums(spec Config) error {
+	if spec.MaxCPU != 0 && spec.MaxCPU < spec.CPU {
+		return fmt.Errorf("vm %q: max_cpu (%d) is below cpu (%d)", spec.Name, spec.MaxCPU, spec.CPU)
+	}
+	if spec.MaxMemory != 0 && spec.MaxMemory < spec.Memory {
+		return fmt.Errorf(
+			"vm %q: max_memory (%d) is below memory (%d)", spec.Name, spec.MaxMemory, spec.Memory)
+	}
+	return nil
+}